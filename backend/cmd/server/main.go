@@ -3,17 +3,49 @@ package main
 import (
 	"log"
 	"os"
+	"path/filepath"
 
 	"opendbm/internal/database"
 	"opendbm/internal/handlers"
+	"opendbm/internal/store"
+	"opendbm/internal/vault"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// Open persistent store
+	dataDir := os.Getenv("OPENDBM_DATA_DIR")
+	if dataDir == "" {
+		dataDir = store.DefaultDataDir()
+	}
+
+	st, err := store.Open(dataDir)
+	if err != nil {
+		log.Fatal("Failed to open store:", err)
+	}
+	defer st.Close()
+
+	// Open credential vault
+	keyFile := os.Getenv("OPENDBM_MASTER_KEY_FILE")
+	if keyFile == "" {
+		keyFile = filepath.Join(dataDir, "master.key")
+	}
+
+	v, err := vault.Open(st, vault.Options{
+		Key:     os.Getenv("OPENDBM_MASTER_KEY"),
+		KeyFile: keyFile,
+	})
+	if err != nil {
+		log.Fatal("Failed to open credential vault:", err)
+	}
+
 	// Create connection manager
-	manager := database.NewManager()
+	manager, err := database.NewManager(st, v)
+	if err != nil {
+		log.Fatal("Failed to create connection manager:", err)
+	}
 
 	// Create router
 	r := gin.Default()
@@ -40,27 +72,84 @@ func main() {
 	// API routes
 	api := r.Group("/api")
 	{
+		// Drivers
+		api.GET("/drivers", handlers.ListDrivers(manager))
+
 		// Connection management
 		api.GET("/connections", handlers.ListConnections(manager))
 		api.POST("/connections", handlers.CreateConnection(manager))
 		api.POST("/connections/test", handlers.TestConnection(manager))
+		api.GET("/connections/:id", handlers.GetConnection(manager))
+		api.PUT("/connections/:id", handlers.UpdateConnection(manager))
 		api.DELETE("/connections/:id", handlers.DeleteConnection(manager))
 		api.POST("/connections/:id/disconnect", handlers.DisconnectConnection(manager))
 
+		// SSH tunnels
+		api.GET("/ssh/tunnels", handlers.ListTunnels(manager))
+		api.POST("/ssh/tunnels", handlers.CreateTunnel(manager))
+		api.POST("/ssh/tunnels/test", handlers.TestTunnel(manager))
+		api.GET("/ssh/tunnels/:id", handlers.GetTunnel(manager))
+		api.PUT("/ssh/tunnels/:id", handlers.UpdateTunnel(manager))
+		api.DELETE("/ssh/tunnels/:id", handlers.DeleteTunnel(manager))
+		api.POST("/ssh/tunnels/:id/test", handlers.TestSavedTunnel(manager))
+		api.GET("/ssh/known-hosts", handlers.ListKnownHosts(manager))
+		api.POST("/ssh/known-hosts", handlers.AddKnownHost(manager))
+		api.GET("/ssh/known-hosts/pending", handlers.ListPendingHostKeys(manager))
+		api.POST("/ssh/known-hosts/trust", handlers.TrustHostKey(manager))
+		api.DELETE("/ssh/known-hosts/:id", handlers.DeleteKnownHost(manager))
+
+		// Credential vault
+		api.GET("/vault/status", handlers.VaultStatus(v))
+		api.POST("/vault/rotate", handlers.RotateVaultKey(v))
+		api.POST("/vault/reencrypt", handlers.ReEncryptVault(v))
+
 		// Query execution
 		api.POST("/query", handlers.ExecuteQuery(manager))
+		api.POST("/query/stream", handlers.StreamQuery(manager))
+		api.POST("/query/script", handlers.ExecuteScript(manager))
+		api.POST("/explain", handlers.ExplainQuery(manager))
+		api.GET("/queries", handlers.ListQueries(manager))
+		api.POST("/query/:queryId/cancel", handlers.CancelQuery(manager))
+
+		// Sessions and transactions
+		api.POST("/sessions/:id", handlers.OpenSession(manager))
+		api.GET("/sessions/:id", handlers.ListSessions(manager))
+		api.DELETE("/sessions/:id/:sessionId", handlers.CloseSession(manager))
+		api.POST("/sessions/:id/:sessionId/begin", handlers.BeginTransaction(manager))
+		api.POST("/sessions/:id/:sessionId/commit", handlers.CommitTransaction(manager))
+		api.POST("/sessions/:id/:sessionId/rollback", handlers.RollbackTransaction(manager))
 
 		// Database structure
 		api.GET("/databases/:id", handlers.ListDatabases(manager))
+		api.GET("/schemas/:id/:db", handlers.ListSchemas(manager))
 		api.GET("/tables/:id/:db", handlers.ListTables(manager))
+		api.GET("/tables/:id/:db/:table", handlers.GetTableDetail(manager))
 		api.GET("/schema/:id/:table", handlers.GetTableSchema(manager))
+		api.GET("/definition/:id/:db/:type/:name", handlers.GetObjectDefinition(manager))
+		api.GET("/ddl/:id/:db/:type/:name", handlers.GetObjectDDL(manager))
 
 		// Table data
 		api.GET("/data/:id/:db/:table", handlers.GetTableData(manager))
+		api.POST("/data/:id/:db/:table", handlers.GetTableData(manager))
 
 		// MongoDB specific
 		api.GET("/collections/:id/:db", handlers.ListCollections(manager))
 		api.POST("/documents/find", handlers.FindDocuments(manager))
+		api.POST("/documents/insert-one", handlers.InsertDocument(manager))
+		api.POST("/documents/insert-many", handlers.InsertDocuments(manager))
+		api.POST("/documents/update-one", handlers.UpdateDocument(manager))
+		api.POST("/documents/update-many", handlers.UpdateDocuments(manager))
+		api.POST("/documents/replace-one", handlers.ReplaceDocument(manager))
+		api.POST("/documents/delete-one", handlers.DeleteDocument(manager))
+		api.POST("/documents/delete-many", handlers.DeleteDocuments(manager))
+		api.POST("/documents/aggregate", handlers.AggregateDocuments(manager))
+		api.POST("/documents/command", handlers.RunDocumentCommand(manager))
+		api.POST("/keys/scan", handlers.ScanKeys(manager))
+		api.POST("/keys/get", handlers.GetKey(manager))
+		api.POST("/keys/write", handlers.WriteKey(manager))
+		api.POST("/keys/delete", handlers.DeleteKeys(manager))
+		api.POST("/keys/ttl", handlers.SetKeyTTL(manager))
+		api.POST("/keys/command", handlers.ExecuteKeyCommand(manager))
 	}
 
 	// Get port from environment
@@ -0,0 +1,412 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"opendbm/internal/models"
+)
+
+// GetDDL returns the CREATE statement of a table, view, materialized view,
+// function, procedure, trigger or sequence, given as a name or
+// schema.name. MySQL and Oracle produce it themselves and SQLite keeps it
+// as written; PostgreSQL and SQL Server tables are rebuilt from the
+// catalog, together with their indexes and comments. Each statement ends
+// with a semicolon.
+func (d *SQLDriverImpl) GetDDL(id string, database string, kind string, name string) (*models.ObjectDDL, error) {
+	_, dbType, err := d.pool(id)
+	if err != nil {
+		return nil, err
+	}
+	t, err := resolveTable(dbType, database, name)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case "table", "view", "materialized_view", "function", "procedure", "trigger", "sequence":
+	default:
+		return nil, fmt.Errorf("cannot generate DDL for object type %q", kind)
+	}
+
+	var ddl string
+	switch dbType {
+	case "mysql":
+		ddl, err = d.mysqlDDL(id, kind, t)
+	case "sqlite":
+		ddl, err = d.sqliteDDL(id, kind, t)
+	case "oracle":
+		ddl, err = d.oracleDDL(id, kind, t)
+	case "postgres":
+		if kind == "table" {
+			ddl, err = d.postgresTableDDL(id, t)
+		} else {
+			ddl, err = d.definitionDDL(id, dbType, database, kind, t)
+		}
+	case "sqlserver":
+		if kind == "table" {
+			ddl, err = d.sqlServerTableDDL(id, database, t)
+		} else {
+			ddl, err = d.definitionDDL(id, dbType, database, kind, t)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", dbType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.ObjectDDL{Name: t.name, Schema: t.schema, Type: kind, DDL: ddl}, nil
+}
+
+// mysqlDDL reads the statement SHOW CREATE returns
+func (d *SQLDriverImpl) mysqlDDL(id string, kind string, t tableName) (string, error) {
+	statements := map[string][2]string{
+		"table":     {"SHOW CREATE TABLE", "Create Table"},
+		"view":      {"SHOW CREATE VIEW", "Create View"},
+		"function":  {"SHOW CREATE FUNCTION", "Create Function"},
+		"procedure": {"SHOW CREATE PROCEDURE", "Create Procedure"},
+		"trigger":   {"SHOW CREATE TRIGGER", "SQL Original Statement"},
+	}
+	statement, ok := statements[kind]
+	if !ok {
+		return "", fmt.Errorf("mysql connections have no %s objects", strings.ReplaceAll(kind, "_", " "))
+	}
+
+	rows, err := d.catalogRows(id, statement[0]+" "+dialect("mysql").qualify(t))
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", ErrObjectNotFound
+	}
+	ddl, ok := rows[0][statement[1]].(string)
+	if !ok {
+		// Routines hide their body from users without the privilege to see it
+		return "", fmt.Errorf("the definition of %s is not visible to this user", t.name)
+	}
+	return ddl + ";", nil
+}
+
+// sqliteDDL returns the statements SQLite keeps: for a table, its own and
+// those of its indexes
+func (d *SQLDriverImpl) sqliteDDL(id string, kind string, t tableName) (string, error) {
+	if kind != "table" && kind != "view" && kind != "trigger" {
+		return "", fmt.Errorf("sqlite connections have no %s objects", strings.ReplaceAll(kind, "_", " "))
+	}
+	schema := t.schema
+	if schema == "" {
+		schema = "main"
+	}
+
+	rows, err := d.catalogRows(id, `SELECT sql FROM `+dialect("sqlite").quote(schema)+`.sqlite_master
+		WHERE sql IS NOT NULL AND ((type = ? AND name = ?) OR (? = 'table' AND type = 'index' AND tbl_name = ?))
+		ORDER BY type = 'index', name`, kind, t.name, kind, t.name)
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", ErrObjectNotFound
+	}
+	statements := make([]string, len(rows))
+	for i, row := range rows {
+		statements[i] = catalogString(row["sql"]) + ";"
+	}
+	return strings.Join(statements, "\n"), nil
+}
+
+// oracleDDL has DBMS_METADATA generate the statement
+func (d *SQLDriverImpl) oracleDDL(id string, kind string, t tableName) (string, error) {
+	rows, err := d.catalogRows(id, `SELECT DBMS_METADATA.GET_DDL(?, ?, NVL(?, SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA'))) AS "ddl" FROM dual`,
+		strings.ToUpper(kind), t.name, t.schemaArg())
+	if err != nil {
+		if strings.Contains(err.Error(), "ORA-31603") {
+			return "", ErrObjectNotFound
+		}
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", ErrObjectNotFound
+	}
+	return strings.TrimSpace(catalogString(rows[0]["ddl"])) + ";", nil
+}
+
+// definitionDDL builds the statement of a view, routine, trigger or
+// sequence from its definition. SQL Server keeps the CREATE statements of
+// views, routines and triggers whole.
+func (d *SQLDriverImpl) definitionDDL(id string, dbType string, database string, kind string, t tableName) (string, error) {
+	if dbType == "postgres" && (kind == "function" || kind == "procedure") {
+		return d.postgresRoutineDDL(id, kind, t)
+	}
+
+	definitions, err := d.GetObjectDefinition(id, database, kind, TableRef(t.schema, t.name))
+	if err != nil {
+		return "", err
+	}
+	statements := make([]string, len(definitions))
+	for i, def := range definitions {
+		statements[i] = definitionStatement(dbType, def)
+	}
+	return strings.Join(statements, "\n\n"), nil
+}
+
+// definitionStatement spells the CREATE statement of an object from its
+// definition
+func definitionStatement(dbType string, def models.ObjectDefinition) string {
+	qualified := dialect(dbType).qualify(tableName{schema: def.Schema, name: def.Name})
+	body := strings.TrimSuffix(strings.TrimSpace(def.Definition), ";")
+	switch {
+	case def.Type == "sequence":
+		return "CREATE SEQUENCE " + qualified + " " + body + ";"
+	case dbType == "sqlserver":
+		return body + ";"
+	case def.Type == "view":
+		return "CREATE OR REPLACE VIEW " + qualified + " AS\n" + body + ";"
+	case def.Type == "materialized_view":
+		return "CREATE MATERIALIZED VIEW " + qualified + " AS\n" + body + ";"
+	}
+	return body + ";"
+}
+
+// postgresRoutineDDL returns the CREATE statements PostgreSQL gives for
+// every overload of a function or procedure
+func (d *SQLDriverImpl) postgresRoutineDDL(id string, kind string, t tableName) (string, error) {
+	prokind := "f"
+	if kind == "procedure" {
+		prokind = "p"
+	}
+	rows, err := d.catalogRows(id, `SELECT pg_get_functiondef(p.oid) AS ddl
+		FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = COALESCE(?, current_schema()) AND p.proname = ? AND p.prokind = '`+prokind+`'
+		ORDER BY pg_get_function_identity_arguments(p.oid)`, t.schemaArg(), t.name)
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", ErrObjectNotFound
+	}
+	statements := make([]string, len(rows))
+	for i, row := range rows {
+		statements[i] = strings.TrimSpace(catalogString(row["ddl"])) + ";"
+	}
+	return strings.Join(statements, "\n\n"), nil
+}
+
+// postgresTableDDL rebuilds a table's CREATE statement. Constraints and
+// indexes are spelled by PostgreSQL itself.
+func (d *SQLDriverImpl) postgresTableDDL(id string, t tableName) (string, error) {
+	dl := dialect("postgres")
+	oid := dl.qualify(t)
+
+	tables, err := d.catalogRows(id, `SELECT n.nspname AS schema, c.relname AS name, c.relkind::text AS kind,
+			c.relpersistence::text AS persistence, obj_description(c.oid, 'pg_class') AS comment,
+			CASE WHEN c.relkind = 'p' THEN pg_get_partkeydef(c.oid) END AS partition_key
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.oid = to_regclass(?)`, oid)
+	if err != nil {
+		return "", err
+	}
+	if len(tables) == 0 {
+		return "", ErrObjectNotFound
+	}
+	table := tables[0]
+	if kind := catalogString(table["kind"]); kind != "r" && kind != "p" {
+		return "", fmt.Errorf("%s is not a table", t.name)
+	}
+	qualified := dl.qualify(tableName{schema: catalogString(table["schema"]), name: catalogString(table["name"])})
+
+	columns, err := d.catalogRows(id, `SELECT a.attname AS name, format_type(a.atttypid, a.atttypmod) AS type, a.attnotnull AS not_null,
+			pg_get_expr(ad.adbin, ad.adrelid) AS default_value, a.attidentity::text AS identity, a.attgenerated::text AS generated,
+			CASE WHEN a.attcollation <> ty.typcollation THEN co.collname END AS collation,
+			col_description(a.attrelid, a.attnum) AS comment
+		FROM pg_attribute a
+		JOIN pg_type ty ON ty.oid = a.atttypid
+		LEFT JOIN pg_collation co ON co.oid = a.attcollation
+		LEFT JOIN pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+		WHERE a.attrelid = to_regclass(?) AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`, oid)
+	if err != nil {
+		return "", err
+	}
+	constraints, err := d.catalogRows(id, `SELECT conname AS name, pg_get_constraintdef(oid, true) AS definition
+		FROM pg_constraint WHERE conrelid = to_regclass(?) AND contype IN ('p', 'u', 'c', 'f', 'x')
+		ORDER BY CASE contype WHEN 'p' THEN 0 WHEN 'u' THEN 1 WHEN 'c' THEN 2 WHEN 'x' THEN 3 ELSE 4 END, conname`, oid)
+	if err != nil {
+		return "", err
+	}
+	indexes, err := d.catalogRows(id, `SELECT pg_get_indexdef(x.indexrelid) AS definition
+		FROM pg_index x JOIN pg_class i ON i.oid = x.indexrelid
+		WHERE x.indrelid = to_regclass(?) AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = x.indexrelid AND c.conrelid = x.indrelid)
+		ORDER BY i.relname`, oid)
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	var comments []string
+	for _, col := range columns {
+		lines = append(lines, postgresColumnDDL(col))
+		if comment, ok := col["comment"].(string); ok {
+			name := catalogString(col["name"])
+			comments = append(comments, "COMMENT ON COLUMN "+qualified+"."+dl.quote(name)+" IS "+dl.literal(comment)+";")
+		}
+	}
+	for _, c := range constraints {
+		lines = append(lines, "CONSTRAINT "+dl.quote(catalogString(c["name"]))+" "+catalogString(c["definition"]))
+	}
+
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if catalogString(table["persistence"]) == "u" {
+		b.WriteString("UNLOGGED ")
+	}
+	b.WriteString("TABLE " + qualified + " (\n    " + strings.Join(lines, ",\n    ") + "\n)")
+	if key := catalogString(table["partition_key"]); key != "" {
+		b.WriteString(" PARTITION BY " + key)
+	}
+	b.WriteString(";")
+	for _, index := range indexes {
+		b.WriteString("\n" + catalogString(index["definition"]) + ";")
+	}
+	if comment, ok := table["comment"].(string); ok {
+		b.WriteString("\nCOMMENT ON TABLE " + qualified + " IS " + dl.literal(comment) + ";")
+	}
+	for _, comment := range comments {
+		b.WriteString("\n" + comment)
+	}
+	return b.String(), nil
+}
+
+// postgresColumnDDL spells a column of a CREATE TABLE statement from its
+// catalog row
+func postgresColumnDDL(col map[string]interface{}) string {
+	dl := dialect("postgres")
+	line := dl.quote(catalogString(col["name"])) + " " + catalogString(col["type"])
+	if collation := catalogString(col["collation"]); collation != "" {
+		line += " COLLATE " + dl.quote(collation)
+	}
+	def := catalogString(col["default_value"])
+	switch {
+	case catalogString(col["identity"]) == "a":
+		line += " GENERATED ALWAYS AS IDENTITY"
+	case catalogString(col["identity"]) == "d":
+		line += " GENERATED BY DEFAULT AS IDENTITY"
+	case catalogString(col["generated"]) == "s":
+		line += " GENERATED ALWAYS AS (" + def + ") STORED"
+	case def != "":
+		line += " DEFAULT " + def
+	}
+	if catalogBool(col["not_null"]) {
+		line += " NOT NULL"
+	}
+	return line
+}
+
+// sqlServerTableDDL rebuilds a table's CREATE statement from its detail,
+// followed by its other indexes and its descriptions
+func (d *SQLDriverImpl) sqlServerTableDDL(id string, database string, t tableName) (string, error) {
+	dl := dialect("sqlserver")
+	detail, err := d.GetTableDetail(id, database, TableRef(t.schema, t.name))
+	if err != nil {
+		return "", err
+	}
+	identities, err := d.catalogRows(id, `SELECT c.name AS name, CAST(c.seed_value AS varchar(40)) AS seed,
+			CAST(c.increment_value AS varchar(40)) AS increment
+		FROM sys.identity_columns c WHERE c.object_id = OBJECT_ID(?)`, dl.qualify(t))
+	if err != nil {
+		return "", err
+	}
+	identity := map[string]string{}
+	for _, row := range identities {
+		identity[catalogString(row["name"])] = fmt.Sprintf("IDENTITY(%s,%s)", catalogString(row["seed"]), catalogString(row["increment"]))
+	}
+	return sqlServerCreateTable(detail, identity), nil
+}
+
+// sqlServerCreateTable spells the statements of a table from its detail,
+// given the IDENTITY specification of its identity columns by name
+func sqlServerCreateTable(detail *models.TableDetail, identity map[string]string) string {
+	dl := dialect("sqlserver")
+	qualified := dl.qualify(tableName{schema: detail.Schema, name: detail.Name})
+	quoteAll := func(names []string) string {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = dl.quote(name)
+		}
+		return strings.Join(quoted, ", ")
+	}
+	indexMethod := map[string]string{}
+	for _, index := range detail.Indexes {
+		indexMethod[index.Name] = strings.ToUpper(index.Method)
+	}
+
+	var lines []string
+	for _, col := range detail.Columns {
+		line := dl.quote(col.Name) + " " + col.Type
+		if spec, ok := identity[col.Name]; ok {
+			line += " " + spec
+		}
+		if col.Nullable {
+			line += " NULL"
+		} else {
+			line += " NOT NULL"
+		}
+		if col.DefaultValue != "" {
+			line += " DEFAULT " + col.DefaultValue
+		}
+		lines = append(lines, line)
+	}
+	constraintNames := map[string]bool{}
+	for _, c := range detail.Constraints {
+		constraintNames[c.Name] = true
+		prefix := "CONSTRAINT " + dl.quote(c.Name) + " "
+		switch c.Type {
+		case "primary_key":
+			lines = append(lines, prefix+"PRIMARY KEY "+indexMethod[c.Name]+" ("+quoteAll(c.Columns)+")")
+		case "unique":
+			lines = append(lines, prefix+"UNIQUE "+indexMethod[c.Name]+" ("+quoteAll(c.Columns)+")")
+		case "check":
+			lines = append(lines, prefix+"CHECK "+c.Expression)
+		}
+	}
+	for _, fk := range detail.ForeignKeys {
+		line := "CONSTRAINT " + dl.quote(fk.Name) + " FOREIGN KEY (" + quoteAll(fk.Columns) + ") REFERENCES " +
+			dl.qualify(tableName{schema: fk.ReferencedSchema, name: fk.ReferencedTable}) + " (" + quoteAll(fk.ReferencedColumns) + ")"
+		line += " ON DELETE " + fk.OnDelete + " ON UPDATE " + fk.OnUpdate
+		lines = append(lines, line)
+	}
+
+	var b strings.Builder
+	b.WriteString("CREATE TABLE " + qualified + " (\n    " + strings.Join(lines, ",\n    ") + "\n);")
+	for _, index := range detail.Indexes {
+		if constraintNames[index.Name] {
+			continue
+		}
+		b.WriteString("\nCREATE ")
+		if index.Unique {
+			b.WriteString("UNIQUE ")
+		}
+		b.WriteString(strings.ToUpper(index.Method) + " INDEX " + dl.quote(index.Name) + " ON " + qualified + " (" + quoteAll(index.Columns) + ")")
+		if index.Predicate != "" {
+			b.WriteString(" WHERE " + index.Predicate)
+		}
+		b.WriteString(";")
+	}
+
+	describe := func(comment string, column string) {
+		b.WriteString("\nEXEC sys.sp_addextendedproperty @name = N'MS_Description', @value = " + dl.literal(comment) +
+			", @level0type = N'SCHEMA', @level0name = " + dl.literal(detail.Schema) +
+			", @level1type = N'TABLE', @level1name = " + dl.literal(detail.Name))
+		if column != "" {
+			b.WriteString(", @level2type = N'COLUMN', @level2name = " + dl.literal(column))
+		}
+		b.WriteString(";")
+	}
+	if detail.Comment != "" {
+		describe(detail.Comment, "")
+	}
+	for _, col := range detail.Columns {
+		if col.Comment != "" {
+			describe(col.Comment, col.Name)
+		}
+	}
+	return b.String()
+}
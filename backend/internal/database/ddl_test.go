@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"opendbm/internal/models"
+)
+
+func TestGetDDLSQLite(t *testing.T) {
+	d, id := newTestSQLite(t)
+	statements := []string{
+		`CREATE TABLE "my t" (id INTEGER PRIMARY KEY, name TEXT UNIQUE, n INTEGER CHECK (n > 0))`,
+		`CREATE INDEX "my t_n" ON "my t" (n) WHERE n > 1`,
+		`CREATE INDEX "a_name" ON "my t" (lower(name))`,
+		`CREATE VIEW v AS SELECT name FROM "my t"`,
+		`CREATE TRIGGER t_check BEFORE DELETE ON "my t" BEGIN SELECT RAISE(ABORT, 'no'); END`,
+	}
+	for _, stmt := range statements {
+		if result, err := d.ExecuteSQL(context.Background(), id, "", stmt); err != nil || result.Error != "" {
+			t.Fatal(err, result.Error)
+		}
+	}
+
+	tests := []struct {
+		kind string
+		name string
+		want string
+	}{
+		// The table comes first, then the indexes it does not create itself
+		{"table", TableRef("", "my t"), statements[0] + ";\n" + statements[2] + ";\n" + statements[1] + ";"},
+		{"view", "main.v", statements[3] + ";"},
+		{"trigger", "t_check", statements[4] + ";"},
+	}
+	for _, tt := range tests {
+		ddl, err := d.GetDDL(id, "", tt.kind, tt.name)
+		if err != nil {
+			t.Errorf("GetDDL(%s %s) error = %v", tt.kind, tt.name, err)
+			continue
+		}
+		if ddl.DDL != tt.want || ddl.Type != tt.kind {
+			t.Errorf("GetDDL(%s %s) = %q, want %q", tt.kind, tt.name, ddl.DDL, tt.want)
+		}
+	}
+
+	if _, err := d.GetDDL(id, "", "table", "missing"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("missing table = %v, want ErrObjectNotFound", err)
+	}
+	if _, err := d.GetDDL(id, "", "view", TableRef("", "my t")); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("table read as a view = %v, want ErrObjectNotFound", err)
+	}
+	for _, kind := range []string{"index", "function", "sequence"} {
+		if _, err := d.GetDDL(id, "", kind, "v"); err == nil || errors.Is(err, ErrObjectNotFound) {
+			t.Errorf("DDL of a %s = %v, want an error", kind, err)
+		}
+	}
+}
+
+func TestDefinitionStatement(t *testing.T) {
+	tests := []struct {
+		dbType string
+		def    models.ObjectDefinition
+		want   string
+	}{
+		{
+			"postgres", models.ObjectDefinition{Schema: "public", Name: "v", Type: "view", Definition: " SELECT 1;\n"},
+			"CREATE OR REPLACE VIEW \"public\".\"v\" AS\nSELECT 1;",
+		},
+		{
+			"postgres", models.ObjectDefinition{Schema: "s", Name: "m", Type: "materialized_view", Definition: "SELECT 2"},
+			"CREATE MATERIALIZED VIEW \"s\".\"m\" AS\nSELECT 2;",
+		},
+		{
+			"postgres", models.ObjectDefinition{Name: "t", Type: "trigger", Definition: "CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW EXECUTE FUNCTION f()"},
+			"CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW EXECUTE FUNCTION f();",
+		},
+		{
+			"postgres", models.ObjectDefinition{Schema: "public", Name: "q", Type: "sequence", Definition: "START WITH 1 INCREMENT BY 1 MINVALUE 1 MAXVALUE 9 NO CYCLE"},
+			`CREATE SEQUENCE "public"."q" START WITH 1 INCREMENT BY 1 MINVALUE 1 MAXVALUE 9 NO CYCLE;`,
+		},
+		{
+			"sqlserver", models.ObjectDefinition{Schema: "dbo", Name: "v", Type: "view", Definition: "CREATE VIEW dbo.v AS SELECT 1"},
+			"CREATE VIEW dbo.v AS SELECT 1;",
+		},
+		{
+			"sqlserver", models.ObjectDefinition{Schema: "dbo", Name: "q", Type: "sequence", Definition: "START WITH 1 INCREMENT BY 1 MINVALUE 1 MAXVALUE 9 NO CYCLE"},
+			"CREATE SEQUENCE [dbo].[q] START WITH 1 INCREMENT BY 1 MINVALUE 1 MAXVALUE 9 NO CYCLE;",
+		},
+	}
+	for _, tt := range tests {
+		if got := definitionStatement(tt.dbType, tt.def); got != tt.want {
+			t.Errorf("definitionStatement(%s, %+v) = %q, want %q", tt.dbType, tt.def, got, tt.want)
+		}
+	}
+}
+
+func TestPostgresColumnDDL(t *testing.T) {
+	tests := []struct {
+		col  map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"name": "id", "type": "bigint", "identity": "a", "not_null": true}, `"id" bigint GENERATED ALWAYS AS IDENTITY NOT NULL`},
+		{map[string]interface{}{"name": "id", "type": "integer", "identity": "d", "not_null": true}, `"id" integer GENERATED BY DEFAULT AS IDENTITY NOT NULL`},
+		{
+			map[string]interface{}{"name": "total", "type": "numeric(10,2)", "generated": "s", "default_value": "(price * qty)"},
+			`"total" numeric(10,2) GENERATED ALWAYS AS ((price * qty)) STORED`,
+		},
+		{
+			map[string]interface{}{"name": "Name", "type": "text", "collation": "C", "default_value": "'x'::text", "not_null": false},
+			`"Name" text COLLATE "C" DEFAULT 'x'::text`,
+		},
+		{
+			map[string]interface{}{"name": "n", "type": "integer", "default_value": "nextval('t_n_seq'::regclass)", "identity": "", "generated": ""},
+			`"n" integer DEFAULT nextval('t_n_seq'::regclass)`,
+		},
+		{map[string]interface{}{"name": `we"ird`, "type": "jsonb"}, `"we""ird" jsonb`},
+	}
+	for _, tt := range tests {
+		if got := postgresColumnDDL(tt.col); got != tt.want {
+			t.Errorf("postgresColumnDDL(%v) = %s, want %s", tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestSQLServerCreateTable(t *testing.T) {
+	detail := &models.TableDetail{
+		Name:    "orders",
+		Schema:  "sales",
+		Comment: "Customer's orders",
+		Columns: []models.ColumnInfo{
+			{Name: "id", Type: "int"},
+			{Name: "customer_id", Type: "int", Nullable: true, Comment: "Who"},
+			{Name: "total", Type: "decimal(10,2)", DefaultValue: "((0))"},
+		},
+		Indexes: []models.IndexInfo{
+			{Name: "PK_orders", Columns: []string{"id"}, Unique: true, Primary: true, Method: "clustered"},
+			{Name: "UQ_orders", Columns: []string{"customer_id", "total"}, Unique: true, Method: "nonclustered"},
+			{Name: "IX_orders_total", Columns: []string{"total"}, Method: "nonclustered", Predicate: "([total]>(0))"},
+		},
+		Constraints: []models.ConstraintInfo{
+			{Name: "PK_orders", Type: "primary_key", Columns: []string{"id"}},
+			{Name: "UQ_orders", Type: "unique", Columns: []string{"customer_id", "total"}},
+			{Name: "CK_total", Type: "check", Expression: "([total]>=(0))"},
+		},
+		ForeignKeys: []models.ForeignKeyInfo{
+			{Name: "FK_customer", Columns: []string{"customer_id"}, ReferencedSchema: "dbo", ReferencedTable: "customers", ReferencedColumns: []string{"id"}, OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+		},
+	}
+	want := "CREATE TABLE [sales].[orders] (\n" +
+		"    [id] int IDENTITY(1,1) NOT NULL,\n" +
+		"    [customer_id] int NULL,\n" +
+		"    [total] decimal(10,2) NOT NULL DEFAULT ((0)),\n" +
+		"    CONSTRAINT [PK_orders] PRIMARY KEY CLUSTERED ([id]),\n" +
+		"    CONSTRAINT [UQ_orders] UNIQUE NONCLUSTERED ([customer_id], [total]),\n" +
+		"    CONSTRAINT [CK_total] CHECK ([total]>=(0)),\n" +
+		"    CONSTRAINT [FK_customer] FOREIGN KEY ([customer_id]) REFERENCES [dbo].[customers] ([id]) ON DELETE CASCADE ON UPDATE NO ACTION\n" +
+		");\n" +
+		"CREATE NONCLUSTERED INDEX [IX_orders_total] ON [sales].[orders] ([total]) WHERE ([total]>(0));\n" +
+		"EXEC sys.sp_addextendedproperty @name = N'MS_Description', @value = N'Customer''s orders', @level0type = N'SCHEMA', @level0name = N'sales', @level1type = N'TABLE', @level1name = N'orders';\n" +
+		"EXEC sys.sp_addextendedproperty @name = N'MS_Description', @value = N'Who', @level0type = N'SCHEMA', @level0name = N'sales', @level1type = N'TABLE', @level1name = N'orders', @level2type = N'COLUMN', @level2name = N'customer_id';"
+	if got := sqlServerCreateTable(detail, map[string]string{"id": "IDENTITY(1,1)"}); got != want {
+		t.Errorf("sqlServerCreateTable() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dialect spells identifiers and placeholders the way a database type
+// expects them. Names from requests only ever reach SQL through quote;
+// values only through placeholders.
+type dialect string
+
+// quote quotes an identifier, doubling any quote character inside it
+func (d dialect) quote(name string) string {
+	switch d {
+	case "mysql":
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case "sqlserver":
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// literal quotes a string as a literal, for statements that are generated
+// to be read rather than run with arguments
+func (d dialect) literal(s string) string {
+	switch d {
+	case "mysql":
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	case "sqlserver":
+		return "N'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// placeholder returns the dialect's placeholder for the nth argument of a
+// statement run through database/sql
+func (d dialect) placeholder(n int) string {
+	switch d {
+	case "postgres":
+		return "$" + strconv.Itoa(n)
+	case "sqlserver":
+		return "@p" + strconv.Itoa(n)
+	case "oracle":
+		return ":" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// qualify quotes a table name, prefixed with its schema when it has one
+func (d dialect) qualify(t tableName) string {
+	if t.schema == "" {
+		return d.quote(t.name)
+	}
+	return d.quote(t.schema) + "." + d.quote(t.name)
+}
+
+// TableRef spells a table as resolveTable reads it: table, or schema.table
+// when schema is set, with parts double quoted when they contain a dot or
+// a quote
+func TableRef(schema string, table string) string {
+	part := func(name string) string {
+		if strings.ContainsAny(name, `."`) {
+			return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		}
+		return name
+	}
+	if schema == "" {
+		return part(table)
+	}
+	return part(schema) + "." + part(table)
+}
+
+// tableName is a table and the schema it is in, if given
+type tableName struct {
+	schema string
+	name   string
+}
+
+// schemaArg returns the schema for binding to a catalog query, NULL when
+// none was given so that the query can fall back to the current schema
+func (t tableName) schemaArg() interface{} {
+	if t.schema == "" {
+		return nil
+	}
+	return t.schema
+}
+
+// resolveTable reads the table of a request. The table is a name or
+// schema.name; either part can be double quoted, with "" for a quote, to
+// keep the dots in it, as in "my.schema"."my.table". MySQL tables default
+// to the request's database and Oracle ones to it as their owner.
+func resolveTable(dbType string, database string, table string) (tableName, error) {
+	parts, err := splitQualifiedName(table)
+	if err != nil {
+		return tableName{}, err
+	}
+
+	var t tableName
+	switch len(parts) {
+	case 1:
+		t.name = parts[0]
+	case 2:
+		t.schema, t.name = parts[0], parts[1]
+	default:
+		return tableName{}, fmt.Errorf("invalid table name %q: expected table or schema.table", table)
+	}
+	if t.schema == "" && (dbType == "mysql" || dbType == "oracle") {
+		t.schema = database
+	}
+	return t, nil
+}
+
+// splitQualifiedName splits a dotted name into its parts, honoring double
+// quoted parts
+func splitQualifiedName(name string) ([]string, error) {
+	invalid := fmt.Errorf("invalid name %q", name)
+	var parts []string
+	var part strings.Builder
+	quoted := false
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case quoted && c == '"':
+			if i+1 < len(name) && name[i+1] == '"' {
+				part.WriteByte('"')
+				i++
+			} else if i+1 < len(name) && name[i+1] != '.' {
+				return nil, invalid
+			} else {
+				quoted = false
+			}
+		case quoted:
+			part.WriteByte(c)
+		case c == '"':
+			if part.Len() > 0 {
+				return nil, invalid
+			}
+			quoted = true
+		case c == '.':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(c)
+		}
+	}
+	if quoted {
+		return nil, invalid
+	}
+	parts = append(parts, part.String())
+	for _, p := range parts {
+		if p == "" {
+			return nil, invalid
+		}
+	}
+	return parts, nil
+}
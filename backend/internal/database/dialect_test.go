@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSplitQualifiedName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    []string
+		wantErr bool
+	}{
+		{"t", []string{"t"}, false},
+		{"s.t", []string{"s", "t"}, false},
+		{"db.s.t", []string{"db", "s", "t"}, false},
+		{`"a.b"`, []string{"a.b"}, false},
+		{`schema."a.b"`, []string{"schema", "a.b"}, false},
+		{`"my.schema"."my.table"`, []string{"my.schema", "my.table"}, false},
+		{`"we""ird té.x"`, []string{`we"ird té.x`}, false},
+		{`"""".t`, []string{`"`, "t"}, false},
+		{"", nil, true},
+		{".t", nil, true},
+		{"s.", nil, true},
+		{"s..t", nil, true},
+		{`""`, nil, true},
+		{`"unterminated`, nil, true},
+		{`s."unterminated.t`, nil, true},
+		{`"a""`, nil, true},
+		{`"a"b`, nil, true},
+		{`a"b"`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := splitQualifiedName(tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("splitQualifiedName(%q) = %q, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitQualifiedName(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestTableRef(t *testing.T) {
+	tests := []struct {
+		schema string
+		table  string
+		want   string
+	}{
+		{"", "t", "t"},
+		{"s", "t", "s.t"},
+		{"", "a.b", `"a.b"`},
+		{"schema", "a.b", `schema."a.b"`},
+		{"my.schema", `we"ird té.x`, `"my.schema"."we""ird té.x"`},
+		{"", `"`, `""""`},
+		{"s", "té x", "s.té x"},
+	}
+	for _, tt := range tests {
+		ref := TableRef(tt.schema, tt.table)
+		if ref != tt.want {
+			t.Errorf("TableRef(%q, %q) = %q, want %q", tt.schema, tt.table, ref, tt.want)
+		}
+		// resolveTable reads back what TableRef spells
+		got, err := resolveTable("postgres", "", ref)
+		if err != nil || got != (tableName{schema: tt.schema, name: tt.table}) {
+			t.Errorf("resolveTable(%q) = %+v, %v, want schema %q and name %q", ref, got, err, tt.schema, tt.table)
+		}
+	}
+}
+
+func TestResolveTable(t *testing.T) {
+	tests := []struct {
+		dbType  string
+		db      string
+		table   string
+		want    tableName
+		wantErr bool
+	}{
+		{"postgres", "app", "t", tableName{name: "t"}, false},
+		{"mysql", "app", "t", tableName{schema: "app", name: "t"}, false},
+		{"oracle", "HR", "t", tableName{schema: "HR", name: "t"}, false},
+		{"mysql", "app", "other.t", tableName{schema: "other", name: "t"}, false},
+		{"sqlite", "main", `"x.y"`, tableName{name: "x.y"}, false},
+		{"postgres", "", "a.b.c", tableName{}, true},
+		{"postgres", "", `"t`, tableName{}, true},
+	}
+	for _, tt := range tests {
+		got, err := resolveTable(tt.dbType, tt.db, tt.table)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveTable(%s, %q, %q) = %+v, %v, want %+v", tt.dbType, tt.db, tt.table, got, err, tt.want)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		dialect dialect
+		name    string
+		want    string
+	}{
+		{"postgres", `we"ird té.x`, `"we""ird té.x"`},
+		{"sqlite", "a.b", `"a.b"`},
+		{"oracle", `""`, `""""""`},
+		{"mysql", "we`ird té.x", "`we``ird té.x`"},
+		{"sqlserver", "we]ird [té.x]", "[we]]ird [té.x]]]"},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.quote(tt.name); got != tt.want {
+			t.Errorf("%s quote(%q) = %q, want %q", tt.dialect, tt.name, got, tt.want)
+		}
+	}
+
+	qualified := dialect("postgres").qualify(tableName{schema: "my.schema", name: `we"ird`})
+	if want := `"my.schema"."we""ird"`; qualified != want {
+		t.Errorf("qualify = %q, want %q", qualified, want)
+	}
+}
+
+// TestQuoteRoundTrip reads names from SQLite through the quoting used to
+// build queries
+func TestQuoteRoundTrip(t *testing.T) {
+	d, id := newTestSQLite(t)
+	names := []string{`we"ird té.x`, "a.b", "select", "x y"}
+	for _, name := range names {
+		q := dialect("sqlite").quote(name)
+		result, err := d.ExecuteSQL(context.Background(), id, "", "CREATE TABLE "+q+" ("+q+" INTEGER)")
+		if err != nil || result.Error != "" {
+			t.Fatalf("creating %q: %v %s", name, err, result.Error)
+		}
+		columns, err := d.GetTableSchema(id, TableRef("", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(columns) != 1 || columns[0].Name != name {
+			t.Errorf("columns of %q = %+v, want one named alike", name, columns)
+		}
+	}
+}
@@ -1,6 +1,13 @@
 package database
 
-import "opendbm/internal/models"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"opendbm/internal/models"
+)
 
 // Driver is the common interface for all database drivers
 type Driver interface {
@@ -9,28 +16,159 @@ type Driver interface {
 	Ping(id string) error
 }
 
-// SQLDriver interface for SQL databases
+// SQLDriver interface for SQL databases: running statements and browsing
+// tables. Everything else a SQL driver can offer is one of the optional
+// interfaces below, which handlers check for with a type assertion, so a
+// new driver only implements what its database supports.
 type SQLDriver interface {
 	Driver
-	ExecuteQuery(id string, sql string) (*models.QueryResult, error)
-	ExecuteSQL(id string, sql string) error
+	ExecuteQuery(ctx context.Context, id string, sessionID string, sql string, args ...interface{}) (*models.ResultSet, error)
+	ExecuteSQL(ctx context.Context, id string, sessionID string, sql string, args ...interface{}) (*models.ResultSet, error)
 	ListDatabases(id string) ([]string, error)
-	ListTables(id string, database string) ([]models.TableInfo, error)
+	ListTables(id string, database string, schema string) ([]models.TableInfo, error)
 	GetTableSchema(id string, table string) ([]models.ColumnInfo, error)
 }
 
+// ParamBinder binds named and positional query parameters
+type ParamBinder interface {
+	BindParams(id string, sql string, params []models.QueryParam) (string, []interface{}, error)
+}
+
+// QueryStreamer streams result sets too large to buffer
+type QueryStreamer interface {
+	StreamQuery(ctx context.Context, id string, sessionID string, sql string, maxRows int, w RowWriter, args ...interface{}) (*models.QueryStreamTrailer, error)
+}
+
+// ScriptRunner runs scripts of several statements
+type ScriptRunner interface {
+	ExecuteScript(ctx context.Context, id string, sessionID string, script string, continueOnError bool, params []models.QueryParam) (*models.ScriptResult, error)
+}
+
+// Explainer returns query plans
+type Explainer interface {
+	Explain(ctx context.Context, id string, sessionID string, sql string, analyze bool, args ...interface{}) (*models.QueryPlan, error)
+}
+
+// SessionDriver pins statements to a connection that can hold a transaction
+type SessionDriver interface {
+	OpenSession(ctx context.Context, id string, idleTimeout time.Duration) (*models.SessionInfo, error)
+	ListSessions(id string) []models.SessionInfo
+	CloseSession(id string, sessionID string) error
+	BeginTransaction(ctx context.Context, id string, sessionID string) (*models.SessionInfo, error)
+	Commit(id string, sessionID string) (*models.SessionInfo, error)
+	Rollback(id string, sessionID string) (*models.SessionInfo, error)
+}
+
+// SchemaLister lists the schemas of a database
+type SchemaLister interface {
+	ListSchemas(id string, database string) ([]string, error)
+}
+
+// TableStatsLister lists tables with estimated row counts and sizes
+type TableStatsLister interface {
+	ListTablesWithStats(id string, database string, schema string) ([]models.TableInfo, error)
+}
+
+// TableDescriber returns a table's indexes, constraints and foreign keys
+type TableDescriber interface {
+	GetTableDetail(id string, database string, table string) (*models.TableDetail, error)
+}
+
+// DefinitionReader returns the source of views, routines, triggers and
+// sequences
+type DefinitionReader interface {
+	GetObjectDefinition(id string, database string, kind string, name string) ([]models.ObjectDefinition, error)
+}
+
+// DDLGenerator returns the CREATE statements of schema objects
+type DDLGenerator interface {
+	GetDDL(id string, database string, kind string, name string) (*models.ObjectDDL, error)
+}
+
+// TableDataReader pages through the rows of a table
+type TableDataReader interface {
+	GetTableData(ctx context.Context, id string, database string, table string, req models.TableDataRequest) (*models.TablePage, error)
+}
+
+// RowWriter receives a result set as it is read: the columns once, then
+// batches of rows. An error from either method stops the query.
+type RowWriter interface {
+	Header(columns []models.ResultColumn) error
+	Rows(rows [][]interface{}) error
+}
+
 // DocumentDriver interface for document databases like MongoDB
 type DocumentDriver interface {
 	Driver
 	ListDatabases(id string) ([]string, error)
 	ListCollections(id string, database string) ([]string, error)
-	FindDocuments(id string, database, collection string, filter map[string]interface{}) ([]map[string]interface{}, error)
+	FindDocuments(ctx context.Context, id string, req models.DocumentFindRequest) ([]json.RawMessage, error)
+	InsertOne(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error)
+	InsertMany(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error)
+	UpdateOne(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error)
+	UpdateMany(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error)
+	ReplaceOne(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error)
+	DeleteOne(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error)
+	DeleteMany(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error)
+	Aggregate(ctx context.Context, id string, req models.DocumentAggregateRequest) (*models.QueryResult, error)
+	RunCommand(ctx context.Context, id string, req models.DocumentCommandRequest) (*models.QueryResult, error)
 }
 
 // KeyValueDriver interface for key-value stores like Redis
 type KeyValueDriver interface {
 	Driver
-	Get(id string, key string) (string, error)
-	Set(id string, key, value string) error
-	Keys(id string, pattern string) ([]string, error)
+	ScanKeys(id string, req models.KeyScanRequest) (*models.KeyScanResult, error)
+	GetKey(id string, req models.KeyGetRequest) (*models.KeyValue, error)
+	WriteKey(id string, req models.KeyWriteRequest) error
+	DeleteKeys(id string, req models.KeyDeleteRequest) (int64, error)
+	SetTTL(id string, req models.KeyTTLRequest) error
+	ExecuteCommand(id string, req models.KeyCommandRequest) (*models.KeyCommandResult, error)
+}
+
+// SecretResolver looks up secrets that connection configs refer to
+type SecretResolver interface {
+	Get(ref string) (string, error)
+}
+
+// resolvePassword returns the plaintext password for a config, preferring an
+// explicitly supplied password over the vault reference
+func resolvePassword(secrets SecretResolver, config models.ConnectionConfig) (string, error) {
+	if config.Password != "" || config.PasswordRef == "" {
+		return config.Password, nil
+	}
+	if secrets == nil {
+		return "", fmt.Errorf("no credential vault configured")
+	}
+
+	password, err := secrets.Get(config.PasswordRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve password: %w", err)
+	}
+	return password, nil
+}
+
+// openForward starts an SSH forward to the database host of a config
+func openForward(tunnels *TunnelManager, config models.ConnectionConfig) (*Forward, error) {
+	chain := sshChain(config)
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("ssh tunnel is enabled but no tunnel is selected")
+	}
+	if tunnels == nil {
+		return nil, fmt.Errorf("ssh tunnels are not available")
+	}
+	return tunnels.Forward(chain, config.Host, config.Port)
+}
+
+// sshChain returns the ordered tunnel IDs a config connects through
+func sshChain(config models.ConnectionConfig) []string {
+	if !config.UseSSH {
+		return nil
+	}
+	if len(config.SSHHops) > 0 {
+		return config.SSHHops
+	}
+	if config.SSHTunnelID != "" {
+		return []string{config.SSHTunnelID}
+	}
+	return nil
 }
@@ -0,0 +1,283 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"opendbm/internal/models"
+)
+
+// showplanColumn names the result sets SQL Server adds for STATISTICS XML
+const showplanColumn = "Microsoft SQL Server 2005 XML Showplan"
+
+// Explain returns the execution plan of a query, with args bound to its
+// placeholders. With analyze the query runs so that actual rows and timings
+// are reported; outside a session it runs in a transaction that is rolled
+// back, so data changes do not persist. Inside a session it runs as part of
+// the session's open transaction, if any. Statement errors are reported in
+// the plan; an error is returned when the connection or session is unknown,
+// the session is busy or the dialect cannot explain as asked.
+func (d *SQLDriverImpl) Explain(ctx context.Context, id string, sessionID string, query string, analyze bool, args ...interface{}) (*models.QueryPlan, error) {
+	_, dbType, err := d.pool(id)
+	if err != nil {
+		return nil, err
+	}
+	if analyze && (dbType == "sqlite" || dbType == "oracle") {
+		return nil, fmt.Errorf("%s plans are estimates only and cannot be analyzed", dbType)
+	}
+
+	start := time.Now()
+	plan := &models.QueryPlan{Plans: []models.PlanNode{}, Analyzed: analyze}
+	fail := func(err error) (*models.QueryPlan, error) {
+		plan.Plans = []models.PlanNode{}
+		plan.Error = queryError(ctx, err)
+		plan.ExecutionTime = time.Since(start).Milliseconds()
+		return plan, nil
+	}
+
+	q, release, err := d.acquire(ctx, id, sessionID)
+	if err != nil {
+		if isLookupError(err) {
+			return nil, err
+		}
+		return fail(err)
+	}
+	defer release()
+
+	if conn, ok := q.(*sql.Conn); ok && analyze {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fail(err)
+		}
+		defer tx.Rollback()
+		q = tx
+	}
+
+	switch dbType {
+	case "postgres":
+		err = explainPostgres(ctx, q, plan, query, args)
+	case "mysql":
+		err = explainMySQL(ctx, q, plan, query, args)
+	case "sqlite":
+		err = explainSQLite(ctx, q, plan, query, args)
+	case "sqlserver":
+		err = explainSQLServer(ctx, q, plan, query, args)
+	case "oracle":
+		err = explainOracle(ctx, q, plan, query)
+	default:
+		return nil, fmt.Errorf("explain is not supported for %s connections", dbType)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	plan.ExecutionTime = time.Since(start).Milliseconds()
+	return plan, nil
+}
+
+func explainPostgres(ctx context.Context, q queryer, plan *models.QueryPlan, query string, args []interface{}) error {
+	options := "FORMAT JSON"
+	if plan.Analyzed {
+		options += ", ANALYZE"
+	}
+	raw, err := queryText(ctx, q, "EXPLAIN ("+options+") "+query, args...)
+	if err != nil {
+		return err
+	}
+	plan.Format = "json"
+	plan.Raw = raw
+	return parsePostgresPlan(plan)
+}
+
+// explainMySQL reads the JSON plan, or for analyze the tree that EXPLAIN
+// ANALYZE prints, as MySQL has no JSON form of actual figures
+func explainMySQL(ctx context.Context, q queryer, plan *models.QueryPlan, query string, args []interface{}) error {
+	if plan.Analyzed {
+		raw, err := queryText(ctx, q, "EXPLAIN ANALYZE "+query, args...)
+		if err != nil {
+			return err
+		}
+		plan.Format = "text"
+		plan.Raw = raw
+		plan.Plans = parseMySQLTree(raw)
+		return nil
+	}
+
+	raw, err := queryText(ctx, q, "EXPLAIN FORMAT=JSON "+query, args...)
+	if err != nil {
+		return err
+	}
+	plan.Format = "json"
+	plan.Raw = raw
+	return parseMySQLPlan(plan)
+}
+
+func explainSQLite(ctx context.Context, q queryer, plan *models.QueryPlan, query string, args []interface{}) error {
+	rows, err := q.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var steps []sqliteStep
+	var raw strings.Builder
+	for rows.Next() {
+		var step sqliteStep
+		var unused interface{}
+		if err := rows.Scan(&step.id, &step.parent, &unused, &step.detail); err != nil {
+			return err
+		}
+		steps = append(steps, step)
+		fmt.Fprintf(&raw, "%d|%d|%s\n", step.id, step.parent, step.detail)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	plan.Format = "text"
+	plan.Raw = raw.String()
+	plan.Plans = sqlitePlan(steps)
+	return nil
+}
+
+// explainSQLServer turns on showplan output for the connection, collects
+// the plan XML and turns it off again. SHOWPLAN_XML only compiles the
+// query; STATISTICS XML runs it and adds a plan after each statement's
+// results, which are discarded.
+func explainSQLServer(ctx context.Context, q queryer, plan *models.QueryPlan, query string, args []interface{}) error {
+	option := "SHOWPLAN_XML"
+	if plan.Analyzed {
+		option = "STATISTICS XML"
+	}
+	if _, err := q.ExecContext(ctx, "SET "+option+" ON"); err != nil {
+		return err
+	}
+	// The connection goes back to the pool or session afterwards, so the
+	// option is reset even when the request has been canceled
+	defer q.ExecContext(context.Background(), "SET "+option+" OFF")
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var docs []string
+	for {
+		columns, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+		showplan := !plan.Analyzed || len(columns) == 1 && columns[0] == showplanColumn
+		for rows.Next() {
+			if !showplan {
+				continue
+			}
+			var doc string
+			if err := rows.Scan(&doc); err != nil {
+				return err
+			}
+			docs = append(docs, doc)
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	plan.Format = "xml"
+	plan.Raw = strings.Join(docs, "\n")
+	for _, doc := range docs {
+		nodes, err := parseShowplan(doc)
+		if err != nil {
+			return err
+		}
+		plan.Plans = append(plan.Plans, nodes...)
+	}
+	return nil
+}
+
+// explainOracle fills PLAN_TABLE under a statement ID of its own, reads
+// the rows back and removes them. Placeholders are left unbound, as
+// EXPLAIN PLAN does not run the query.
+func explainOracle(ctx context.Context, q queryer, plan *models.QueryPlan, query string) error {
+	statementID := strings.ReplaceAll(uuid.New().String(), "-", "")[:30]
+	if _, err := q.ExecContext(ctx, "EXPLAIN PLAN SET STATEMENT_ID = '"+statementID+"' FOR "+query); err != nil {
+		return err
+	}
+	defer q.ExecContext(context.Background(), "DELETE FROM plan_table WHERE statement_id = :1", statementID)
+
+	raw, err := queryText(ctx, q, "SELECT plan_table_output FROM TABLE(DBMS_XPLAN.DISPLAY('PLAN_TABLE', :1, 'TYPICAL'))", statementID)
+	if err != nil {
+		return err
+	}
+	plan.Format = "text"
+	plan.Raw = raw
+
+	rows, err := q.QueryContext(ctx, `SELECT id, parent_id, operation, options, object_owner, object_name, cardinality, cost, time
+		FROM plan_table WHERE statement_id = :1 ORDER BY id`, statementID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var steps []oracleStep
+	for rows.Next() {
+		var step oracleStep
+		if err := rows.Scan(&step.id, &step.parent, &step.operation, &step.options, &step.owner, &step.object, &step.rows, &step.cost, &step.time); err != nil {
+			return err
+		}
+		steps = append(steps, step)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	plan.Plans = oraclePlan(steps)
+	return nil
+}
+
+// queryText runs a statement whose result is text in its first column,
+// one line per row
+func queryText(ctx context.Context, q queryer, query string, args ...interface{}) (string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return "", err
+		}
+		switch v := values[0].(type) {
+		case []byte:
+			lines = append(lines, string(v))
+		case string:
+			lines = append(lines, v)
+		case nil:
+		default:
+			lines = append(lines, fmt.Sprint(v))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
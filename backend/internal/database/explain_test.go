@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExplainSQLite(t *testing.T) {
+	d, id := newTestSQLite(t)
+	ctx := context.Background()
+	for _, stmt := range []string{
+		"CREATE TABLE a (id INTEGER PRIMARY KEY, x INTEGER)",
+		"CREATE INDEX a_x ON a (x)",
+	} {
+		if result, err := d.ExecuteSQL(ctx, id, "", stmt); err != nil || result.Error != "" {
+			t.Fatal(err, result.Error)
+		}
+	}
+
+	plan, err := d.Explain(ctx, id, "", "SELECT id FROM a WHERE x = ?", false, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Error != "" || plan.Format != "text" || plan.Analyzed {
+		t.Fatalf("plan = %+v", plan)
+	}
+	if len(plan.Plans) != 1 || plan.Plans[0].Operation != "Search" || plan.Plans[0].Object != "a" || plan.Plans[0].Index != "a_x" {
+		t.Errorf("plan = %s, want a search of a using a_x", planTree(plan.Plans))
+	}
+	if plan.Raw == "" {
+		t.Error("raw plan is empty")
+	}
+
+	// Explaining in a session runs on its connection
+	s, err := d.OpenSession(ctx, id, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.CloseSession(id, s.ID)
+	plan, err = d.Explain(ctx, id, s.ID, "SELECT * FROM a ORDER BY x DESC", false)
+	if err != nil || plan.Error != "" || len(plan.Plans) == 0 || plan.Plans[0].Operation != "Scan" {
+		t.Errorf("plan in a session = %+v, %v", plan, err)
+	}
+
+	plan, err = d.Explain(ctx, id, "", "SELECT * FROM missing", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Error == "" || plan.Plans == nil || len(plan.Plans) != 0 {
+		t.Errorf("plan of a missing table = %+v, want an error and no steps", plan)
+	}
+
+	if _, err := d.Explain(ctx, id, "", "SELECT 1", true); err == nil {
+		t.Error("analyzing a SQLite plan succeeded")
+	}
+	if _, err := d.Explain(ctx, "unknown", "", "SELECT 1", false); err == nil {
+		t.Error("explaining on an unknown connection succeeded")
+	}
+}
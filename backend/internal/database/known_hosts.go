@@ -0,0 +1,230 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"opendbm/internal/store"
+)
+
+const (
+	// HostKeyUnknown is the error code for a host key seen for the first time
+	HostKeyUnknown = "ssh_host_key_unknown"
+	// HostKeyChanged is the error code for a host key that differs from the trusted one
+	HostKeyChanged = "ssh_host_key_changed"
+)
+
+// HostKeyError reports an SSH host key that is not trusted
+type HostKeyError struct {
+	Code             string `json:"code"`
+	Host             string `json:"host"`
+	KeyType          string `json:"keyType"`
+	Fingerprint      string `json:"fingerprint"`
+	KnownFingerprint string `json:"knownFingerprint,omitempty"`
+}
+
+func (e *HostKeyError) Error() string {
+	if e.Code == HostKeyChanged {
+		return fmt.Sprintf("ssh host key for %s has changed: got %s %s, expected %s; remove the known host entry only if this change is expected",
+			e.Host, e.KeyType, e.Fingerprint, e.KnownFingerprint)
+	}
+	return fmt.Sprintf("ssh host key for %s is not trusted: %s %s", e.Host, e.KeyType, e.Fingerprint)
+}
+
+// PendingHostKey is a host key seen for the first time that awaits a trust decision
+type PendingHostKey struct {
+	Host        string    `json:"host"`
+	KeyType     string    `json:"keyType"`
+	Fingerprint string    `json:"fingerprint"`
+	SeenAt      time.Time `json:"seenAt"`
+	key         ssh.PublicKey
+}
+
+// KnownHosts verifies SSH host keys against trusted keys kept in the store.
+// Unknown keys are held as pending until trusted through the API.
+type KnownHosts struct {
+	store   *store.Store
+	pending map[string]*PendingHostKey
+	mu      sync.Mutex
+}
+
+// NewKnownHosts creates a known hosts verifier backed by the store
+func NewKnownHosts(st *store.Store) *KnownHosts {
+	return &KnownHosts{
+		store:   st,
+		pending: make(map[string]*PendingHostKey),
+	}
+}
+
+// List returns every trusted host key
+func (k *KnownHosts) List() ([]store.KnownHost, error) {
+	return k.store.ListKnownHosts()
+}
+
+// Pending returns host keys awaiting a trust decision
+func (k *KnownHosts) Pending() []PendingHostKey {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	result := make([]PendingHostKey, 0, len(k.pending))
+	for _, p := range k.pending {
+		result = append(result, *p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SeenAt.Before(result[j].SeenAt)
+	})
+	return result
+}
+
+// Trust accepts a pending host key identified by host and fingerprint
+func (k *KnownHosts) Trust(host string, fingerprint string) (*store.KnownHost, error) {
+	host = normalizeHost(host)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	p, exists := k.pending[host+" "+fingerprint]
+	if !exists {
+		return nil, fmt.Errorf("no pending host key %s for %s", fingerprint, host)
+	}
+
+	known, err := k.save(host, p.key)
+	if err != nil {
+		return nil, err
+	}
+	delete(k.pending, host+" "+fingerprint)
+	return known, nil
+}
+
+// Add trusts a host key given in authorized_keys format, e.g. "ssh-ed25519 AAAA..."
+func (k *KnownHosts) Add(host string, publicKey string) (*store.KnownHost, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	host = normalizeHost(host)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	known, err := k.save(host, key)
+	if err != nil {
+		return nil, err
+	}
+	delete(k.pending, host+" "+known.Fingerprint)
+	return known, nil
+}
+
+// Remove deletes a trusted host key
+func (k *KnownHosts) Remove(id uint) error {
+	return k.store.DeleteKnownHost(id)
+}
+
+// Callback returns a host key callback that accepts only trusted keys
+func (k *KnownHosts) Callback() ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		host := normalizeHost(hostname)
+
+		entries, err := k.store.FindKnownHosts(host)
+		if err != nil {
+			return fmt.Errorf("failed to read known hosts: %w", err)
+		}
+
+		marshaled := key.Marshal()
+		for _, e := range entries {
+			if bytes.Equal(e.Key, marshaled) {
+				return nil
+			}
+		}
+
+		fingerprint := ssh.FingerprintSHA256(key)
+		if len(entries) > 0 {
+			known := entries[0]
+			for _, e := range entries {
+				if e.KeyType == key.Type() {
+					known = e
+					break
+				}
+			}
+			return &HostKeyError{
+				Code:             HostKeyChanged,
+				Host:             host,
+				KeyType:          key.Type(),
+				Fingerprint:      fingerprint,
+				KnownFingerprint: known.Fingerprint,
+			}
+		}
+
+		k.mu.Lock()
+		k.pending[host+" "+fingerprint] = &PendingHostKey{
+			Host:        host,
+			KeyType:     key.Type(),
+			Fingerprint: fingerprint,
+			SeenAt:      time.Now(),
+			key:         key,
+		}
+		k.mu.Unlock()
+
+		return &HostKeyError{
+			Code:        HostKeyUnknown,
+			Host:        host,
+			KeyType:     key.Type(),
+			Fingerprint: fingerprint,
+		}
+	}
+}
+
+// Algorithms returns the host key algorithms to negotiate with a host so that
+// the server presents one of the keys that is already trusted
+func (k *KnownHosts) Algorithms(hostname string) []string {
+	entries, err := k.store.FindKnownHosts(normalizeHost(hostname))
+	if err != nil {
+		return nil
+	}
+
+	var algorithms []string
+	for _, e := range entries {
+		if e.KeyType == ssh.KeyAlgoRSA {
+			algorithms = append(algorithms, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256)
+		}
+		algorithms = append(algorithms, e.KeyType)
+	}
+	return algorithms
+}
+
+func (k *KnownHosts) save(host string, key ssh.PublicKey) (*store.KnownHost, error) {
+	entries, err := k.store.FindKnownHosts(host)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.KeyType == key.Type() {
+			return nil, fmt.Errorf("a %s key is already trusted for %s; remove it first", e.KeyType, host)
+		}
+	}
+
+	known := &store.KnownHost{
+		Host:        host,
+		KeyType:     key.Type(),
+		Key:         key.Marshal(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+	}
+	if err := k.store.SaveKnownHost(known); err != nil {
+		return nil, fmt.Errorf("failed to save known host: %w", err)
+	}
+	return known, nil
+}
+
+// normalizeHost formats an address the way known_hosts files do: the port is
+// dropped when it is 22 and the host is bracketed otherwise
+func normalizeHost(address string) string {
+	return knownhosts.Normalize(address)
+}
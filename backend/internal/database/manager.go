@@ -1,70 +1,241 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+
 	"opendbm/internal/models"
+	"opendbm/internal/store"
+	"opendbm/internal/vault"
 )
 
 // Manager handles all database connections
 type Manager struct {
 	connections map[string]*ManagedConnection
-	sqlDriver   *SQLDriverImpl
+	drivers     map[string]Driver
+	env         DriverEnv
+	tunnels     *TunnelManager
+	queries     *QueryRegistry
+	store       *store.Store
+	vault       *vault.Vault
 	mu          sync.RWMutex
+	driversMu   sync.Mutex
 }
 
 // ManagedConnection wraps a connection with its metadata
 type ManagedConnection struct {
-	Config    models.ConnectionConfig
-	Status    string
-	CreatedAt time.Time
-	LastUsed  time.Time
-	Error     string
+	Config          models.ConnectionConfig
+	Status          string
+	CreatedAt       time.Time
+	LastUsed        time.Time
+	LastConnectedAt *time.Time
+	Error           string
+
+	// attempt is the reconnect in progress while Status is "connecting";
+	// dialMu keeps a superseded attempt from racing the next one
+	attempt *connectAttempt
+	dialMu  sync.Mutex
 }
 
-// NewManager creates a new connection manager
-func NewManager() *Manager {
-	return &Manager{
+// connectAttempt is a driver connect running outside the manager lock.
+// done is closed once err is set.
+type connectAttempt struct {
+	done chan struct{}
+	err  error
+}
+
+// errConnectionChanged is returned to callers waiting on a reconnect that
+// an update, disconnect or delete made obsolete
+var errConnectionChanged = errors.New("connection was changed while connecting")
+
+// NewManager creates a new connection manager and restores saved connections
+// from the store. Restored connections start out disconnected and are
+// reconnected lazily on first use.
+func NewManager(st *store.Store, v *vault.Vault) (*Manager, error) {
+	tunnels, err := NewTunnelManager(st, v)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
 		connections: make(map[string]*ManagedConnection),
-		sqlDriver:   NewSQLDriver(),
+		drivers:     make(map[string]Driver),
+		env:         DriverEnv{Secrets: v, Tunnels: tunnels},
+		tunnels:     tunnels,
+		queries:     NewQueryRegistry(),
+		store:       st,
+		vault:       v,
 	}
+
+	saved, err := st.ListConnections()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saved connections: %w", err)
+	}
+
+	for _, s := range saved {
+		// Move plaintext passwords saved by older versions into the vault
+		if s.Config.Password != "" || s.Config.SSLKey != "" {
+			if err := m.storeSecrets(&s.Config); err != nil {
+				return nil, err
+			}
+			if err := st.SaveConnection(s.Config); err != nil {
+				return nil, fmt.Errorf("failed to save connection: %w", err)
+			}
+		}
+		if s.Config.HasPassword {
+			s.Config.PasswordRef = secretRef(s.Config.ID, "password")
+		}
+		if s.Config.HasSSLKey {
+			s.Config.SSLKeyRef = secretRef(s.Config.ID, "sslKey")
+		}
+
+		m.connections[s.Config.ID] = &ManagedConnection{
+			Config:          s.Config,
+			Status:          "disconnected",
+			CreatedAt:       s.CreatedAt,
+			LastConnectedAt: s.LastConnectedAt,
+		}
+	}
+
+	return m, nil
 }
 
-// Connect creates a new database connection
+// Connect creates and saves a new database connection. The connection is
+// only published once it is up, so dialing happens without the manager lock.
 func (m *Manager) Connect(config models.ConnectionConfig) (*models.Connection, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	config.ID = uuid.New().String()
+	config.ClearPassword = false
+
+	driver, err := m.driverFor(config.Type)
+	if err != nil {
+		return nil, err
+	}
 
-	id, err := m.sqlDriver.Connect(config)
+	id, err := driver.Connect(config)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := m.storeSecrets(&config); err != nil {
+		driver.Disconnect(id)
+		return nil, err
+	}
+
+	if err := m.store.SaveConnection(config); err != nil {
+		driver.Disconnect(id)
+		m.deleteSecrets(id)
+		return nil, fmt.Errorf("failed to save connection: %w", err)
+	}
+
 	now := time.Now()
+	m.touch(id, now)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.connections[id] = &ManagedConnection{
-		Config:    config,
-		Status:    "connected",
-		CreatedAt: now,
-		LastUsed:  now,
+		Config:          config,
+		Status:          "connected",
+		CreatedAt:       now,
+		LastUsed:        now,
+		LastConnectedAt: &now,
 	}
-	m.connections[id].Config.ID = id
+	return m.connections[id].toModel(), nil
+}
 
-	return &models.Connection{
-		ConnectionConfig: m.connections[id].Config,
-		Status:           "connected",
-		CreatedAt:        now,
-	}, nil
+// Update replaces the saved config of a connection. A live connection is
+// closed so that the next use reconnects with the new settings.
+func (m *Manager) Update(id string, config models.ConnectionConfig) (*models.Connection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conn, exists := m.connections[id]
+	if !exists {
+		return nil, fmt.Errorf("connection not found: %s", id)
+	}
+
+	config.ID = id
+	switch {
+	case config.Password != "":
+	case config.ClearPassword:
+		if err := m.vault.Delete(secretRef(id, "password")); err != nil {
+			return nil, fmt.Errorf("failed to delete password: %w", err)
+		}
+		config.HasPassword = false
+		config.PasswordRef = ""
+	default:
+		config.HasPassword = conn.Config.HasPassword
+		config.PasswordRef = conn.Config.PasswordRef
+	}
+	config.ClearPassword = false
+
+	// A key path replaces a previously uploaded client key
+	switch {
+	case config.SSLKey != "":
+	case config.SSLKeyPath != "":
+		if err := m.vault.Delete(secretRef(id, "sslKey")); err != nil {
+			return nil, fmt.Errorf("failed to delete client key: %w", err)
+		}
+		config.HasSSLKey = false
+		config.SSLKeyRef = ""
+	default:
+		config.HasSSLKey = conn.Config.HasSSLKey
+		config.SSLKeyRef = conn.Config.SSLKeyRef
+	}
+
+	if err := m.storeSecrets(&config); err != nil {
+		return nil, err
+	}
+
+	if err := m.store.SaveConnection(config); err != nil {
+		return nil, fmt.Errorf("failed to save connection: %w", err)
+	}
+
+	m.disconnect(conn)
+	conn.Config = config
+	conn.Status = "disconnected"
+	conn.Error = ""
+	conn.attempt = nil
+
+	return conn.toModel(), nil
 }
 
 // TestConnection tests a database connection without storing it
 func (m *Manager) TestConnection(config models.ConnectionConfig) error {
-	id, err := m.sqlDriver.Connect(config)
+	// Testing an edited saved connection reuses its stored secrets
+	if config.ID != "" {
+		m.mu.RLock()
+		if conn, exists := m.connections[config.ID]; exists {
+			if config.Password == "" {
+				config.PasswordRef = conn.Config.PasswordRef
+			}
+			if config.SSLKey == "" && config.SSLKeyPath == "" {
+				config.SSLKeyRef = conn.Config.SSLKeyRef
+			}
+		}
+		m.mu.RUnlock()
+	}
+
+	// Never let a test connection replace a live one with the same ID
+	config.ID = ""
+
+	driver, err := m.driverFor(config.Type)
 	if err != nil {
 		return err
 	}
-	m.sqlDriver.Disconnect(id)
+
+	id, err := driver.Connect(config)
+	if err != nil {
+		return err
+	}
+	driver.Disconnect(id)
 	return nil
 }
 
@@ -73,15 +244,17 @@ func (m *Manager) Disconnect(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.connections[id]; !exists {
+	conn, exists := m.connections[id]
+	if !exists {
 		return fmt.Errorf("connection not found: %s", id)
 	}
 
-	if err := m.sqlDriver.Disconnect(id); err != nil {
+	if err := m.disconnect(conn); err != nil {
 		return err
 	}
 
-	m.connections[id].Status = "disconnected"
+	conn.Status = "disconnected"
+	conn.attempt = nil
 	return nil
 }
 
@@ -90,11 +263,18 @@ func (m *Manager) Delete(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.connections[id]; !exists {
+	conn, exists := m.connections[id]
+	if !exists {
 		return fmt.Errorf("connection not found: %s", id)
 	}
 
-	m.sqlDriver.Disconnect(id)
+	if err := m.store.DeleteConnection(id); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("failed to delete connection: %w", err)
+	}
+	m.deleteSecrets(id)
+
+	m.disconnect(conn)
+	conn.attempt = nil
 	delete(m.connections, id)
 	return nil
 }
@@ -109,32 +289,238 @@ func (m *Manager) GetConnection(id string) (*models.Connection, error) {
 		return nil, fmt.Errorf("connection not found: %s", id)
 	}
 
-	return &models.Connection{
-		ConnectionConfig: conn.Config,
-		Status:           conn.Status,
-		CreatedAt:        conn.CreatedAt,
-		Error:            conn.Error,
-	}, nil
+	return conn.toModel(), nil
 }
 
-// ListConnections returns all connections
+// ListConnections returns all connections ordered by creation time
 func (m *Manager) ListConnections() []models.Connection {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	result := make([]models.Connection, 0, len(m.connections))
 	for _, conn := range m.connections {
-		result = append(result, models.Connection{
-			ConnectionConfig: conn.Config,
-			Status:           conn.Status,
-			CreatedAt:        conn.CreatedAt,
-			Error:            conn.Error,
-		})
+		result = append(result, *conn.toModel())
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
 	return result
 }
 
-// GetSQLDriver returns the SQL driver for query execution
-func (m *Manager) GetSQLDriver() *SQLDriverImpl {
-	return m.sqlDriver
+// Tunnels returns the SSH tunnel manager
+func (m *Manager) Tunnels() *TunnelManager {
+	return m.tunnels
+}
+
+// Queries returns the registry of running queries
+func (m *Manager) Queries() *QueryRegistry {
+	return m.queries
+}
+
+// StartQuery registers a query on a connection and returns the context it
+// must run under. A zero timeout falls back to the connection's query timeout.
+func (m *Manager) StartQuery(parent context.Context, connectionID string, queryID string, sql string, timeout time.Duration) (context.Context, string, func(), error) {
+	if timeout <= 0 {
+		m.mu.RLock()
+		if conn, exists := m.connections[connectionID]; exists {
+			timeout = time.Duration(conn.Config.QueryTimeout) * time.Second
+		}
+		m.mu.RUnlock()
+	}
+	return m.queries.Start(parent, queryID, connectionID, sql, timeout)
+}
+
+// TunnelInUse reports whether any saved connection routes through a tunnel
+func (m *Manager) TunnelInUse(tunnelID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, conn := range m.connections {
+		for _, id := range sshChain(conn.Config) {
+			if id == tunnelID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Driver returns the driver for a connection, reconnecting it first if it
+// was restored from the store or previously disconnected. Callers check for
+// optional capabilities such as SQLDriver with a type assertion.
+func (m *Manager) Driver(id string) (Driver, error) {
+	if err := m.ensureConnected(id); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	conn, exists := m.connections[id]
+	m.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("connection not found: %s", id)
+	}
+
+	return m.driverFor(conn.Config.Type)
+}
+
+// TypeCapabilities returns the capabilities of the driver registered for a type
+func (m *Manager) TypeCapabilities(dbType string) ([]string, error) {
+	driver, err := m.driverFor(dbType)
+	if err != nil {
+		return nil, err
+	}
+	return Capabilities(driver), nil
+}
+
+// driverFor returns the driver registered for a database type, creating it on first use
+func (m *Manager) driverFor(dbType string) (Driver, error) {
+	m.driversMu.Lock()
+	defer m.driversMu.Unlock()
+
+	if driver, exists := m.drivers[dbType]; exists {
+		return driver, nil
+	}
+
+	factory, exists := lookupFactory(dbType)
+	if !exists {
+		return nil, fmt.Errorf("unsupported database type: %s", dbType)
+	}
+
+	driver := factory(m.env)
+	m.drivers[dbType] = driver
+	return driver, nil
+}
+
+// disconnect closes the driver connection of a saved connection; callers must hold m.mu
+func (m *Manager) disconnect(conn *ManagedConnection) error {
+	driver, err := m.driverFor(conn.Config.Type)
+	if err != nil {
+		return nil
+	}
+	return driver.Disconnect(conn.Config.ID)
+}
+
+// ensureConnected opens the driver connection for a saved connection if it
+// is not currently connected. The connection is marked "connecting" while
+// the driver dials without the manager lock, so that a slow or unreachable
+// host holds up only the callers of that connection, which wait for the
+// one attempt in progress.
+func (m *Manager) ensureConnected(id string) error {
+	m.mu.Lock()
+	conn, exists := m.connections[id]
+	if !exists {
+		m.mu.Unlock()
+		return fmt.Errorf("connection not found: %s", id)
+	}
+	if conn.Status == "connected" {
+		conn.LastUsed = time.Now()
+		m.mu.Unlock()
+		return nil
+	}
+	if attempt := conn.attempt; attempt != nil {
+		m.mu.Unlock()
+		<-attempt.done
+		return attempt.err
+	}
+
+	attempt := &connectAttempt{done: make(chan struct{})}
+	conn.attempt = attempt
+	conn.Status = "connecting"
+	conn.Error = ""
+	config := conn.Config
+	m.mu.Unlock()
+
+	conn.dialMu.Lock()
+	defer conn.dialMu.Unlock()
+	defer close(attempt.done)
+
+	driver, err := m.driverFor(config.Type)
+	if err == nil {
+		_, err = driver.Connect(config)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// An update, disconnect or delete while dialing has taken the
+	// connection over; drop what was opened with the old settings
+	if conn.attempt != attempt || m.connections[id] != conn {
+		if err == nil {
+			driver.Disconnect(id)
+		}
+		attempt.err = errConnectionChanged
+		return attempt.err
+	}
+	conn.attempt = nil
+
+	if err != nil {
+		conn.Status = "error"
+		conn.Error = err.Error()
+		attempt.err = err
+		return err
+	}
+
+	now := time.Now()
+	m.touch(id, now)
+	conn.Status = "connected"
+	conn.LastUsed = now
+	conn.LastConnectedAt = &now
+	return nil
+}
+
+// touch records when a connection last connected
+func (m *Manager) touch(id string, at time.Time) {
+	if err := m.store.TouchConnection(id, at); err != nil {
+		log.Printf("Failed to record connect time of connection %s: %v", id, err)
+	}
+}
+
+// storeSecrets moves a plaintext password and client key from the config
+// into the vault
+func (m *Manager) storeSecrets(config *models.ConnectionConfig) error {
+	extractURICredentials(config)
+
+	if config.Password != "" {
+		ref := secretRef(config.ID, "password")
+		if err := m.vault.Put(ref, config.Password); err != nil {
+			return fmt.Errorf("failed to store password: %w", err)
+		}
+		config.Password = ""
+		config.HasPassword = true
+		config.PasswordRef = ref
+	}
+
+	if config.SSLKey != "" {
+		ref := secretRef(config.ID, "sslKey")
+		if err := m.vault.Put(ref, config.SSLKey); err != nil {
+			return fmt.Errorf("failed to store client key: %w", err)
+		}
+		config.SSLKey = ""
+		config.HasSSLKey = true
+		config.SSLKeyRef = ref
+	}
+	return nil
+}
+
+func (m *Manager) deleteSecrets(id string) {
+	m.vault.Delete(secretRef(id, "password"))
+	m.vault.Delete(secretRef(id, "sslKey"))
+}
+
+func secretRef(id string, name string) string {
+	return "connection/" + id + "/" + name
+}
+
+func (c *ManagedConnection) toModel() *models.Connection {
+	config := c.Config
+	config.Password = ""
+	config.SSLKey = ""
+
+	return &models.Connection{
+		ConnectionConfig: config,
+		Status:           c.Status,
+		CreatedAt:        c.CreatedAt,
+		LastConnectedAt:  c.LastConnectedAt,
+		Error:            c.Error,
+	}
 }
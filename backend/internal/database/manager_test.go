@@ -0,0 +1,167 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"opendbm/internal/models"
+	"opendbm/internal/store"
+	"opendbm/internal/vault"
+)
+
+// blockingDriver holds every Connect until release is closed
+type blockingDriver struct {
+	mu          sync.Mutex
+	release     chan struct{}
+	connects    int
+	connected   map[string]bool
+	disconnects int
+}
+
+var testBlockingDriver = &blockingDriver{connected: map[string]bool{}}
+
+func init() {
+	Register("blocking-test", func(env DriverEnv) Driver { return testBlockingDriver })
+}
+
+func (d *blockingDriver) Connect(config models.ConnectionConfig) (string, error) {
+	d.mu.Lock()
+	release := d.release
+	d.connects++
+	d.mu.Unlock()
+
+	<-release
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.connected[config.ID] = true
+	return config.ID, nil
+}
+
+func (d *blockingDriver) Disconnect(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.connected[id] {
+		d.disconnects++
+	}
+	delete(d.connected, id)
+	return nil
+}
+
+func (d *blockingDriver) Ping(id string) error {
+	return nil
+}
+
+// reset starts a test with Connect blocked and no connections
+func (d *blockingDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.release = make(chan struct{})
+	d.connects = 0
+	d.connected = map[string]bool{}
+	d.disconnects = 0
+}
+
+// newTestManager returns a manager with one saved, disconnected connection
+// of the blocking test driver
+func newTestManager(t *testing.T) (*Manager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	st, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	v, err := vault.Open(st, vault.Options{KeyFile: filepath.Join(dir, "master.key")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := "saved"
+	if err := st.SaveConnection(models.ConnectionConfig{ID: id, Name: "slow", Type: "blocking-test"}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(st, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, id
+}
+
+// waitForStatus polls until a connection reaches a status
+func waitForStatus(t *testing.T, m *Manager, id string, status string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := m.GetConnection(id)
+		if err == nil && conn.Status == status {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("connection never reached status %q", status)
+}
+
+func TestReconnectDoesNotBlockManager(t *testing.T) {
+	testBlockingDriver.reset()
+	m, id := newTestManager(t)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := m.Driver(id)
+			errs <- err
+		}()
+	}
+	waitForStatus(t, m, id, "connecting")
+
+	listed := make(chan []models.Connection)
+	go func() { listed <- m.ListConnections() }()
+	select {
+	case conns := <-listed:
+		if len(conns) != 1 || conns[0].Status != "connecting" {
+			t.Fatalf("ListConnections() = %+v, want one connecting connection", conns)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ListConnections blocked on a reconnect in progress")
+	}
+
+	close(testBlockingDriver.release)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Driver() error = %v", err)
+		}
+	}
+	if testBlockingDriver.connects != 1 {
+		t.Errorf("driver connected %d times, want 1 attempt shared by both callers", testBlockingDriver.connects)
+	}
+	waitForStatus(t, m, id, "connected")
+}
+
+func TestReconnectSupersededByDisconnect(t *testing.T) {
+	testBlockingDriver.reset()
+	m, id := newTestManager(t)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := m.Driver(id)
+		errs <- err
+	}()
+	waitForStatus(t, m, id, "connecting")
+
+	if err := m.Disconnect(id); err != nil {
+		t.Fatal(err)
+	}
+	close(testBlockingDriver.release)
+
+	if err := <-errs; !errors.Is(err, errConnectionChanged) {
+		t.Fatalf("Driver() error = %v, want %v", err, errConnectionChanged)
+	}
+	if testBlockingDriver.disconnects != 1 || len(testBlockingDriver.connected) != 0 {
+		t.Errorf("connection opened with stale settings was not closed")
+	}
+	waitForStatus(t, m, id, "disconnected")
+}
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	mssql "github.com/microsoft/go-mssqldb"
+
+	"opendbm/internal/models"
+)
+
+// messageSink collects the messages the server sends while statements run,
+// such as PostgreSQL notices
+type messageSink struct {
+	mu       sync.Mutex
+	messages []models.ServerMessage
+}
+
+func (s *messageSink) add(messages ...models.ServerMessage) {
+	s.mu.Lock()
+	s.messages = append(s.messages, messages...)
+	s.mu.Unlock()
+}
+
+// take returns the messages collected so far and clears them
+func (s *messageSink) take() []models.ServerMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := s.messages
+	s.messages = nil
+	return messages
+}
+
+type messageSinkKey struct{}
+
+// withMessages returns a context under which statements report the
+// server's messages to the returned sink
+func withMessages(ctx context.Context) (context.Context, *messageSink) {
+	sink := &messageSink{}
+	return context.WithValue(ctx, messageSinkKey{}, sink), sink
+}
+
+func messagesFrom(ctx context.Context) *messageSink {
+	sink, _ := ctx.Value(messageSinkKey{}).(*messageSink)
+	return sink
+}
+
+// statementMessages returns the messages of the statement that just ran on
+// q: those collected in the context's sink and, for MySQL, the warnings it
+// left, which the server only reports when asked. A failed statement's
+// warnings are left out, as they repeat its error.
+func statementMessages(ctx context.Context, q queryer, dbType string, err error) []models.ServerMessage {
+	sink := messagesFrom(ctx)
+	if sink == nil {
+		return nil
+	}
+	if dbType == "mysql" && err == nil && ctx.Err() == nil {
+		sink.add(mysqlWarnings(ctx, q)...)
+	}
+	return sink.take()
+}
+
+func mysqlWarnings(ctx context.Context, q queryer) []models.ServerMessage {
+	rows, err := q.QueryContext(ctx, "SHOW WARNINGS")
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var messages []models.ServerMessage
+	for rows.Next() {
+		var level, message string
+		var code int64
+		if err := rows.Scan(&level, &code, &message); err != nil {
+			return messages
+		}
+		messages = append(messages, models.ServerMessage{
+			Level:   strings.ToLower(level),
+			Code:    strconv.FormatInt(code, 10),
+			Message: message,
+		})
+	}
+	return messages
+}
+
+// noticeRouter hands PostgreSQL notices, which pgx reports per connection,
+// to the sink of the statement running on that connection
+type noticeRouter struct {
+	mu    sync.Mutex
+	sinks map[*pgconn.PgConn]*messageSink
+}
+
+func newNoticeRouter() *noticeRouter {
+	return &noticeRouter{sinks: make(map[*pgconn.PgConn]*messageSink)}
+}
+
+// notice is the pgconn.NoticeHandler of every PostgreSQL connection
+func (r *noticeRouter) notice(conn *pgconn.PgConn, n *pgconn.Notice) {
+	r.mu.Lock()
+	sink := r.sinks[conn]
+	r.mu.Unlock()
+
+	if sink != nil {
+		sink.add(models.ServerMessage{
+			Level:   strings.ToLower(n.Severity),
+			Code:    n.Code,
+			Message: n.Message,
+		})
+	}
+}
+
+// attach routes the notices of conn to the context's sink until the
+// returned function is called
+func (r *noticeRouter) attach(ctx context.Context, conn *sql.Conn) (detach func()) {
+	sink := messagesFrom(ctx)
+	if sink == nil {
+		return func() {}
+	}
+
+	var pgConn *pgconn.PgConn
+	conn.Raw(func(driverConn interface{}) error {
+		if c, ok := driverConn.(*stdlib.Conn); ok {
+			pgConn = c.Conn().PgConn()
+		}
+		return nil
+	})
+	if pgConn == nil {
+		return func() {}
+	}
+
+	r.mu.Lock()
+	r.sinks[pgConn] = sink
+	r.mu.Unlock()
+	return func() {
+		r.mu.Lock()
+		delete(r.sinks, pgConn)
+		r.mu.Unlock()
+	}
+}
+
+// sqlServerMessage converts an informational message, such as the output
+// of PRINT, from the SQL Server driver
+func sqlServerMessage(m interface{ String() string }) models.ServerMessage {
+	msg, ok := m.(mssql.Error)
+	if !ok {
+		return models.ServerMessage{Level: "info", Message: m.String()}
+	}
+	level := "info"
+	if msg.Class > 10 {
+		level = "error"
+	}
+	return models.ServerMessage{
+		Level:   level,
+		Code:    strconv.Itoa(int(msg.Number)),
+		Message: msg.Message,
+	}
+}
@@ -0,0 +1,695 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"opendbm/internal/models"
+)
+
+const (
+	mongoTimeout      = 30 * time.Second
+	defaultFindLimit  = 100
+	maxFindLimit      = 10000
+	mongoConnectLimit = 10 * time.Second
+)
+
+// MongoDriverImpl implements DocumentDriver for MongoDB
+type MongoDriverImpl struct {
+	clients   map[string]*mongo.Client
+	databases map[string]string
+	forwards  map[string]*Forward
+	secrets   SecretResolver
+	tunnels   *TunnelManager
+	mu        sync.RWMutex
+}
+
+var _ DocumentDriver = (*MongoDriverImpl)(nil)
+
+func init() {
+	Register("mongodb", func(env DriverEnv) Driver {
+		return NewMongoDriver(env.Secrets, env.Tunnels)
+	})
+}
+
+// NewMongoDriver creates a new MongoDB driver instance
+func NewMongoDriver(secrets SecretResolver, tunnels *TunnelManager) *MongoDriverImpl {
+	return &MongoDriverImpl{
+		clients:   make(map[string]*mongo.Client),
+		databases: make(map[string]string),
+		forwards:  make(map[string]*Forward),
+		secrets:   secrets,
+		tunnels:   tunnels,
+	}
+}
+
+// Connect establishes a new MongoDB connection from a connection string
+// (including mongodb+srv://) or from host, port and credentials
+func (d *MongoDriverImpl) Connect(config models.ConnectionConfig) (string, error) {
+	id := config.ID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	password, err := resolvePassword(d.secrets, config)
+	if err != nil {
+		return "", err
+	}
+
+	tlsConfig, err := buildTLSConfig(d.secrets, config, config.Host)
+	if err != nil {
+		return "", err
+	}
+
+	opts := options.Client().
+		SetConnectTimeout(mongoConnectLimit).
+		SetServerSelectionTimeout(mongoConnectLimit)
+
+	var forward *Forward
+	if config.URI != "" {
+		if config.UseSSH {
+			return "", fmt.Errorf("ssh tunnels require host and port instead of a connection string")
+		}
+		opts.ApplyURI(uriWithPassword(config.URI, password))
+		if err := opts.Validate(); err != nil {
+			return "", fmt.Errorf("invalid connection string: %w", err)
+		}
+	} else {
+		host, port := config.Host, config.Port
+		if port == 0 {
+			port = 27017
+		}
+		if config.UseSSH {
+			forward, err = openForward(d.tunnels, config)
+			if err != nil {
+				return "", err
+			}
+			host, port = forward.LocalAddr()
+		}
+
+		// A tunnel reaches a single member, so skip replica set discovery
+		opts.SetHosts([]string{host + ":" + strconv.Itoa(port)}).SetDirect(forward != nil)
+		if config.Username != "" {
+			authSource := config.AuthSource
+			if authSource == "" {
+				authSource = "admin"
+			}
+			opts.SetAuth(options.Credential{
+				Username:   config.Username,
+				Password:   password,
+				AuthSource: authSource,
+			})
+		}
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	closeForward := func() {
+		if forward != nil {
+			forward.Close()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mongoConnectLimit)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		closeForward()
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	if err := client.Ping(ctx, readpref.PrimaryPreferred()); err != nil {
+		client.Disconnect(context.Background())
+		closeForward()
+		return "", fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	database := config.Database
+	if database == "" && config.URI != "" {
+		if cs := opts.GetURI(); cs != "" {
+			database = uriDatabase(cs)
+		}
+	}
+
+	d.mu.Lock()
+	d.close(id)
+	d.clients[id] = client
+	d.databases[id] = database
+	if forward != nil {
+		d.forwards[id] = forward
+	}
+	d.mu.Unlock()
+
+	return id, nil
+}
+
+// Disconnect closes a MongoDB connection
+func (d *MongoDriverImpl) Disconnect(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.close(id)
+	return nil
+}
+
+// close releases a client and its SSH forward; callers must hold d.mu
+func (d *MongoDriverImpl) close(id string) {
+	if client, exists := d.clients[id]; exists {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		client.Disconnect(ctx)
+		cancel()
+	}
+	if forward, exists := d.forwards[id]; exists {
+		forward.Close()
+	}
+
+	delete(d.clients, id)
+	delete(d.databases, id)
+	delete(d.forwards, id)
+}
+
+// Ping checks if connection is alive
+func (d *MongoDriverImpl) Ping(id string) error {
+	client, err := d.client(id)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mongoConnectLimit)
+	defer cancel()
+	return client.Ping(ctx, readpref.PrimaryPreferred())
+}
+
+// ListDatabases lists the databases the user is authorized for
+func (d *MongoDriverImpl) ListDatabases(id string) ([]string, error) {
+	return d.listDatabases(context.Background(), id)
+}
+
+func (d *MongoDriverImpl) listDatabases(ctx context.Context, id string) ([]string, error) {
+	client, err := d.client(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+
+	names, err := client.ListDatabaseNames(ctx, bson.D{}, options.ListDatabases().SetAuthorizedDatabases(true))
+	if err != nil {
+		// Users restricted to one database may not run listDatabases at all
+		d.mu.RLock()
+		database := d.databases[id]
+		d.mu.RUnlock()
+		if database != "" {
+			return []string{database}, nil
+		}
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListCollections lists the collections of a database
+func (d *MongoDriverImpl) ListCollections(id string, database string) ([]string, error) {
+	return d.listCollections(context.Background(), id, database)
+}
+
+func (d *MongoDriverImpl) listCollections(ctx context.Context, id string, database string) ([]string, error) {
+	client, err := d.client(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+
+	names, err := client.Database(database).ListCollectionNames(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// FindDocuments runs a find and returns each document as Extended JSON
+func (d *MongoDriverImpl) FindDocuments(ctx context.Context, id string, req models.DocumentFindRequest) ([]json.RawMessage, error) {
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+
+	cursor, limit, err := d.find(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	return cursorToExtJSON(ctx, cursor, limit, req.Canonical)
+}
+
+// find starts a find capped at maxFindLimit documents and returns the
+// limit it applied. The cursor holds one document past the limit so
+// callers can tell whether more matched.
+func (d *MongoDriverImpl) find(ctx context.Context, id string, req models.DocumentFindRequest) (*mongo.Cursor, int, error) {
+	coll, err := d.collection(id, req.Database, req.Collection)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter, err := parseExtJSON(req.Filter, "filter")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultFindLimit
+	}
+	if limit > maxFindLimit {
+		limit = maxFindLimit
+	}
+
+	opts := options.Find().SetLimit(limit + 1)
+	if req.Skip > 0 {
+		opts.SetSkip(req.Skip)
+	}
+	if len(req.Projection) > 0 {
+		projection, err := parseExtJSON(req.Projection, "projection")
+		if err != nil {
+			return nil, 0, err
+		}
+		opts.SetProjection(projection)
+	}
+	if len(req.Sort) > 0 {
+		sortDoc, err := parseExtJSON(req.Sort, "sort")
+		if err != nil {
+			return nil, 0, err
+		}
+		opts.SetSort(sortDoc)
+	}
+
+	cursor, err := coll.Find(ctx, filter, opts)
+	return cursor, int(limit), err
+}
+
+// InsertOne inserts a single document
+func (d *MongoDriverImpl) InsertOne(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error) {
+	coll, err := d.collection(id, req.Database, req.Collection)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Document) == 0 {
+		return nil, fmt.Errorf("document is required")
+	}
+	doc, err := parseExtJSON(req.Document, "document")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	res, err := coll.InsertOne(ctx, doc)
+	if err != nil {
+		return errorResult(start, err), nil
+	}
+	return summaryResult(bson.D{{Key: "insertedId", Value: res.InsertedID}}, req.Canonical, start)
+}
+
+// InsertMany inserts several documents in order
+func (d *MongoDriverImpl) InsertMany(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error) {
+	coll, err := d.collection(id, req.Database, req.Collection)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Documents) == 0 {
+		return nil, fmt.Errorf("documents are required")
+	}
+	docs := make([]interface{}, len(req.Documents))
+	for i, data := range req.Documents {
+		doc, err := parseExtJSON(data, fmt.Sprintf("document %d", i+1))
+		if err != nil {
+			return nil, err
+		}
+		docs[i] = doc
+	}
+
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	res, err := coll.InsertMany(ctx, docs)
+	if err != nil {
+		return errorResult(start, err), nil
+	}
+	return summaryResult(bson.D{
+		{Key: "insertedCount", Value: len(res.InsertedIDs)},
+		{Key: "insertedIds", Value: res.InsertedIDs},
+	}, req.Canonical, start)
+}
+
+// UpdateOne updates the first document matching the filter
+func (d *MongoDriverImpl) UpdateOne(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error) {
+	return d.update(ctx, id, req, false)
+}
+
+// UpdateMany updates every document matching the filter
+func (d *MongoDriverImpl) UpdateMany(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error) {
+	return d.update(ctx, id, req, true)
+}
+
+func (d *MongoDriverImpl) update(ctx context.Context, id string, req models.DocumentWriteRequest, many bool) (*models.QueryResult, error) {
+	coll, err := d.collection(id, req.Database, req.Collection)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := requiredFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Update) == 0 {
+		return nil, fmt.Errorf("update is required")
+	}
+
+	// An update is either an update document or an aggregation pipeline
+	var update interface{}
+	if trimmed := bytes.TrimSpace(req.Update); len(trimmed) > 0 && trimmed[0] == '[' {
+		update, err = parseExtJSONArray(req.Update, "update pipeline")
+	} else {
+		update, err = parseExtJSON(req.Update, "update")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	opts := options.Update().SetUpsert(req.Upsert)
+	var res *mongo.UpdateResult
+	if many {
+		res, err = coll.UpdateMany(ctx, filter, update, opts)
+	} else {
+		res, err = coll.UpdateOne(ctx, filter, update, opts)
+	}
+	if err != nil {
+		return errorResult(start, err), nil
+	}
+	return updateSummary(res, req.Canonical, start)
+}
+
+// ReplaceOne replaces the first document matching the filter
+func (d *MongoDriverImpl) ReplaceOne(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error) {
+	coll, err := d.collection(id, req.Database, req.Collection)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := requiredFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Document) == 0 {
+		return nil, fmt.Errorf("replacement document is required")
+	}
+	replacement, err := parseExtJSON(req.Document, "replacement document")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	res, err := coll.ReplaceOne(ctx, filter, replacement, options.Replace().SetUpsert(req.Upsert))
+	if err != nil {
+		return errorResult(start, err), nil
+	}
+	return updateSummary(res, req.Canonical, start)
+}
+
+// DeleteOne deletes the first document matching the filter
+func (d *MongoDriverImpl) DeleteOne(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error) {
+	return d.delete(ctx, id, req, false)
+}
+
+// DeleteMany deletes every document matching the filter
+func (d *MongoDriverImpl) DeleteMany(ctx context.Context, id string, req models.DocumentWriteRequest) (*models.QueryResult, error) {
+	return d.delete(ctx, id, req, true)
+}
+
+func (d *MongoDriverImpl) delete(ctx context.Context, id string, req models.DocumentWriteRequest, many bool) (*models.QueryResult, error) {
+	coll, err := d.collection(id, req.Database, req.Collection)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := requiredFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	var res *mongo.DeleteResult
+	if many {
+		res, err = coll.DeleteMany(ctx, filter)
+	} else {
+		res, err = coll.DeleteOne(ctx, filter)
+	}
+	if err != nil {
+		return errorResult(start, err), nil
+	}
+	return summaryResult(bson.D{{Key: "deletedCount", Value: res.DeletedCount}}, req.Canonical, start)
+}
+
+// Aggregate runs an aggregation pipeline against a collection
+func (d *MongoDriverImpl) Aggregate(ctx context.Context, id string, req models.DocumentAggregateRequest) (*models.QueryResult, error) {
+	coll, err := d.collection(id, req.Database, req.Collection)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := parseExtJSONArray(req.Pipeline, "pipeline")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return errorResult(start, err), nil
+	}
+	defer cursor.Close(ctx)
+
+	return documentsResult(ctx, cursor, maxFindLimit, req.Canonical, start)
+}
+
+func (d *MongoDriverImpl) client(id string) (*mongo.Client, error) {
+	d.mu.RLock()
+	client, exists := d.clients[id]
+	d.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("connection not found")
+	}
+	return client, nil
+}
+
+func (d *MongoDriverImpl) collection(id string, database string, collection string) (*mongo.Collection, error) {
+	client, err := d.client(id)
+	if err != nil {
+		return nil, err
+	}
+	if database == "" || collection == "" {
+		return nil, fmt.Errorf("database and collection are required")
+	}
+	return client.Database(database).Collection(collection), nil
+}
+
+// parseExtJSON decodes an Extended JSON document, keeping key order so that
+// sort specifications stay meaningful. An empty input is an empty document.
+func parseExtJSON(data json.RawMessage, what string) (bson.D, error) {
+	doc := bson.D{}
+	if len(data) == 0 || string(data) == "null" {
+		return doc, nil
+	}
+	if err := bson.UnmarshalExtJSON(data, false, &doc); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", what, err)
+	}
+	return doc, nil
+}
+
+// parseExtJSONArray decodes an Extended JSON array such as a pipeline
+func parseExtJSONArray(data json.RawMessage, what string) (bson.A, error) {
+	arr := bson.A{}
+	if len(data) == 0 || string(data) == "null" {
+		return arr, nil
+	}
+	if err := bson.UnmarshalExtJSON(data, false, &arr); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", what, err)
+	}
+	return arr, nil
+}
+
+// requiredFilter parses the filter of an update or delete. A missing filter
+// is rejected so that a forgotten field never touches a whole collection;
+// {} still matches every document.
+func requiredFilter(data json.RawMessage) (bson.D, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, fmt.Errorf("filter is required; use {} to match every document")
+	}
+	return parseExtJSON(data, "filter")
+}
+
+// operationContext bounds an operation by mongoTimeout unless the caller's
+// context already has a deadline, such as a query timeout
+func operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, mongoTimeout)
+}
+
+// cursorToExtJSON reads up to limit documents of a cursor as Extended JSON
+func cursorToExtJSON(ctx context.Context, cursor *mongo.Cursor, limit int, canonical bool) ([]json.RawMessage, error) {
+	documents := []json.RawMessage{}
+	for len(documents) < limit && cursor.Next(ctx) {
+		data, err := bson.MarshalExtJSON(cursor.Current, canonical, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode document: %w", err)
+		}
+		documents = append(documents, data)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// documentsResult reads up to limit documents of a cursor into a result
+// grid, marking it truncated when the cursor held more
+func documentsResult(ctx context.Context, cursor *mongo.Cursor, limit int, canonical bool, start time.Time) (*models.QueryResult, error) {
+	docs := []bson.Raw{}
+	for len(docs) < limit && cursor.Next(ctx) {
+		docs = append(docs, append(bson.Raw(nil), cursor.Current...))
+	}
+	truncated := len(docs) == limit && cursor.Next(ctx)
+	if err := cursor.Err(); err != nil {
+		return errorResult(start, err), nil
+	}
+
+	result, err := rawDocumentsResult(docs, canonical, start)
+	if err != nil {
+		return nil, err
+	}
+	result.Truncated = truncated
+	return result, nil
+}
+
+// rawDocumentsResult lays documents out as a result grid. Columns are the
+// top-level fields in order of first appearance and every cell holds the
+// field's Extended JSON value.
+func rawDocumentsResult(docs []bson.Raw, canonical bool, start time.Time) (*models.QueryResult, error) {
+	columns := []string{}
+	seen := make(map[string]bool)
+	rows := make([]map[string]interface{}, 0, len(docs))
+
+	for _, doc := range docs {
+		elements, err := doc.Elements()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document: %w", err)
+		}
+		for _, e := range elements {
+			if key := e.Key(); !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+
+		data, err := bson.MarshalExtJSON(doc, canonical, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode document: %w", err)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("failed to encode document: %w", err)
+		}
+
+		row := make(map[string]interface{}, len(fields))
+		for key, value := range fields {
+			row[key] = value
+		}
+		rows = append(rows, row)
+	}
+
+	return &models.QueryResult{
+		Columns:       columns,
+		Rows:          rows,
+		RowCount:      len(rows),
+		ExecutionTime: time.Since(start).Milliseconds(),
+	}, nil
+}
+
+// summaryResult shows the outcome of a write or a scalar command as one row
+func summaryResult(fields bson.D, canonical bool, start time.Time) (*models.QueryResult, error) {
+	doc, err := bson.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return rawDocumentsResult([]bson.Raw{doc}, canonical, start)
+}
+
+func updateSummary(res *mongo.UpdateResult, canonical bool, start time.Time) (*models.QueryResult, error) {
+	return summaryResult(bson.D{
+		{Key: "matchedCount", Value: res.MatchedCount},
+		{Key: "modifiedCount", Value: res.ModifiedCount},
+		{Key: "upsertedCount", Value: res.UpsertedCount},
+		{Key: "upsertedId", Value: res.UpsertedID},
+	}, canonical, start)
+}
+
+// errorResult reports a failed operation the way ExecuteQuery reports a
+// failed statement
+func errorResult(start time.Time, err error) *models.QueryResult {
+	return &models.QueryResult{
+		Columns:       []string{},
+		Rows:          []map[string]interface{}{},
+		RowCount:      0,
+		ExecutionTime: time.Since(start).Milliseconds(),
+		Error:         err.Error(),
+	}
+}
+
+// uriDatabase returns the default database named in a connection string
+func uriDatabase(uri string) string {
+	_, _, tail, ok := splitURI(uri)
+	if !ok || len(tail) < 2 || tail[0] != '/' {
+		return ""
+	}
+	database := tail[1:]
+	for i, c := range database {
+		if c == '?' {
+			return database[:i]
+		}
+	}
+	return database
+}
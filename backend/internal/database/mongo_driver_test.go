@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func testCursor(t *testing.T, n int) *mongo.Cursor {
+	t.Helper()
+	docs := make([]interface{}, n)
+	for i := range docs {
+		docs[i] = bson.D{{Key: "n", Value: i}}
+	}
+	cursor, err := mongo.NewCursorFromDocuments(docs, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cursor
+}
+
+func TestDocumentsResultTruncated(t *testing.T) {
+	tests := []struct {
+		name      string
+		documents int
+		limit     int
+		rows      int
+		truncated bool
+	}{
+		{"empty", 0, 3, 0, false},
+		{"under the limit", 2, 3, 2, false},
+		{"at the limit", 3, 3, 3, false},
+		{"over the limit", 5, 3, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := documentsResult(context.Background(), testCursor(t, tt.documents), tt.limit, false, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.RowCount != tt.rows || result.Truncated != tt.truncated {
+				t.Errorf("documentsResult() = %d rows, truncated %v; want %d, %v", result.RowCount, result.Truncated, tt.rows, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestCursorToExtJSONLimit(t *testing.T) {
+	// find asks the server for one document past the limit
+	documents, err := cursorToExtJSON(context.Background(), testCursor(t, 4), 3, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 3 {
+		t.Errorf("cursorToExtJSON() returned %d documents, want 3", len(documents))
+	}
+}
+
+func TestOperationContext(t *testing.T) {
+	ctx, cancel := operationContext(context.Background())
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > mongoTimeout {
+		t.Errorf("operationContext() without a deadline = %v, %v; want one mongoTimeout away", deadline, ok)
+	}
+
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelParent()
+	ctx, cancel = operationContext(parent)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); time.Until(deadline) < 59*time.Minute {
+		t.Errorf("operationContext() shortened the caller's deadline to %v", deadline)
+	}
+
+	cancelParent()
+	if ctx.Err() == nil {
+		t.Error("operationContext() did not follow the caller's cancellation")
+	}
+}
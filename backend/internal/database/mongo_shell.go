@@ -0,0 +1,1042 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"opendbm/internal/models"
+)
+
+// shellCall is one method call of a shell command with its arguments
+// converted to Extended JSON
+type shellCall struct {
+	name string
+	args []json.RawMessage
+}
+
+// shellCommand is a parsed mongo shell command: "show <what>", a database
+// method such as db.runCommand(...) or a collection method with cursor
+// modifiers such as db.users.find(...).sort(...).limit(10)
+type shellCommand struct {
+	show       string
+	collection string
+	calls      []shellCall
+}
+
+// RunCommand runs a mongo shell style command against the request database
+func (d *MongoDriverImpl) RunCommand(ctx context.Context, id string, req models.DocumentCommandRequest) (*models.QueryResult, error) {
+	if _, err := d.client(id); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	cmd, err := parseShellCommand(req.Command)
+	if err != nil {
+		return errorResult(start, err), nil
+	}
+
+	var result *models.QueryResult
+	switch {
+	case cmd.show != "":
+		result, err = d.runShow(ctx, id, req, cmd.show)
+	case cmd.collection == "":
+		result, err = d.runDatabaseCall(ctx, id, req, cmd.calls)
+	default:
+		result, err = d.runCollectionCall(ctx, id, req, cmd.collection, cmd.calls)
+	}
+	if err != nil {
+		return errorResult(start, err), nil
+	}
+
+	result.ExecutionTime = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+func (d *MongoDriverImpl) runShow(ctx context.Context, id string, req models.DocumentCommandRequest, what string) (*models.QueryResult, error) {
+	var names []string
+	var err error
+	switch what {
+	case "dbs", "databases":
+		names, err = d.listDatabases(ctx, id)
+	case "collections", "tables":
+		if req.Database == "" {
+			return nil, fmt.Errorf("no database selected")
+		}
+		names, err = d.listCollections(ctx, id, req.Database)
+	default:
+		return nil, fmt.Errorf("unsupported command: show %s", what)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(names))
+	for i, name := range names {
+		values[i] = name
+	}
+	return valuesResult("name", values, req.Canonical)
+}
+
+func (d *MongoDriverImpl) runDatabaseCall(ctx context.Context, id string, req models.DocumentCommandRequest, calls []shellCall) (*models.QueryResult, error) {
+	if len(calls) != 1 {
+		return nil, fmt.Errorf("expected a single database method, e.g. db.runCommand({ping: 1})")
+	}
+	if req.Database == "" {
+		return nil, fmt.Errorf("no database selected")
+	}
+
+	client, err := d.client(id)
+	if err != nil {
+		return nil, err
+	}
+	db := client.Database(req.Database)
+	call := calls[0]
+
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+	start := time.Now()
+
+	switch call.name {
+	case "getName":
+		return valuesResult("name", []interface{}{req.Database}, req.Canonical)
+	case "getCollectionNames":
+		return d.runShow(ctx, id, req, "collections")
+	case "runCommand", "adminCommand":
+		if len(call.args) != 1 {
+			return nil, fmt.Errorf("%s expects a command document", call.name)
+		}
+		command, err := commandDocument(call.args[0])
+		if err != nil {
+			return nil, err
+		}
+		if call.name == "adminCommand" {
+			db = client.Database("admin")
+		}
+		raw, err := db.RunCommand(ctx, command).Raw()
+		if err != nil {
+			return nil, err
+		}
+		return rawDocumentsResult([]bson.Raw{raw}, req.Canonical, start)
+	case "stats":
+		raw, err := db.RunCommand(ctx, bson.D{{Key: "dbStats", Value: 1}}).Raw()
+		if err != nil {
+			return nil, err
+		}
+		return rawDocumentsResult([]bson.Raw{raw}, req.Canonical, start)
+	case "createCollection":
+		name, err := stringArg(call, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.CreateCollection(ctx, name); err != nil {
+			return nil, err
+		}
+		return summaryResult(bson.D{{Key: "ok", Value: 1}}, req.Canonical, start)
+	default:
+		return nil, fmt.Errorf("unsupported database method: %s", call.name)
+	}
+}
+
+func (d *MongoDriverImpl) runCollectionCall(ctx context.Context, id string, req models.DocumentCommandRequest, collection string, calls []shellCall) (*models.QueryResult, error) {
+	if len(calls) == 0 {
+		return nil, fmt.Errorf("expected a method call on %s, e.g. db.%s.find()", collection, collection)
+	}
+	if req.Database == "" {
+		return nil, fmt.Errorf("no database selected")
+	}
+
+	coll, err := d.collection(id, req.Database, collection)
+	if err != nil {
+		return nil, err
+	}
+	call, modifiers := calls[0], calls[1:]
+
+	switch call.name {
+	case "find", "findOne":
+		return d.runFind(ctx, id, req, collection, call, modifiers)
+	case "aggregate":
+		if err := noModifiers(call, modifiers); err != nil {
+			return nil, err
+		}
+		pipeline := json.RawMessage("[]")
+		switch {
+		case len(call.args) == 1 && bytes.HasPrefix(call.args[0], []byte("[")):
+			pipeline = call.args[0]
+		case len(call.args) > 0:
+			pipeline = json.RawMessage("[" + string(bytes.Join(rawBytes(call.args), []byte(","))) + "]")
+		}
+		return d.Aggregate(ctx, id, models.DocumentAggregateRequest{
+			Database:   req.Database,
+			Collection: collection,
+			Pipeline:   pipeline,
+			Canonical:  req.Canonical,
+		})
+	}
+
+	if err := noModifiers(call, modifiers); err != nil {
+		return nil, err
+	}
+
+	write := models.DocumentWriteRequest{
+		Database:   req.Database,
+		Collection: collection,
+		Canonical:  req.Canonical,
+	}
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+	start := time.Now()
+
+	switch call.name {
+	case "countDocuments", "count":
+		filter, err := parseExtJSON(optionalArg(call, 0), "filter")
+		if err != nil {
+			return nil, err
+		}
+		count, err := coll.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		return summaryResult(bson.D{{Key: "count", Value: count}}, req.Canonical, start)
+	case "estimatedDocumentCount":
+		count, err := coll.EstimatedDocumentCount(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return summaryResult(bson.D{{Key: "count", Value: count}}, req.Canonical, start)
+	case "distinct":
+		field, err := stringArg(call, 0)
+		if err != nil {
+			return nil, err
+		}
+		filter, err := parseExtJSON(optionalArg(call, 1), "filter")
+		if err != nil {
+			return nil, err
+		}
+		values, err := coll.Distinct(ctx, field, filter)
+		if err != nil {
+			return nil, err
+		}
+		return valuesResult(field, values, req.Canonical)
+	case "insertOne":
+		write.Document = optionalArg(call, 0)
+		return d.InsertOne(ctx, id, write)
+	case "insertMany":
+		if len(call.args) == 0 {
+			return nil, fmt.Errorf("insertMany expects an array of documents")
+		}
+		if err := json.Unmarshal(call.args[0], &write.Documents); err != nil {
+			return nil, fmt.Errorf("insertMany expects an array of documents")
+		}
+		return d.InsertMany(ctx, id, write)
+	case "updateOne", "updateMany", "replaceOne":
+		if len(call.args) < 2 {
+			return nil, fmt.Errorf("%s expects a filter and an update", call.name)
+		}
+		write.Filter = call.args[0]
+		if call.name == "replaceOne" {
+			write.Document = call.args[1]
+		} else {
+			write.Update = call.args[1]
+		}
+		if write.Upsert, err = upsertOption(optionalArg(call, 2)); err != nil {
+			return nil, err
+		}
+		switch call.name {
+		case "updateOne":
+			return d.UpdateOne(ctx, id, write)
+		case "updateMany":
+			return d.UpdateMany(ctx, id, write)
+		default:
+			return d.ReplaceOne(ctx, id, write)
+		}
+	case "deleteOne", "deleteMany":
+		if len(call.args) == 0 {
+			return nil, fmt.Errorf("%s expects a filter; use {} to match every document", call.name)
+		}
+		write.Filter = call.args[0]
+		if call.name == "deleteOne" {
+			return d.DeleteOne(ctx, id, write)
+		}
+		return d.DeleteMany(ctx, id, write)
+	case "drop":
+		if err := coll.Drop(ctx); err != nil {
+			return nil, err
+		}
+		return summaryResult(bson.D{{Key: "ok", Value: 1}}, req.Canonical, start)
+	default:
+		return nil, fmt.Errorf("unsupported collection method: %s", call.name)
+	}
+}
+
+// runFind runs find or findOne with cursor modifiers such as sort and limit
+func (d *MongoDriverImpl) runFind(ctx context.Context, id string, req models.DocumentCommandRequest, collection string, call shellCall, modifiers []shellCall) (*models.QueryResult, error) {
+	find := models.DocumentFindRequest{
+		Database:   req.Database,
+		Collection: collection,
+		Filter:     optionalArg(call, 0),
+		Projection: optionalArg(call, 1),
+		Canonical:  req.Canonical,
+	}
+	if call.name == "findOne" {
+		if err := noModifiers(call, modifiers); err != nil {
+			return nil, err
+		}
+		find.Limit = 1
+	}
+
+	count := false
+	for _, m := range modifiers {
+		switch m.name {
+		case "sort":
+			find.Sort = optionalArg(m, 0)
+		case "projection":
+			find.Projection = optionalArg(m, 0)
+		case "limit", "skip":
+			n, err := intArg(m, 0)
+			if err != nil {
+				return nil, err
+			}
+			if m.name == "limit" {
+				find.Limit = n
+			} else {
+				find.Skip = n
+			}
+		case "count":
+			count = true
+		case "toArray", "pretty":
+		default:
+			return nil, fmt.Errorf("unsupported cursor method: %s", m.name)
+		}
+	}
+
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+	start := time.Now()
+
+	if count {
+		coll, err := d.collection(id, req.Database, collection)
+		if err != nil {
+			return nil, err
+		}
+		filter, err := parseExtJSON(find.Filter, "filter")
+		if err != nil {
+			return nil, err
+		}
+		n, err := coll.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		return summaryResult(bson.D{{Key: "count", Value: n}}, req.Canonical, start)
+	}
+
+	cursor, limit, err := d.find(ctx, id, find)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	result, err := documentsResult(ctx, cursor, limit, req.Canonical, start)
+	if err == nil && call.name == "findOne" {
+		// findOne returns the first match; later ones are not dropped rows
+		result.Truncated = false
+	}
+	return result, err
+}
+
+// valuesResult lays out a list of values as a single column grid
+func valuesResult(column string, values []interface{}, canonical bool) (*models.QueryResult, error) {
+	start := time.Now()
+	docs := make([]bson.Raw, len(values))
+	for i, value := range values {
+		doc, err := bson.Marshal(bson.D{{Key: column, Value: value}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode result: %w", err)
+		}
+		docs[i] = doc
+	}
+	return rawDocumentsResult(docs, canonical, start)
+}
+
+func noModifiers(call shellCall, modifiers []shellCall) error {
+	if len(modifiers) > 0 {
+		return fmt.Errorf("%s does not support .%s()", call.name, modifiers[0].name)
+	}
+	return nil
+}
+
+func optionalArg(call shellCall, i int) json.RawMessage {
+	if i < len(call.args) {
+		return call.args[i]
+	}
+	return nil
+}
+
+func stringArg(call shellCall, i int) (string, error) {
+	var s string
+	if i >= len(call.args) || json.Unmarshal(call.args[i], &s) != nil {
+		return "", fmt.Errorf("%s expects a string as argument %d", call.name, i+1)
+	}
+	return s, nil
+}
+
+func intArg(call shellCall, i int) (int64, error) {
+	var n int64
+	if i >= len(call.args) || json.Unmarshal(call.args[i], &n) != nil {
+		return 0, fmt.Errorf("%s expects an integer as argument %d", call.name, i+1)
+	}
+	return n, nil
+}
+
+// commandDocument accepts {ping: 1} as well as the shorthand "ping"
+func commandDocument(arg json.RawMessage) (bson.D, error) {
+	var name string
+	if json.Unmarshal(arg, &name) == nil {
+		return bson.D{{Key: name, Value: 1}}, nil
+	}
+	return parseExtJSON(arg, "command")
+}
+
+func upsertOption(arg json.RawMessage) (bool, error) {
+	if len(arg) == 0 {
+		return false, nil
+	}
+	var opts struct {
+		Upsert bool `json:"upsert"`
+	}
+	if err := json.Unmarshal(arg, &opts); err != nil {
+		return false, fmt.Errorf("invalid options: %w", err)
+	}
+	return opts.Upsert, nil
+}
+
+func rawBytes(args []json.RawMessage) [][]byte {
+	result := make([][]byte, len(args))
+	for i, arg := range args {
+		result[i] = arg
+	}
+	return result
+}
+
+// parseShellCommand parses a single mongo shell command. Arguments are
+// JavaScript literals: unquoted keys, single quoted strings, regular
+// expressions and constructors such as ObjectId("...") or ISODate("...").
+func parseShellCommand(input string) (*shellCommand, error) {
+	p := &shellParser{src: input}
+	cmd := &shellCommand{}
+
+	p.skipSpace()
+	switch word := p.ident(); word {
+	case "show":
+		p.skipSpace()
+		cmd.show = p.ident()
+		if cmd.show == "" {
+			return nil, p.errorf("expected dbs or collections after show")
+		}
+	case "db":
+		if err := p.parseChain(cmd); err != nil {
+			return nil, err
+		}
+	case "":
+		return nil, p.errorf("expected a command starting with db. or show")
+	default:
+		return nil, fmt.Errorf("unsupported command: %s", word)
+	}
+
+	p.skipSpace()
+	p.consume(';')
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected input; run one command at a time")
+	}
+	return cmd, nil
+}
+
+type shellParser struct {
+	src string
+	pos int
+}
+
+func (p *shellParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), p.pos+1)
+}
+
+func (p *shellParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *shellParser) consume(c byte) bool {
+	if p.peek() == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *shellParser) expect(c byte) error {
+	if !p.consume(c) {
+		return p.errorf("expected %q", c)
+	}
+	return nil
+}
+
+func (p *shellParser) skipSpace() {
+	for p.pos < len(p.src) {
+		rest := p.src[p.pos:]
+		switch {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r':
+			p.pos++
+		case strings.HasPrefix(rest, "//"):
+			if end := strings.IndexByte(rest, '\n'); end >= 0 {
+				p.pos += end + 1
+			} else {
+				p.pos = len(p.src)
+			}
+		case strings.HasPrefix(rest, "/*"):
+			if end := strings.Index(rest[2:], "*/"); end >= 0 {
+				p.pos += end + 4
+			} else {
+				p.pos = len(p.src)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (p *shellParser) ident() string {
+	start := p.pos
+	if !isIdentStart(p.peek()) {
+		return ""
+	}
+	for p.pos < len(p.src) && (isIdentStart(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// parseChain parses the members after "db". Plain members name the
+// collection (which may contain dots), calls are the method and its
+// cursor modifiers.
+func (p *shellParser) parseChain(cmd *shellCommand) error {
+	var path []string
+	for {
+		p.skipSpace()
+		var name string
+		switch {
+		case p.consume('.'):
+			p.skipSpace()
+			if name = p.ident(); name == "" {
+				return p.errorf("expected a name after '.'")
+			}
+		case p.consume('['):
+			p.skipSpace()
+			s, err := p.parseString()
+			if err != nil {
+				return err
+			}
+			p.skipSpace()
+			if err := p.expect(']'); err != nil {
+				return err
+			}
+			name = s
+		default:
+			cmd.collection = strings.Join(path, ".")
+			if len(cmd.calls) == 0 {
+				return p.errorf("expected a method call")
+			}
+			return nil
+		}
+
+		p.skipSpace()
+		if p.peek() != '(' {
+			if len(cmd.calls) > 0 {
+				return p.errorf("unexpected property %s", name)
+			}
+			path = append(path, name)
+			continue
+		}
+
+		args, err := p.parseArgs()
+		if err != nil {
+			return err
+		}
+		if name == "getCollection" && len(path) == 0 && len(cmd.calls) == 0 {
+			collection, err := stringArg(shellCall{name: name, args: args}, 0)
+			if err != nil {
+				return err
+			}
+			path = append(path, collection)
+			continue
+		}
+		cmd.calls = append(cmd.calls, shellCall{name: name, args: args})
+	}
+}
+
+func (p *shellParser) parseArgs() ([]json.RawMessage, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+
+	args := []json.RawMessage{}
+	for {
+		p.skipSpace()
+		if p.consume(')') {
+			return args, nil
+		}
+
+		var b strings.Builder
+		if err := p.parseValue(&b); err != nil {
+			return nil, err
+		}
+		args = append(args, json.RawMessage(b.String()))
+
+		p.skipSpace()
+		if p.consume(',') {
+			continue
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+		return args, nil
+	}
+}
+
+// parseValue converts one JavaScript literal to Extended JSON
+func (p *shellParser) parseValue(b *strings.Builder) error {
+	switch c := p.peek(); {
+	case c == '{':
+		return p.parseObject(b)
+	case c == '[':
+		return p.parseArray(b)
+	case c == '"' || c == '\'':
+		s, err := p.parseString()
+		if err != nil {
+			return err
+		}
+		writeJSON(b, s)
+		return nil
+	case c == '/':
+		return p.parseRegex(b)
+	case c == '-' || c == '+' || c == '.' || isDigit(c):
+		return p.parseNumber(b)
+	case isIdentStart(c):
+		return p.parseIdentValue(b)
+	case c == 0:
+		return p.errorf("unexpected end of command")
+	default:
+		return p.errorf("unexpected %q", c)
+	}
+}
+
+func (p *shellParser) parseObject(b *strings.Builder) error {
+	p.pos++
+	b.WriteByte('{')
+	first := true
+	for {
+		p.skipSpace()
+		if p.consume('}') {
+			b.WriteByte('}')
+			return nil
+		}
+
+		var key string
+		if c := p.peek(); c == '"' || c == '\'' {
+			s, err := p.parseString()
+			if err != nil {
+				return err
+			}
+			key = s
+		} else if key = p.ident(); key == "" {
+			return p.errorf("expected a field name")
+		}
+
+		p.skipSpace()
+		if err := p.expect(':'); err != nil {
+			return err
+		}
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		writeJSON(b, key)
+		b.WriteByte(':')
+
+		p.skipSpace()
+		if err := p.parseValue(b); err != nil {
+			return err
+		}
+
+		p.skipSpace()
+		if p.consume(',') {
+			continue
+		}
+		if err := p.expect('}'); err != nil {
+			return err
+		}
+		b.WriteByte('}')
+		return nil
+	}
+}
+
+func (p *shellParser) parseArray(b *strings.Builder) error {
+	p.pos++
+	b.WriteByte('[')
+	first := true
+	for {
+		p.skipSpace()
+		if p.consume(']') {
+			b.WriteByte(']')
+			return nil
+		}
+
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		if err := p.parseValue(b); err != nil {
+			return err
+		}
+
+		p.skipSpace()
+		if p.consume(',') {
+			continue
+		}
+		if err := p.expect(']'); err != nil {
+			return err
+		}
+		b.WriteByte(']')
+		return nil
+	}
+}
+
+func (p *shellParser) parseString() (string, error) {
+	quote := p.peek()
+	if quote != '"' && quote != '\'' {
+		return "", p.errorf("expected a string")
+	}
+	p.pos++
+
+	var s strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case quote:
+			return s.String(), nil
+		case '\\':
+			if p.pos >= len(p.src) {
+				return "", p.errorf("unterminated string")
+			}
+			e := p.src[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				s.WriteByte('\n')
+			case 't':
+				s.WriteByte('\t')
+			case 'r':
+				s.WriteByte('\r')
+			case 'b':
+				s.WriteByte('\b')
+			case 'f':
+				s.WriteByte('\f')
+			case 'v':
+				s.WriteByte('\v')
+			case '0':
+				s.WriteByte(0)
+			case 'u', 'x':
+				size := 4
+				if e == 'x' {
+					size = 2
+				}
+				if p.pos+size > len(p.src) {
+					return "", p.errorf("invalid escape sequence")
+				}
+				code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+				if err != nil {
+					return "", p.errorf("invalid escape sequence")
+				}
+				p.pos += size
+				s.WriteRune(rune(code))
+			default:
+				s.WriteByte(e)
+			}
+		default:
+			s.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *shellParser) parseRegex(b *strings.Builder) error {
+	p.pos++
+	start := p.pos
+	inClass := false
+	for {
+		if p.pos >= len(p.src) {
+			return p.errorf("unterminated regular expression")
+		}
+		c := p.src[p.pos]
+		if c == '\\' {
+			p.pos += 2
+			continue
+		}
+		if c == '[' {
+			inClass = true
+		} else if c == ']' {
+			inClass = false
+		} else if c == '/' && !inClass {
+			break
+		}
+		p.pos++
+	}
+	pattern := p.src[start:p.pos]
+	p.pos++
+
+	flagStart := p.pos
+	for p.pos < len(p.src) && ((p.src[p.pos] >= 'a' && p.src[p.pos] <= 'z') || (p.src[p.pos] >= 'A' && p.src[p.pos] <= 'Z')) {
+		p.pos++
+	}
+	flags := []byte(p.src[flagStart:p.pos])
+	sort.Slice(flags, func(i, j int) bool { return flags[i] < flags[j] })
+
+	b.WriteString(`{"$regularExpression":{"pattern":`)
+	writeJSON(b, pattern)
+	b.WriteString(`,"options":`)
+	writeJSON(b, string(flags))
+	b.WriteString(`}}`)
+	return nil
+}
+
+func (p *shellParser) parseNumber(b *strings.Builder) error {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if !(isDigit(c) || c == '.' || c == 'e' || c == 'E' || ((c == '-' || c == '+') && (p.pos == start || p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E'))) {
+			break
+		}
+		p.pos++
+	}
+
+	text := strings.TrimPrefix(p.src[start:p.pos], "+")
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		b.WriteString(strconv.FormatInt(n, 10))
+		return nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return p.errorf("invalid number %q", text)
+	}
+	// Keep a fraction so that 1.0 stays a double instead of an int32
+	formatted := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(formatted, ".e") {
+		formatted += ".0"
+	}
+	b.WriteString(formatted)
+	return nil
+}
+
+// parseIdentValue handles keywords and the BSON type constructors of the shell
+func (p *shellParser) parseIdentValue(b *strings.Builder) error {
+	name := p.ident()
+	switch name {
+	case "true", "false", "null":
+		b.WriteString(name)
+		return nil
+	case "undefined":
+		b.WriteString("null")
+		return nil
+	case "Infinity", "NaN":
+		b.WriteString(`{"$numberDouble":"` + name + `"}`)
+		return nil
+	case "MinKey", "MaxKey":
+		p.skipSpace()
+		if p.peek() == '(' {
+			if _, err := p.parseArgs(); err != nil {
+				return err
+			}
+		}
+		b.WriteString(`{"$` + strings.ToLower(name[:1]) + name[1:] + `":1}`)
+		return nil
+	case "new":
+		p.skipSpace()
+		if name = p.ident(); name == "" {
+			return p.errorf("expected a constructor after new")
+		}
+	}
+
+	p.skipSpace()
+	if p.peek() != '(' {
+		return p.errorf("unsupported value %s", name)
+	}
+	args, err := p.parseArgs()
+	if err != nil {
+		return err
+	}
+	call := shellCall{name: name, args: args}
+
+	switch name {
+	case "ObjectId", "ObjectID":
+		oid := primitive.NewObjectID()
+		if len(args) > 0 {
+			s, err := stringArg(call, 0)
+			if err != nil {
+				return err
+			}
+			if oid, err = primitive.ObjectIDFromHex(s); err != nil {
+				return fmt.Errorf("invalid ObjectId %q", s)
+			}
+		}
+		b.WriteString(`{"$oid":"` + oid.Hex() + `"}`)
+	case "ISODate", "Date":
+		t := time.Now()
+		if len(args) > 0 {
+			if t, err = dateArg(call); err != nil {
+				return err
+			}
+		}
+		b.WriteString(`{"$date":{"$numberLong":"` + strconv.FormatInt(t.UnixMilli(), 10) + `"}}`)
+	case "NumberLong", "Long", "NumberInt", "Int32":
+		n, err := numericArg(call)
+		if err != nil {
+			return err
+		}
+		bits, key := 64, "$numberLong"
+		if name == "NumberInt" || name == "Int32" {
+			bits, key = 32, "$numberInt"
+		}
+		if _, err := strconv.ParseInt(n, 10, bits); err != nil {
+			return fmt.Errorf("invalid %s %q", name, n)
+		}
+		b.WriteString(`{"` + key + `":"` + n + `"}`)
+	case "NumberDecimal", "Decimal128":
+		n, err := numericArg(call)
+		if err != nil {
+			return err
+		}
+		if _, err := primitive.ParseDecimal128(n); err != nil {
+			return fmt.Errorf("invalid %s %q", name, n)
+		}
+		b.WriteString(`{"$numberDecimal":"` + n + `"}`)
+	case "UUID":
+		u := uuid.New()
+		if len(args) > 0 {
+			s, err := stringArg(call, 0)
+			if err != nil {
+				return err
+			}
+			if u, err = uuid.Parse(s); err != nil {
+				return fmt.Errorf("invalid UUID %q", s)
+			}
+		}
+		b.WriteString(`{"$uuid":"` + u.String() + `"}`)
+	case "BinData":
+		if len(args) != 2 {
+			return fmt.Errorf("BinData expects a subtype and base64 data")
+		}
+		subtype, err := intArg(call, 0)
+		if err != nil || subtype < 0 || subtype > 255 {
+			return fmt.Errorf("invalid BinData subtype")
+		}
+		data, err := stringArg(call, 1)
+		if err != nil {
+			return err
+		}
+		b.WriteString(`{"$binary":{"base64":`)
+		writeJSON(b, data)
+		b.WriteString(`,"subType":"` + hex.EncodeToString([]byte{byte(subtype)}) + `"}}`)
+	case "Timestamp":
+		var t, i int64
+		if len(args) == 2 {
+			if t, err = intArg(call, 0); err == nil {
+				i, err = intArg(call, 1)
+			}
+		} else {
+			var ts struct {
+				T int64 `json:"t"`
+				I int64 `json:"i"`
+			}
+			if len(args) != 1 || json.Unmarshal(args[0], &ts) != nil {
+				err = fmt.Errorf("invalid")
+			}
+			t, i = ts.T, ts.I
+		}
+		if err != nil {
+			return fmt.Errorf("Timestamp expects seconds and an increment")
+		}
+		b.WriteString(fmt.Sprintf(`{"$timestamp":{"t":%d,"i":%d}}`, t, i))
+	default:
+		return fmt.Errorf("unsupported constructor: %s", name)
+	}
+	return nil
+}
+
+var shellDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// dateArg reads an ISO date string, interpreted as UTC without an offset, or
+// milliseconds since the epoch
+func dateArg(call shellCall) (time.Time, error) {
+	var ms int64
+	if json.Unmarshal(call.args[0], &ms) == nil {
+		return time.UnixMilli(ms), nil
+	}
+	s, err := stringArg(call, 0)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, layout := range shellDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// numericArg returns a number or numeric string argument as text
+func numericArg(call shellCall) (string, error) {
+	if len(call.args) != 1 {
+		return "", fmt.Errorf("%s expects one argument", call.name)
+	}
+	var s string
+	if json.Unmarshal(call.args[0], &s) == nil {
+		return s, nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(call.args[0], &n); err != nil {
+		return "", fmt.Errorf("%s expects a number", call.name)
+	}
+	return n.String(), nil
+}
+
+func writeJSON(b *strings.Builder, s string) {
+	data, _ := json.Marshal(s)
+	b.Write(data)
+}
@@ -0,0 +1,169 @@
+package database
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// chain spells the calls of a parsed command as name(args).name(args),
+// with the arguments in Extended JSON
+func chain(calls []shellCall) string {
+	parts := make([]string, len(calls))
+	for i, call := range calls {
+		args := make([]string, len(call.args))
+		for j, arg := range call.args {
+			args[j] = string(arg)
+		}
+		parts[i] = call.name + "(" + strings.Join(args, ",") + ")"
+	}
+	return strings.Join(parts, ".")
+}
+
+func TestParseShellCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		show       string
+		collection string
+		calls      string
+	}{
+		{"show", "show dbs", "dbs", "", ""},
+		{"database method", "db.runCommand({ping: 1});", "", "", `runCommand({"ping":1})`},
+		{"find with limit", "db.users.find({age: {$gt: 30}}).limit(5)", "", "users", `find({"age":{"$gt":30}}).limit(5)`},
+		{
+			"sort and skip chain", "db.users.find({}, {name: 1}).sort({age: -1, name: 1}).skip(10).limit(5).pretty()",
+			"", "users", `find({},{"name":1}).sort({"age":-1,"name":1}).skip(10).limit(5).pretty()`,
+		},
+		{"findOne", "db.users.findOne({_id: 1})", "", "users", `findOne({"_id":1})`},
+		{"dotted collection", "db.logs.archive.find()", "", "logs.archive", "find()"},
+		{"bracket collection", `db["my-coll"].countDocuments()`, "", "my-coll", "countDocuments()"},
+		{"getCollection", `db.getCollection('a b').distinct('tag', {x: 1})`, "", "a b", `distinct("tag",{"x":1})`},
+		{"insertOne", `db.c.insertOne({name: 'x', tags: ['a', "b"]})`, "", "c", `insertOne({"name":"x","tags":["a","b"]})`},
+		{"insertMany", `db.c.insertMany([{a: 1}, {a: 2}])`, "", "c", `insertMany([{"a":1},{"a":2}])`},
+		{"updateOne", `db.c.updateOne({a: 1}, {$set: {b: 2}}, {upsert: true})`, "", "c", `updateOne({"a":1},{"$set":{"b":2}},{"upsert":true})`},
+		{"updateMany", `db.c.updateMany({}, {$inc: {n: 1}})`, "", "c", `updateMany({},{"$inc":{"n":1}})`},
+		{"replaceOne", `db.c.replaceOne({a: 1}, {a: 1, b: 3})`, "", "c", `replaceOne({"a":1},{"a":1,"b":3})`},
+		{"deleteOne", `db.c.deleteOne({a: 1})`, "", "c", `deleteOne({"a":1})`},
+		{"deleteMany", `db.c.deleteMany({})`, "", "c", `deleteMany({})`},
+		{
+			"aggregate", `db.c.aggregate([{$match: {a: 1}}, {$group: {_id: "$b", n: {$sum: 1}}}])`,
+			"", "c", `aggregate([{"$match":{"a":1}},{"$group":{"_id":"$b","n":{"$sum":1}}}])`,
+		},
+		{"aggregate stages as arguments", `db.c.aggregate({$match: {}}, {$limit: 2})`, "", "c", `aggregate({"$match":{}},{"$limit":2})`},
+		{
+			"quoted keys", `db.c.find({"a.b": 1, 'c d': 2, e_f: 3, $or: []})`,
+			"", "c", `find({"a.b":1,"c d":2,"e_f":3,"$or":[]})`,
+		},
+		{"nested arrays", `db.c.find({m: [[1, [2, []]], [{x: [3]}]]})`, "", "c", `find({"m":[[1,[2,[]]],[{"x":[3]}]]})`},
+		{
+			"ObjectId and ISODate", `db.c.find({_id: ObjectId("65a1b2c3d4e5f60718293a4b"), at: {$gte: ISODate("2024-01-02T03:04:05Z")}})`,
+			"", "c", `find({"_id":{"$oid":"65a1b2c3d4e5f60718293a4b"},"at":{"$gte":{"$date":{"$numberLong":"1704164645000"}}}})`,
+		},
+		{
+			"other constructors", `db.c.insertOne({l: NumberLong("9007199254740993"), i: NumberInt(7), d: NumberDecimal("1.10"), u: UUID("0e0f1f9d-7e4c-4b7a-9a2b-2f7f0cde1a11"), b: BinData(0, "AQI="), t: Timestamp(1, 2), at: new Date(0)})`,
+			"", "c",
+			`insertOne({"l":{"$numberLong":"9007199254740993"},"i":{"$numberInt":"7"},"d":{"$numberDecimal":"1.10"},"u":{"$uuid":"0e0f1f9d-7e4c-4b7a-9a2b-2f7f0cde1a11"},"b":{"$binary":{"base64":"AQI=","subType":"00"}},"t":{"$timestamp":{"t":1,"i":2}},"at":{"$date":{"$numberLong":"0"}}})`,
+		},
+		{
+			"literals", `db.c.find({a: true, b: null, c: undefined, d: -1.5, e: 1.0, f: 2e3, g: /ab[/]c/mi, h: Infinity, i: MinKey})`,
+			"", "c",
+			`find({"a":true,"b":null,"c":null,"d":-1.5,"e":1.0,"f":2000.0,"g":{"$regularExpression":{"pattern":"ab[/]c","options":"im"}},"h":{"$numberDouble":"Infinity"},"i":{"$minKey":1}})`,
+		},
+		{"escapes", `db.c.find({s: 'it\'s\né\x41'})`, "", "c", `find({"s":"it's\né` + "A" + `"})`},
+		{"comments", "db.c /* which */ .find( // all\n{} )", "", "c", "find({})"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := parseShellCommand(tt.input)
+			if err != nil {
+				t.Fatalf("parseShellCommand(%q) error = %v", tt.input, err)
+			}
+			if cmd.show != tt.show || cmd.collection != tt.collection || chain(cmd.calls) != tt.calls {
+				t.Errorf("parseShellCommand(%q) = show %q, collection %q, calls %s; want %q, %q, %s",
+					tt.input, cmd.show, cmd.collection, chain(cmd.calls), tt.show, tt.collection, tt.calls)
+			}
+			for _, call := range cmd.calls {
+				for _, arg := range call.args {
+					if !json.Valid(arg) {
+						t.Errorf("argument %s of %s is not JSON", arg, call.name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseShellCommandErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"show",
+		"use test",
+		"db",
+		"db.users",
+		"db.users.find(",
+		"db.users.find({a: 1)",
+		"db.users.find({a 1})",
+		"db.users.find({: 1})",
+		"db.users.find({a: [1, 2})",
+		"db.users.find({a: 'open})",
+		"db.users.find({a: /open})",
+		`db.users.find({a: "\u12"})`,
+		"db.users.find({a: 1-})",
+		"db.users.find({a: foo})",
+		"db.users.find({a: Foo(1)})",
+		`db.users.find({_id: ObjectId("nothex")})`,
+		`db.users.find({at: ISODate("yesterday")})`,
+		`db.users.find({n: NumberInt("3000000000")})`,
+		`db.users.find({b: BinData(300, "")})`,
+		"db.users.find().limit",
+		"db.users.find(); db.users.find()",
+		"db.users.find() db",
+		"db.getCollection(1).find()",
+		"db.users.find().x.limit(1)",
+	}
+	for _, input := range tests {
+		if cmd, err := parseShellCommand(input); err == nil {
+			t.Errorf("parseShellCommand(%q) = %+v, want an error", input, cmd)
+		}
+	}
+}
+
+// TestParseShellCommandPrefixes parses every prefix of a command, which
+// must fail cleanly rather than panic
+func TestParseShellCommandPrefixes(t *testing.T) {
+	input := `db["a"].find({x: [1, {y: 'zA'}], r: /a\/b/i, o: ObjectId("65a1b2c3d4e5f60718293a4b")} /* c */).sort({x: -1}).limit(2);`
+	for i := 0; i < len(input); i++ {
+		parseShellCommand(input[:i])
+	}
+	if _, err := parseShellCommand(input); err != nil {
+		t.Errorf("parseShellCommand(%q) error = %v", input, err)
+	}
+}
+
+func TestShellArguments(t *testing.T) {
+	cmd, err := parseShellCommand(`db.c.updateOne({}, {}, {upsert: true})`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := cmd.calls[0]
+	if upsert, err := upsertOption(optionalArg(call, 2)); err != nil || !upsert {
+		t.Errorf("upsertOption = %v, %v, want true", upsert, err)
+	}
+	if upsert, err := upsertOption(optionalArg(call, 3)); err != nil || upsert {
+		t.Errorf("upsertOption without options = %v, %v, want false", upsert, err)
+	}
+	if _, err := intArg(call, 0); err == nil {
+		t.Error("intArg of a document succeeded")
+	}
+	if _, err := stringArg(call, 5); err == nil {
+		t.Error("stringArg past the arguments succeeded")
+	}
+
+	for _, arg := range []string{`"ping"`, `{"ping": 1}`} {
+		command, err := commandDocument(json.RawMessage(arg))
+		if err != nil || len(command) != 1 || command[0].Key != "ping" {
+			t.Errorf("commandDocument(%s) = %v, %v, want ping", arg, command, err)
+		}
+	}
+}
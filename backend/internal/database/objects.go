@@ -0,0 +1,389 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"opendbm/internal/models"
+)
+
+// ErrObjectNotFound is returned when a schema object does not exist
+var ErrObjectNotFound = errors.New("object not found")
+
+// objectKinds are the kinds of schema object ListTables returns, in the
+// order it lists them
+var objectKinds = []string{"table", "view", "materialized_view", "foreign_table", "sequence", "function", "procedure", "trigger"}
+
+// ListTables lists the tables, views, sequences, routines and triggers in
+// a database, in one schema when schema is set or else in every schema
+// ListSchemas returns. MySQL and Oracle list the database's objects;
+// SQLite lists the main database unless asked. Objects come grouped by
+// schema and then kind, as in objectKinds.
+func (d *SQLDriverImpl) ListTables(id string, database string, schema string) ([]models.TableInfo, error) {
+	return d.listTables(id, database, schema, false)
+}
+
+// ListTablesWithStats lists objects as ListTables does, with the row
+// counts and sizes addTableStats finds on tables
+func (d *SQLDriverImpl) ListTablesWithStats(id string, database string, schema string) ([]models.TableInfo, error) {
+	return d.listTables(id, database, schema, true)
+}
+
+func (d *SQLDriverImpl) listTables(id string, database string, schema string, stats bool) ([]models.TableInfo, error) {
+	d.mu.RLock()
+	dbType := d.connectionTypes[id]
+	d.mu.RUnlock()
+
+	var schemaArg interface{}
+	if schema != "" {
+		schemaArg = schema
+	}
+
+	// Each query returns schema, name, type and, for triggers, table_name;
+	// each of its placeholders takes the schema
+	var queries []catalogQuery
+	switch dbType {
+	case "mysql":
+		if schema == "" {
+			schema = database
+		}
+		queries = []catalogQuery{
+			{"SELECT table_schema AS `schema`, table_name AS name, IF(table_type = 'VIEW', 'view', 'table') AS type FROM information_schema.tables WHERE table_schema = ?", 1},
+			{"SELECT routine_schema AS `schema`, routine_name AS name, LOWER(routine_type) AS type FROM information_schema.routines WHERE routine_schema = ?", 1},
+			{"SELECT trigger_schema AS `schema`, trigger_name AS name, 'trigger' AS type, event_object_table AS table_name FROM information_schema.triggers WHERE trigger_schema = ?", 1},
+		}
+		schemaArg = schema
+	case "postgres":
+		queries = []catalogQuery{{postgresObjectsQuery, 1}}
+	case "sqlserver":
+		queries = []catalogQuery{{sqlServerObjectsQuery, 1}}
+	case "sqlite":
+		if schema == "" {
+			schema = "main"
+		}
+		queries = []catalogQuery{{
+			`SELECT ? AS schema, name, type, CASE WHEN type = 'trigger' THEN tbl_name END AS table_name
+			FROM ` + dialect(dbType).quote(schema) + `.sqlite_master
+			WHERE type IN ('table', 'view', 'trigger') AND name NOT LIKE 'sqlite_%'`, 1,
+		}}
+		schemaArg = schema
+	case "oracle":
+		if schema == "" {
+			schema = database
+		}
+		queries = oracleObjectsQueries
+		schemaArg = schema
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", dbType)
+	}
+
+	tables := []models.TableInfo{}
+	for _, query := range queries {
+		args := make([]interface{}, query.placeholders)
+		for i := range args {
+			args[i] = schemaArg
+		}
+		result, err := d.query(id, query.sql, args...)
+		if err != nil {
+			return nil, err
+		}
+		if result.Error != "" {
+			return nil, fmt.Errorf("%s", result.Error)
+		}
+		for _, row := range result.Rows {
+			name, ok := row["name"].(string)
+			if !ok {
+				continue
+			}
+			info := models.TableInfo{Name: name}
+			info.Schema, _ = row["schema"].(string)
+			info.Type, _ = row["type"].(string)
+			info.Table, _ = row["table_name"].(string)
+			tables = append(tables, info)
+		}
+	}
+
+	if stats {
+		d.addTableStats(id, dbType, schemaArg, tables)
+	}
+
+	kindOrder := make(map[string]int, len(objectKinds))
+	for i, kind := range objectKinds {
+		kindOrder[kind] = i
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		a, b := tables[i], tables[j]
+		if a.Schema != b.Schema {
+			return a.Schema < b.Schema
+		}
+		if a.Type != b.Type {
+			return kindOrder[a.Type] < kindOrder[b.Type]
+		}
+		return a.Name < b.Name
+	})
+	return tables, nil
+}
+
+// catalogQuery is a catalog query and the number of placeholders it has
+type catalogQuery struct {
+	sql          string
+	placeholders int
+}
+
+// postgresObjectsQuery lists relations, routines and triggers outside the
+// system schemas. Partitions are left out, as are routines that belong to
+// extensions.
+const postgresObjectsQuery = `SELECT DISTINCT * FROM (
+	SELECT n.nspname AS schema, c.relname AS name,
+		CASE c.relkind WHEN 'v' THEN 'view' WHEN 'm' THEN 'materialized_view' WHEN 'f' THEN 'foreign_table'
+			WHEN 'S' THEN 'sequence' ELSE 'table' END AS type,
+		NULL AS table_name
+	FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f', 'S') AND NOT c.relispartition
+	UNION ALL
+	SELECT n.nspname, p.proname, CASE p.prokind WHEN 'p' THEN 'procedure' ELSE 'function' END, NULL
+	FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+	WHERE p.prokind IN ('f', 'p') AND NOT EXISTS (SELECT 1 FROM pg_depend d
+		WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'e')
+	UNION ALL
+	SELECT n.nspname, t.tgname, 'trigger', c.relname
+	FROM pg_trigger t JOIN pg_class c ON c.oid = t.tgrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE NOT t.tgisinternal
+) o
+WHERE schema <> 'information_schema' AND schema !~ '^pg_' AND schema = COALESCE(?, schema)`
+
+// sqlServerObjectsQuery lists user objects. Views with a clustered index
+// are indexed views, which SQL Server keeps materialized.
+const sqlServerObjectsQuery = `SELECT s.name AS [schema], o.name AS name,
+	CASE
+		WHEN o.type = 'U' THEN 'table'
+		WHEN o.type = 'V' AND EXISTS (SELECT 1 FROM sys.indexes i WHERE i.object_id = o.object_id AND i.index_id = 1) THEN 'materialized_view'
+		WHEN o.type = 'V' THEN 'view'
+		WHEN o.type = 'SO' THEN 'sequence'
+		WHEN o.type IN ('P', 'PC') THEN 'procedure'
+		WHEN o.type = 'TR' THEN 'trigger'
+		ELSE 'function'
+	END AS type,
+	CASE WHEN o.type = 'TR' THEN OBJECT_NAME(o.parent_object_id) END AS table_name
+FROM sys.objects o JOIN sys.schemas s ON s.schema_id = o.schema_id
+WHERE o.is_ms_shipped = 0 AND o.type IN ('U', 'V', 'SO', 'P', 'PC', 'TR', 'FN', 'IF', 'TF', 'FS', 'FT')
+	AND s.name = COALESCE(?, s.name)`
+
+// oracleObjectsQueries list an owner's objects. Materialized views and
+// external tables also show up among the tables, so they are left out
+// there.
+var oracleObjectsQueries = []catalogQuery{
+	{`SELECT owner AS "schema", table_name AS "name", 'table' AS "type" FROM all_tables
+	WHERE owner = ? AND nested = 'NO' AND secondary = 'N' AND dropped = 'NO'
+		AND table_name NOT IN (SELECT mview_name FROM all_mviews WHERE owner = ?)
+		AND table_name NOT IN (SELECT table_name FROM all_external_tables WHERE owner = ?)`, 3},
+	{`SELECT owner AS "schema", table_name AS "name", 'foreign_table' AS "type" FROM all_external_tables WHERE owner = ?`, 1},
+	{`SELECT owner AS "schema", object_name AS "name", LOWER(REPLACE(object_type, ' ', '_')) AS "type" FROM all_objects
+	WHERE owner = ? AND object_type IN ('VIEW', 'MATERIALIZED VIEW', 'SEQUENCE', 'FUNCTION', 'PROCEDURE')`, 1},
+	{`SELECT owner AS "schema", trigger_name AS "name", 'trigger' AS "type", table_name AS "table_name" FROM all_triggers WHERE owner = ?`, 1},
+}
+
+// GetObjectDefinition returns the source of a view, materialized view,
+// function, procedure, trigger or sequence, given as a name or
+// schema.name. PostgreSQL routines can be overloaded and trigger names are
+// only unique per table, so every match is returned.
+func (d *SQLDriverImpl) GetObjectDefinition(id string, database string, kind string, name string) ([]models.ObjectDefinition, error) {
+	_, dbType, err := d.pool(id)
+	if err != nil {
+		return nil, err
+	}
+	t, err := resolveTable(dbType, database, name)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case "view", "materialized_view", "function", "procedure", "trigger", "sequence":
+	case "table", "foreign_table":
+		return nil, fmt.Errorf("a %s has no definition source", strings.ReplaceAll(kind, "_", " "))
+	default:
+		return nil, fmt.Errorf("unknown object type %q", kind)
+	}
+
+	query, args, err := definitionQuery(dbType, kind, t)
+	if err != nil {
+		return nil, err
+	}
+	result, err := d.query(id, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
+	if len(result.Rows) == 0 {
+		return nil, ErrObjectNotFound
+	}
+
+	var definitions []models.ObjectDefinition
+	for _, row := range result.Rows {
+		def := models.ObjectDefinition{Type: kind}
+		def.Name, _ = row["name"].(string)
+		def.Schema, _ = row["schema"].(string)
+		def.Table, _ = row["table_name"].(string)
+		def.Arguments, _ = row["arguments"].(string)
+		def.Language, _ = row["language"].(string)
+		if kind == "sequence" {
+			def.Definition = sequenceDefinition(row)
+		} else {
+			def.Definition, _ = row["definition"].(string)
+		}
+
+		// Oracle keeps source one line per row
+		if line, ok := row["text"].(string); ok {
+			if n := len(definitions); n > 0 {
+				definitions[n-1].Definition += line
+				continue
+			}
+			def.Definition = line
+		}
+		definitions = append(definitions, def)
+	}
+	return definitions, nil
+}
+
+// definitionQuery returns the catalog query for an object's source. Rows
+// have name and schema columns, definition or, for sequences, the
+// options sequenceDefinition reads, and where they apply table_name,
+// arguments and language.
+func definitionQuery(dbType string, kind string, t tableName) (string, []interface{}, error) {
+	args := []interface{}{t.schemaArg(), t.name}
+	unsupported := fmt.Errorf("%s connections have no %s objects", dbType, strings.ReplaceAll(kind, "_", " "))
+
+	switch dbType {
+	case "postgres":
+		switch kind {
+		case "view", "materialized_view":
+			relkind := "v"
+			if kind == "materialized_view" {
+				relkind = "m"
+			}
+			return `SELECT n.nspname AS schema, c.relname AS name, pg_get_viewdef(c.oid, true) AS definition
+				FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+				WHERE n.nspname = COALESCE(?, current_schema()) AND c.relname = ? AND c.relkind = '` + relkind + `'`, args, nil
+		case "function", "procedure":
+			prokind := "f"
+			if kind == "procedure" {
+				prokind = "p"
+			}
+			return `SELECT n.nspname AS schema, p.proname AS name, pg_get_function_identity_arguments(p.oid) AS arguments,
+					l.lanname AS language, p.prosrc AS definition
+				FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace JOIN pg_language l ON l.oid = p.prolang
+				WHERE n.nspname = COALESCE(?, current_schema()) AND p.proname = ? AND p.prokind = '` + prokind + `'
+				ORDER BY arguments`, args, nil
+		case "trigger":
+			return `SELECT n.nspname AS schema, t.tgname AS name, c.relname AS table_name, pg_get_triggerdef(t.oid, true) AS definition
+				FROM pg_trigger t JOIN pg_class c ON c.oid = t.tgrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+				WHERE n.nspname = COALESCE(?, current_schema()) AND t.tgname = ? AND NOT t.tgisinternal
+				ORDER BY c.relname`, args, nil
+		case "sequence":
+			return `SELECT schemaname AS schema, sequencename AS name, start_value, increment_by, min_value, max_value, cycle
+				FROM pg_sequences WHERE schemaname = COALESCE(?, current_schema()) AND sequencename = ?`, args, nil
+		}
+
+	case "mysql":
+		switch kind {
+		case "view":
+			return "SELECT table_schema AS `schema`, table_name AS name, view_definition AS definition FROM information_schema.views WHERE table_schema = COALESCE(?, DATABASE()) AND table_name = ?", args, nil
+		case "function", "procedure":
+			return "SELECT routine_schema AS `schema`, routine_name AS name, routine_body AS language, routine_definition AS definition FROM information_schema.routines WHERE routine_schema = COALESCE(?, DATABASE()) AND routine_name = ? AND routine_type = '" + strings.ToUpper(kind) + "'", args, nil
+		case "trigger":
+			return "SELECT trigger_schema AS `schema`, trigger_name AS name, event_object_table AS table_name, action_statement AS definition FROM information_schema.triggers WHERE trigger_schema = COALESCE(?, DATABASE()) AND trigger_name = ?", args, nil
+		}
+
+	case "sqlite":
+		switch kind {
+		case "view", "trigger":
+			schema := t.schema
+			if schema == "" {
+				schema = "main"
+			}
+			return `SELECT ? AS schema, name, CASE WHEN type = 'trigger' THEN tbl_name END AS table_name, sql AS definition
+				FROM ` + dialect(dbType).quote(schema) + `.sqlite_master WHERE type = ? AND name = ?`,
+				[]interface{}{schema, kind, t.name}, nil
+		}
+
+	case "sqlserver":
+		types := map[string]string{
+			"view":              "'V'",
+			"materialized_view": "'V'",
+			"function":          "'FN', 'IF', 'TF', 'FS', 'FT'",
+			"procedure":         "'P', 'PC'",
+			"trigger":           "'TR'",
+		}
+		if kind == "sequence" {
+			return `SELECT s.name AS [schema], q.name AS name,
+					CAST(q.start_value AS varchar(40)) AS start_value, CAST(q.increment AS varchar(40)) AS increment_by,
+					CAST(q.minimum_value AS varchar(40)) AS min_value, CAST(q.maximum_value AS varchar(40)) AS max_value,
+					q.is_cycling AS cycle
+				FROM sys.sequences q JOIN sys.schemas s ON s.schema_id = q.schema_id
+				WHERE s.name = COALESCE(?, SCHEMA_NAME()) AND q.name = ?`, args, nil
+		}
+		return `SELECT s.name AS [schema], o.name AS name, CASE WHEN o.type = 'TR' THEN OBJECT_NAME(o.parent_object_id) END AS table_name,
+				OBJECT_DEFINITION(o.object_id) AS definition
+			FROM sys.objects o JOIN sys.schemas s ON s.schema_id = o.schema_id
+			WHERE s.name = COALESCE(?, SCHEMA_NAME()) AND o.name = ? AND o.type IN (` + types[kind] + `)`, args, nil
+
+	case "oracle":
+		const owner = "NVL(?, SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA'))"
+		switch kind {
+		case "view":
+			return `SELECT owner AS "schema", view_name AS "name", text AS "definition" FROM all_views WHERE owner = ` + owner + ` AND view_name = ?`, args, nil
+		case "materialized_view":
+			return `SELECT owner AS "schema", mview_name AS "name", query AS "definition" FROM all_mviews WHERE owner = ` + owner + ` AND mview_name = ?`, args, nil
+		case "function", "procedure", "trigger":
+			return `SELECT owner AS "schema", name AS "name", text AS "text" FROM all_source
+				WHERE owner = ` + owner + ` AND name = ? AND type = '` + strings.ToUpper(kind) + `' ORDER BY line`, args, nil
+		case "sequence":
+			return `SELECT sequence_owner AS "schema", sequence_name AS "name", last_number AS "start_value", increment_by AS "increment_by",
+					min_value AS "min_value", max_value AS "max_value", cycle_flag AS "cycle"
+				FROM all_sequences WHERE sequence_owner = ` + owner + ` AND sequence_name = ?`, args, nil
+		}
+
+	default:
+		return "", nil, fmt.Errorf("unsupported database type: %s", dbType)
+	}
+	return "", nil, unsupported
+}
+
+// sequenceDefinition spells a sequence's options as CREATE SEQUENCE takes
+// them
+func sequenceDefinition(row map[string]interface{}) string {
+	cycle := "NO CYCLE"
+	switch v := row["cycle"].(type) {
+	case bool:
+		if v {
+			cycle = "CYCLE"
+		}
+	case string:
+		if v == "Y" || v == "1" || strings.EqualFold(v, "true") {
+			cycle = "CYCLE"
+		}
+	case int64:
+		if v != 0 {
+			cycle = "CYCLE"
+		}
+	}
+	return fmt.Sprintf("START WITH %s INCREMENT BY %s MINVALUE %s MAXVALUE %s %s",
+		catalogNumber(row["start_value"]), catalogNumber(row["increment_by"]),
+		catalogNumber(row["min_value"]), catalogNumber(row["max_value"]), cycle)
+}
+
+// catalogNumber formats a number read from a catalog, which drivers
+// return as integers, floats or text
+func catalogNumber(v interface{}) string {
+	switch n := v.(type) {
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// createObjects creates a table with a view and a trigger on it, and a
+// table whose AUTOINCREMENT key adds SQLite's sqlite_sequence
+func createObjects(t *testing.T, d *SQLDriverImpl, id string) {
+	t.Helper()
+	for _, stmt := range []string{
+		"CREATE TABLE b (id INTEGER PRIMARY KEY AUTOINCREMENT)",
+		"CREATE TABLE a (x INTEGER)",
+		"CREATE VIEW v AS SELECT x FROM a WHERE x > 0",
+		"CREATE TRIGGER a_log AFTER INSERT ON a BEGIN INSERT INTO b (id) VALUES (NULL); END",
+	} {
+		if result, err := d.ExecuteSQL(context.Background(), id, "", stmt); err != nil || result.Error != "" {
+			t.Fatal(err, result.Error)
+		}
+	}
+}
+
+func TestListObjectsSQLite(t *testing.T) {
+	d, id := newTestSQLite(t)
+	createObjects(t, d, id)
+
+	objects, err := d.ListTables(id, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range objects {
+		got = append(got, o.Schema+"."+o.Name+":"+o.Type+":"+o.Table)
+	}
+	want := "main.a:table: main.b:table: main.v:view: main.a_log:trigger:a"
+	if strings.Join(got, " ") != want {
+		t.Errorf("ListTables() = %s, want %s", strings.Join(got, " "), want)
+	}
+}
+
+func TestGetObjectDefinitionSQLite(t *testing.T) {
+	d, id := newTestSQLite(t)
+	createObjects(t, d, id)
+
+	defs, err := d.GetObjectDefinition(id, "", "view", "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 1 || defs[0].Name != "v" || defs[0].Schema != "main" || defs[0].Type != "view" ||
+		defs[0].Definition != "CREATE VIEW v AS SELECT x FROM a WHERE x > 0" {
+		t.Errorf("view definition = %+v", defs)
+	}
+
+	defs, err = d.GetObjectDefinition(id, "", "trigger", "main.a_log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 1 || defs[0].Table != "a" || !strings.HasPrefix(defs[0].Definition, "CREATE TRIGGER a_log") {
+		t.Errorf("trigger definition = %+v", defs)
+	}
+
+	if _, err := d.GetObjectDefinition(id, "", "view", "missing"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("missing view = %v, want ErrObjectNotFound", err)
+	}
+	// A name of the wrong kind is not found either
+	if _, err := d.GetObjectDefinition(id, "", "trigger", "v"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("view read as a trigger = %v, want ErrObjectNotFound", err)
+	}
+	for _, kind := range []string{"table", "sequence", "function", "index"} {
+		if _, err := d.GetObjectDefinition(id, "", kind, "a"); err == nil || errors.Is(err, ErrObjectNotFound) {
+			t.Errorf("definition of a %s = %v, want an error", kind, err)
+		}
+	}
+}
+
+func TestDefinitionQuery(t *testing.T) {
+	tests := []struct {
+		dbType string
+		kind   string
+		table  tableName
+		want   string // in the query
+		args   int
+	}{
+		{"postgres", "view", tableName{name: "v"}, "c.relkind = 'v'", 2},
+		{"postgres", "materialized_view", tableName{schema: "s", name: "m"}, "c.relkind = 'm'", 2},
+		{"postgres", "function", tableName{name: "f"}, "p.prokind = 'f'", 2},
+		{"postgres", "procedure", tableName{name: "p"}, "p.prokind = 'p'", 2},
+		{"postgres", "trigger", tableName{name: "t"}, "pg_get_triggerdef", 2},
+		{"postgres", "sequence", tableName{name: "q"}, "FROM pg_sequences", 2},
+		{"mysql", "view", tableName{name: "v"}, "information_schema.views", 2},
+		{"mysql", "procedure", tableName{name: "p"}, "routine_type = 'PROCEDURE'", 2},
+		{"mysql", "trigger", tableName{name: "t"}, "information_schema.triggers", 2},
+		{"sqlite", "view", tableName{name: "v"}, `FROM "main".sqlite_master`, 3},
+		{"sqlite", "trigger", tableName{schema: `a"b`, name: "t"}, `FROM "a""b".sqlite_master`, 3},
+		{"sqlserver", "function", tableName{name: "f"}, "'FN', 'IF', 'TF', 'FS', 'FT'", 2},
+		{"sqlserver", "materialized_view", tableName{name: "v"}, "o.type IN ('V')", 2},
+		{"sqlserver", "sequence", tableName{name: "q"}, "FROM sys.sequences", 2},
+		{"oracle", "materialized_view", tableName{name: "M"}, "FROM all_mviews", 2},
+		{"oracle", "trigger", tableName{name: "T"}, "type = 'TRIGGER' ORDER BY line", 2},
+		{"oracle", "sequence", tableName{name: "Q"}, "FROM all_sequences", 2},
+	}
+	for _, tt := range tests {
+		query, args, err := definitionQuery(tt.dbType, tt.kind, tt.table)
+		if err != nil {
+			t.Errorf("definitionQuery(%s, %s) error = %v", tt.dbType, tt.kind, err)
+			continue
+		}
+		if !strings.Contains(strings.Join(strings.Fields(query), " "), tt.want) || len(args) != tt.args {
+			t.Errorf("definitionQuery(%s, %s) = %s with %d args, want %s with %d", tt.dbType, tt.kind, query, len(args), tt.want, tt.args)
+		}
+	}
+
+	unsupported := []struct{ dbType, kind string }{
+		{"mysql", "materialized_view"},
+		{"mysql", "sequence"},
+		{"sqlite", "function"},
+		{"sqlite", "sequence"},
+		{"oracle", "index"},
+		{"mongodb", "view"},
+	}
+	for _, tt := range unsupported {
+		if query, _, err := definitionQuery(tt.dbType, tt.kind, tableName{name: "x"}); err == nil {
+			t.Errorf("definitionQuery(%s, %s) = %s, want an error", tt.dbType, tt.kind, query)
+		}
+	}
+}
+
+func TestSequenceDefinition(t *testing.T) {
+	tests := []struct {
+		row  map[string]interface{}
+		want string
+	}{
+		{
+			map[string]interface{}{"start_value": int64(1), "increment_by": int64(1), "min_value": int64(1), "max_value": int64(9223372036854775807), "cycle": false},
+			"START WITH 1 INCREMENT BY 1 MINVALUE 1 MAXVALUE 9223372036854775807 NO CYCLE",
+		},
+		{
+			map[string]interface{}{"start_value": float64(100), "increment_by": "-5", "min_value": float64(1), "max_value": "100", "cycle": "Y"},
+			"START WITH 100 INCREMENT BY -5 MINVALUE 1 MAXVALUE 100 CYCLE",
+		},
+		{
+			map[string]interface{}{"start_value": "10", "increment_by": "1", "min_value": "1", "max_value": "1e+28", "cycle": int64(1)},
+			"START WITH 10 INCREMENT BY 1 MINVALUE 1 MAXVALUE 1e+28 CYCLE",
+		},
+		{
+			map[string]interface{}{"start_value": "1", "increment_by": "1", "min_value": "1", "max_value": "9", "cycle": "N"},
+			"START WITH 1 INCREMENT BY 1 MINVALUE 1 MAXVALUE 9 NO CYCLE",
+		},
+	}
+	for _, tt := range tests {
+		if got := sequenceDefinition(tt.row); got != tt.want {
+			t.Errorf("sequenceDefinition(%v) = %q, want %q", tt.row, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogNumber(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{float64(1e15), "1000000000000000"},
+		{2.5, "2.5"},
+		{int64(-3), "-3"},
+		{"42", "42"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := catalogNumber(tt.value); got != tt.want {
+			t.Errorf("catalogNumber(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "github.com/sijms/go-ora/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+
+	"opendbm/internal/models"
+)
+
+// oracleDriverName is the database/sql driver name go-ora registers
+const oracleDriverName = "oracle"
+
+// openOracle opens an Oracle database through go-ora
+func openOracle(config models.ConnectionConfig, password string) (gorm.Dialector, error) {
+	dsn, err := oracleDSN(config, password)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sql.Open(oracleDriverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection settings: %w", err)
+	}
+	return oracleDialector{conn: conn}, nil
+}
+
+// oracleDSN builds an oracle:// URL for a service name, or for a SID when
+// one is set
+func oracleDSN(config models.ConnectionConfig, password string) (string, error) {
+	port := config.Port
+	if port == 0 {
+		port = 1521
+	}
+
+	u := &url.URL{
+		Scheme: "oracle",
+		User:   url.UserPassword(config.Username, password),
+		Host:   config.Host + ":" + strconv.Itoa(port),
+	}
+
+	query := url.Values{}
+	if config.SID != "" {
+		query.Set("SID", config.SID)
+	} else {
+		service := config.ServiceName
+		if service == "" {
+			service = config.Database
+		}
+		if service == "" {
+			return "", fmt.Errorf("oracle connections need a service name or SID")
+		}
+		u.Path = "/" + service
+	}
+
+	mode, err := sslMode(config)
+	if err != nil {
+		return "", err
+	}
+	if mode != SSLModeDisable {
+		// Oracle reads CA and client certificates from a wallet
+		if config.SSLCA != "" || config.SSLCAPath != "" || config.SSLCert != "" || config.SSLCertPath != "" {
+			return "", fmt.Errorf("oracle connections take certificates from a wallet; custom CA and client certificates are not supported")
+		}
+		query.Set("SSL", "true")
+		if mode == SSLModeRequire {
+			query.Set("SSL VERIFY", "false")
+		}
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func oracleQuote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// oracleColumnType formats a column type the way Oracle DDL spells it,
+// e.g. NUMBER(10,2) or VARCHAR2(100)
+func oracleColumnType(row map[string]interface{}) string {
+	dataType, _ := row["DATA_TYPE"].(string)
+	precision, hasPrecision := catalogInt(row["DATA_PRECISION"])
+	scale, hasScale := catalogInt(row["DATA_SCALE"])
+	length, _ := catalogInt(row["CHAR_LENGTH"])
+	if length == 0 {
+		length, _ = catalogInt(row["DATA_LENGTH"])
+	}
+
+	switch dataType {
+	case "NUMBER", "FLOAT":
+		if !hasPrecision {
+			return dataType
+		}
+		if hasScale && scale != 0 {
+			return fmt.Sprintf("%s(%d,%d)", dataType, precision, scale)
+		}
+		return fmt.Sprintf("%s(%d)", dataType, precision)
+	case "VARCHAR2", "NVARCHAR2", "CHAR", "NCHAR", "RAW":
+		return fmt.Sprintf("%s(%d)", dataType, length)
+	}
+	return dataType
+}
+
+// oracleDialector is a minimal GORM dialector for Oracle. OpenDBM only runs
+// raw SQL through GORM, so it provides binding and quoting but no migrations.
+type oracleDialector struct {
+	conn *sql.DB
+}
+
+func (oracleDialector) Name() string {
+	return "oracle"
+}
+
+func (d oracleDialector) Initialize(db *gorm.DB) error {
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	db.ConnPool = d.conn
+	return nil
+}
+
+func (d oracleDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return migrator.Migrator{Config: migrator.Config{DB: db, Dialector: d}}
+}
+
+func (oracleDialector) DataTypeOf(field *schema.Field) string {
+	return string(field.DataType)
+}
+
+func (oracleDialector) DefaultValueOf(field *schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+
+func (oracleDialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	writer.WriteByte(':')
+	writer.WriteString(strconv.Itoa(len(stmt.Vars)))
+}
+
+func (oracleDialector) QuoteTo(writer clause.Writer, str string) {
+	writer.WriteString(oracleQuote(str))
+}
+
+func (oracleDialector) Explain(sql string, vars ...interface{}) string {
+	return logger.ExplainSQL(sql, nil, `'`, vars...)
+}
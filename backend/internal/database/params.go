@@ -0,0 +1,249 @@
+package database
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"opendbm/internal/models"
+)
+
+// datetimeLayouts are the accepted spellings of datetime parameters
+var datetimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// BindParams prepares a query's parameters for binding. Named parameters
+// fill :name placeholders, which are rewritten into the dialect's own style
+// ($1, ?, @p1 or :1); placeholders inside strings, quoted identifiers and
+// comments are left alone, as are names no parameter matches. Unnamed
+// parameters fill the query's native positional placeholders in order and
+// leave the query as it is. Every named parameter must be used.
+func BindParams(query string, dbType string, params []models.QueryParam) (string, []interface{}, error) {
+	query, args, used, err := bindParams(query, dbType, params)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, p := range params {
+		if p.Name != "" && !used[p.Name] {
+			return "", nil, fmt.Errorf("parameter :%s is not used in the query", p.Name)
+		}
+	}
+	return query, args, nil
+}
+
+// BindParams prepares parameters for a query on a connection
+func (d *SQLDriverImpl) BindParams(id string, query string, params []models.QueryParam) (string, []interface{}, error) {
+	_, dbType, err := d.pool(id)
+	if err != nil {
+		return "", nil, err
+	}
+	return BindParams(query, dbType, params)
+}
+
+// bindParams is BindParams without the check for unused parameters, for
+// scripts whose statements each use some of them
+func bindParams(query string, dbType string, params []models.QueryParam) (string, []interface{}, map[string]bool, error) {
+	if len(params) == 0 {
+		return query, nil, nil, nil
+	}
+
+	named := make(map[string]interface{}, len(params))
+	var positional []interface{}
+	for i, p := range params {
+		v, err := paramValue(p)
+		if err != nil {
+			if p.Name != "" {
+				return "", nil, nil, fmt.Errorf("parameter :%s: %w", p.Name, err)
+			}
+			return "", nil, nil, fmt.Errorf("parameter %d: %w", i+1, err)
+		}
+		if p.Name == "" {
+			positional = append(positional, v)
+			continue
+		}
+		if _, exists := named[p.Name]; exists {
+			return "", nil, nil, fmt.Errorf("parameter :%s is given twice", p.Name)
+		}
+		named[p.Name] = v
+	}
+	if len(positional) > 0 && len(named) > 0 {
+		return "", nil, nil, fmt.Errorf("parameters must be all named or all positional")
+	}
+	if len(positional) > 0 {
+		return query, positional, nil, nil
+	}
+
+	s := &splitter{src: query, dbType: dbType}
+	var b strings.Builder
+	var args []interface{}
+	used := make(map[string]bool)
+	index := make(map[string]int)
+	last := 0
+	for i := 0; i < len(query); {
+		if next := s.skipComment(i); next > i {
+			i = next
+			continue
+		}
+		if next := s.skipLiteral(i); next > i {
+			i = next
+			continue
+		}
+
+		// A :name placeholder, but not a PostgreSQL ::type cast
+		if query[i] != ':' || !identStart(query, i+1) || i > 0 && query[i-1] == ':' {
+			i++
+			continue
+		}
+		end := i + 1
+		for identChar(query, end) {
+			end++
+		}
+		name := query[i+1 : end]
+		v, exists := named[name]
+		if !exists {
+			i = end
+			continue
+		}
+
+		b.WriteString(query[last:i])
+		switch dbType {
+		case "postgres", "sqlserver":
+			// Numbered placeholders can repeat, so each name binds once
+			n, seen := index[name]
+			if !seen {
+				args = append(args, v)
+				n = len(args)
+				index[name] = n
+			}
+			if dbType == "postgres" {
+				b.WriteString("$" + strconv.Itoa(n))
+			} else {
+				b.WriteString("@p" + strconv.Itoa(n))
+			}
+		case "oracle":
+			args = append(args, v)
+			b.WriteString(":" + strconv.Itoa(len(args)))
+		default:
+			args = append(args, v)
+			b.WriteString("?")
+		}
+		used[name] = true
+		last = end
+		i = end
+	}
+	b.WriteString(query[last:])
+	return b.String(), args, used, nil
+}
+
+// identStart reports whether src[i] can start an identifier
+func identStart(src string, i int) bool {
+	return identChar(src, i) && (src[i] < '0' || src[i] > '9')
+}
+
+// paramValue converts a parameter to the value bound for it. Types are the
+// JSON type hints of result columns; without one the type follows the JSON
+// value. null binds NULL whatever the type.
+func paramValue(p models.QueryParam) (interface{}, error) {
+	raw := bytes.TrimSpace(p.Value)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	var text string
+	isString := raw[0] == '"'
+	if isString {
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+	}
+
+	switch p.Type {
+	case "":
+		switch {
+		case isString:
+			return text, nil
+		case raw[0] == '{' || raw[0] == '[':
+			if b, ok := binaryParam(raw); ok {
+				return b, nil
+			}
+			return string(raw), nil
+		case bytes.Equal(raw, []byte("true")), bytes.Equal(raw, []byte("false")):
+			return raw[0] == 't', nil
+		}
+		if n, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			return n, nil
+		}
+		return strconv.ParseFloat(string(raw), 64)
+	case jsonString:
+		if isString {
+			return text, nil
+		}
+		return string(raw), nil
+	case jsonInteger:
+		if isString {
+			raw = []byte(text)
+		}
+		return strconv.ParseInt(string(raw), 10, 64)
+	case jsonNumber:
+		if isString {
+			raw = []byte(text)
+		}
+		return strconv.ParseFloat(string(raw), 64)
+	case jsonDecimal:
+		if isString {
+			raw = []byte(text)
+		}
+		if _, err := strconv.ParseFloat(string(raw), 64); err != nil {
+			return nil, fmt.Errorf("invalid decimal %s", raw)
+		}
+		return string(raw), nil
+	case jsonBoolean:
+		if isString {
+			raw = []byte(text)
+		}
+		return strconv.ParseBool(string(raw))
+	case jsonDatetime:
+		if !isString {
+			return nil, fmt.Errorf("datetime values must be strings")
+		}
+		for _, layout := range datetimeLayouts {
+			if t, err := time.Parse(layout, text); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid datetime %q", text)
+	case jsonJSON:
+		// A string holds the JSON text itself; anything else is the value
+		if isString {
+			return text, nil
+		}
+		return string(raw), nil
+	case jsonBinary:
+		if isString {
+			return base64.StdEncoding.DecodeString(text)
+		}
+		if b, ok := binaryParam(raw); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("binary values must be base64 strings")
+	}
+	return nil, fmt.Errorf("unknown type %q", p.Type)
+}
+
+// binaryParam decodes a {"$base64": ...} value as returned in results
+func binaryParam(raw json.RawMessage) ([]byte, bool) {
+	var v models.BinaryValue
+	if err := json.Unmarshal(raw, &v); err != nil || v.Base64 == "" {
+		return nil, false
+	}
+	b, err := base64.StdEncoding.DecodeString(v.Base64)
+	return b, err == nil
+}
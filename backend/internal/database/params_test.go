@@ -0,0 +1,150 @@
+package database
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"opendbm/internal/models"
+)
+
+func named(name string, value string) models.QueryParam {
+	return models.QueryParam{Name: name, Value: json.RawMessage(value)}
+}
+
+func TestBindParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		dbType    string
+		query     string
+		params    []models.QueryParam
+		wantQuery string
+		wantArgs  []interface{}
+		wantErr   string
+	}{
+		{
+			"no params", "postgres", "SELECT :a", nil,
+			"SELECT :a", nil, "",
+		},
+		{
+			"mysql", "mysql", "SELECT * FROM t WHERE a = :a AND b = :b OR a = :a",
+			[]models.QueryParam{named("a", "1"), named("b", `"x"`)},
+			"SELECT * FROM t WHERE a = ? AND b = ? OR a = ?", []interface{}{int64(1), "x", int64(1)}, "",
+		},
+		{
+			"postgres numbers each name once", "postgres", "SELECT :a, :b, :a",
+			[]models.QueryParam{named("a", "1"), named("b", "2")},
+			"SELECT $1, $2, $1", []interface{}{int64(1), int64(2)}, "",
+		},
+		{
+			"sqlserver", "sqlserver", "SELECT :a, :a",
+			[]models.QueryParam{named("a", "true")},
+			"SELECT @p1, @p1", []interface{}{true}, "",
+		},
+		{
+			"oracle", "oracle", "SELECT :a, :a FROM dual",
+			[]models.QueryParam{named("a", "1.5")},
+			"SELECT :1, :2 FROM dual", []interface{}{1.5, 1.5}, "",
+		},
+		{
+			"strings, identifiers, comments and casts are left alone", "postgres",
+			"SELECT ':a', \":a\", x::text, $$ :a $$ -- :a\n, /* :a */ :a",
+			[]models.QueryParam{named("a", "1")},
+			"SELECT ':a', \":a\", x::text, $$ :a $$ -- :a\n, /* :a */ $1", []interface{}{int64(1)}, "",
+		},
+		{
+			"unknown names stay", "sqlite", "SELECT :a, :other, :1",
+			[]models.QueryParam{named("a", "null")},
+			"SELECT ?, :other, :1", []interface{}{nil}, "",
+		},
+		{
+			"positional", "postgres", "SELECT $1, $2",
+			[]models.QueryParam{{Value: json.RawMessage("1")}, {Value: json.RawMessage(`"b"`)}},
+			"SELECT $1, $2", []interface{}{int64(1), "b"}, "",
+		},
+		{
+			"mixed", "postgres", "SELECT :a, $1",
+			[]models.QueryParam{named("a", "1"), {Value: json.RawMessage("2")}},
+			"", nil, "all named or all positional",
+		},
+		{
+			"duplicate", "postgres", "SELECT :a",
+			[]models.QueryParam{named("a", "1"), named("a", "2")},
+			"", nil, "given twice",
+		},
+		{
+			"unused", "postgres", "SELECT :a",
+			[]models.QueryParam{named("a", "1"), named("b", "2")},
+			"", nil, ":b is not used",
+		},
+		{
+			"bad value", "postgres", "SELECT :a",
+			[]models.QueryParam{{Name: "a", Type: "integer", Value: json.RawMessage(`"x"`)}},
+			"", nil, "parameter :a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := BindParams(tt.query, tt.dbType, tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("BindParams() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query != tt.wantQuery || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("BindParams() = %q, %#v; want %q, %#v", query, args, tt.wantQuery, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParamValue(t *testing.T) {
+	tests := []struct {
+		typ     string
+		value   string
+		want    interface{}
+		wantErr bool
+	}{
+		{"", "null", nil, false},
+		{"integer", "null", nil, false},
+		{"", `"text"`, "text", false},
+		{"", "42", int64(42), false},
+		{"", "4.5", 4.5, false},
+		{"", "false", false, false},
+		{"", `{"a":1}`, `{"a":1}`, false},
+		{"", `{"$base64":"AAE="}`, []byte{0, 1}, false},
+		{"string", "12", "12", false},
+		{"integer", `"12"`, int64(12), false},
+		{"integer", "1.5", nil, true},
+		{"number", `"1e3"`, 1000.0, false},
+		{"decimal", "12.50", "12.50", false},
+		{"decimal", `"abc"`, nil, true},
+		{"boolean", `"true"`, true, false},
+		{"datetime", `"2024-02-03"`, time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC), false},
+		{"datetime", `"2024-02-03 04:05:06"`, time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC), false},
+		{"datetime", "1", nil, true},
+		{"datetime", `"yesterday"`, nil, true},
+		{"json", `"[1]"`, "[1]", false},
+		{"json", "[1]", "[1]", false},
+		{"binary", `"AAE="`, []byte{0, 1}, false},
+		{"binary", `{"$base64":"AAE="}`, []byte{0, 1}, false},
+		{"binary", "1", nil, true},
+		{"uuid", `"x"`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := paramValue(models.QueryParam{Type: tt.typ, Value: json.RawMessage(tt.value)})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("paramValue(%s %s) error = %v, want error %v", tt.typ, tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("paramValue(%s %s) = %#v, want %#v", tt.typ, tt.value, got, tt.want)
+		}
+	}
+}
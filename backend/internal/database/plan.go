@@ -0,0 +1,582 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"opendbm/internal/models"
+)
+
+// parsePostgresPlan reads the output of EXPLAIN (FORMAT JSON)
+func parsePostgresPlan(plan *models.QueryPlan) error {
+	var statements []map[string]interface{}
+	if err := json.Unmarshal([]byte(plan.Raw), &statements); err != nil {
+		return fmt.Errorf("failed to parse plan: %w", err)
+	}
+
+	for _, statement := range statements {
+		if node, ok := statement["Plan"].(map[string]interface{}); ok {
+			plan.Plans = append(plan.Plans, postgresNode(node))
+		}
+		if t := planNumber(statement["Planning Time"]); t != nil {
+			plan.PlanningTime = t
+		}
+	}
+	return nil
+}
+
+func postgresNode(raw map[string]interface{}) models.PlanNode {
+	node := models.PlanNode{
+		Operation:     planString(raw["Node Type"]),
+		Object:        planString(raw["Relation Name"]),
+		Index:         planString(raw["Index Name"]),
+		EstimatedRows: planNumber(raw["Plan Rows"]),
+		ActualRows:    planNumber(raw["Actual Rows"]),
+		Cost:          planNumber(raw["Total Cost"]),
+		ActualTime:    planNumber(raw["Actual Total Time"]),
+		Loops:         planNumber(raw["Actual Loops"]),
+	}
+	if schema := planString(raw["Schema"]); schema != "" && node.Object != "" {
+		node.Object = schema + "." + node.Object
+	}
+	if node.Object == "" {
+		node.Object = planString(raw["Function Name"])
+	}
+	if node.Object == "" {
+		node.Object = planString(raw["CTE Name"])
+	}
+
+	for _, child := range planArray(raw["Plans"]) {
+		if child, ok := child.(map[string]interface{}); ok {
+			node.Children = append(node.Children, postgresNode(child))
+		}
+	}
+	node.Details = planDetails(raw, "Node Type", "Relation Name", "Schema", "Index Name", "Plan Rows",
+		"Actual Rows", "Total Cost", "Actual Total Time", "Actual Loops", "Plans")
+	return node
+}
+
+// mysqlAccessTypes names MySQL's table access types
+var mysqlAccessTypes = map[string]string{
+	"system":          "System Table",
+	"const":           "Constant Lookup",
+	"eq_ref":          "Unique Index Lookup",
+	"ref":             "Index Lookup",
+	"fulltext":        "Fulltext Index Lookup",
+	"ref_or_null":     "Index Lookup or Null",
+	"index_merge":     "Index Merge",
+	"unique_subquery": "Unique Subquery",
+	"index_subquery":  "Index Subquery",
+	"range":           "Index Range Scan",
+	"index":           "Full Index Scan",
+	"ALL":             "Full Table Scan",
+}
+
+// mysqlOperations names the keys of a MySQL JSON plan that wrap a step
+var mysqlOperations = map[string]string{
+	"ordering_operation":         "Order By",
+	"grouping_operation":         "Group By",
+	"duplicates_removal":         "Remove Duplicates",
+	"windowing":                  "Window",
+	"buffer_result":              "Buffer Result",
+	"materialized_from_subquery": "Materialize",
+}
+
+// mysqlSubqueries are the keys of a MySQL JSON plan holding subquery plans
+var mysqlSubqueries = map[string]bool{
+	"attached_subqueries":       true,
+	"optimized_away_subqueries": true,
+	"having_subqueries":         true,
+	"select_list_subqueries":    true,
+	"order_by_subqueries":       true,
+	"group_by_subqueries":       true,
+	"update_value_subqueries":   true,
+}
+
+// parseMySQLPlan reads the output of EXPLAIN FORMAT=JSON
+func parseMySQLPlan(plan *models.QueryPlan) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(plan.Raw), &raw); err != nil {
+		return fmt.Errorf("failed to parse plan: %w", err)
+	}
+	plan.Plans, _ = mysqlChildren(raw)
+	return nil
+}
+
+// mysqlChildren turns the steps nested in a MySQL plan object into nodes,
+// returning them with the object's other properties
+func mysqlChildren(raw map[string]interface{}) ([]models.PlanNode, map[string]interface{}) {
+	var children []models.PlanNode
+	details := map[string]interface{}{}
+	for _, key := range sortedKeys(raw) {
+		value := raw[key]
+		object, _ := value.(map[string]interface{})
+		switch {
+		case key == "query_block" && object != nil:
+			node := models.PlanNode{Operation: "Query Block", Cost: mysqlCost(object, "query_cost")}
+			node.Children, node.Details = mysqlChildren(object)
+			children = append(children, node)
+		case key == "table" && object != nil:
+			children = append(children, mysqlTable(object))
+		case key == "nested_loop":
+			node := models.PlanNode{Operation: "Nested Loop"}
+			for _, step := range planArray(value) {
+				if step, ok := step.(map[string]interface{}); ok {
+					nested, _ := mysqlChildren(step)
+					node.Children = append(node.Children, nested...)
+				}
+			}
+			children = append(children, node)
+		case mysqlOperations[key] != "" && object != nil:
+			node := models.PlanNode{Operation: mysqlOperations[key]}
+			node.Children, node.Details = mysqlChildren(object)
+			children = append(children, node)
+		case key == "union_result" && object != nil:
+			node := models.PlanNode{Operation: "Union", Object: planString(object["table_name"])}
+			for _, spec := range planArray(object["query_specifications"]) {
+				if spec, ok := spec.(map[string]interface{}); ok {
+					nested, _ := mysqlChildren(spec)
+					node.Children = append(node.Children, nested...)
+				}
+			}
+			node.Details = planDetails(object, "table_name", "query_specifications")
+			children = append(children, node)
+		case mysqlSubqueries[key]:
+			for _, subquery := range planArray(value) {
+				if subquery, ok := subquery.(map[string]interface{}); ok {
+					node := models.PlanNode{Operation: "Subquery"}
+					node.Children, node.Details = mysqlChildren(subquery)
+					children = append(children, node)
+				}
+			}
+		default:
+			details[key] = value
+		}
+	}
+	if len(details) == 0 {
+		details = nil
+	}
+	return children, details
+}
+
+func mysqlTable(raw map[string]interface{}) models.PlanNode {
+	accessType := planString(raw["access_type"])
+	node := models.PlanNode{
+		Operation:     mysqlAccessTypes[accessType],
+		Object:        planString(raw["table_name"]),
+		Index:         planString(raw["key"]),
+		EstimatedRows: planNumber(raw["rows_produced_per_join"]),
+		Cost:          mysqlCost(raw, "prefix_cost"),
+	}
+	if node.Operation == "" {
+		node.Operation = "Table Access"
+	}
+	if node.EstimatedRows == nil {
+		node.EstimatedRows = planNumber(raw["rows_examined_per_scan"])
+	}
+
+	var details map[string]interface{}
+	node.Children, details = mysqlChildren(raw)
+	delete(details, "table_name")
+	delete(details, "key")
+	if len(details) > 0 {
+		node.Details = details
+	}
+	return node
+}
+
+// mysqlCost reads a figure from the cost_info of a MySQL plan object
+func mysqlCost(raw map[string]interface{}, key string) *float64 {
+	info, _ := raw["cost_info"].(map[string]interface{})
+	return planNumber(info[key])
+}
+
+var (
+	// mysqlTreeLine matches a step of EXPLAIN ANALYZE output:
+	// "-> Table scan on t  (cost=1.25 rows=10) (actual time=0.03..0.04 rows=10 loops=1)"
+	mysqlTreeLine = regexp.MustCompile(`^(\s*)-> (.*?)(?:\s+\(cost=(?:[0-9.e+-]+\.\.)?([0-9.e+-]+) rows=([0-9.e+-]+)\))?(?:\s+\(actual time=[0-9.e+-]+\.\.([0-9.e+-]+) rows=([0-9.e+-]+) loops=([0-9]+)\)|\s+\((never executed)\))?\s*$`)
+	// mysqlTreeObject finds the table and index a step reads
+	mysqlTreeObject = regexp.MustCompile(`\bon (\S+)(?: using (\S+))?`)
+)
+
+// mysqlTreeStep is a step of EXPLAIN ANALYZE output while its tree is built
+type mysqlTreeStep struct {
+	indent   int
+	node     models.PlanNode
+	children []*mysqlTreeStep
+}
+
+func (s *mysqlTreeStep) planNode() models.PlanNode {
+	node := s.node
+	for _, child := range s.children {
+		node.Children = append(node.Children, child.planNode())
+	}
+	return node
+}
+
+// parseMySQLTree reads the indented tree EXPLAIN ANALYZE prints. Lines
+// that do not start a step continue the one before.
+func parseMySQLTree(raw string) []models.PlanNode {
+	var lines []string
+	for _, line := range strings.Split(raw, "\n") {
+		text := strings.TrimSpace(line)
+		switch {
+		case text == "":
+		case strings.HasPrefix(text, "-> ") || len(lines) == 0:
+			lines = append(lines, line)
+		default:
+			lines[len(lines)-1] += " " + text
+		}
+	}
+
+	var roots, stack []*mysqlTreeStep
+	for _, line := range lines {
+		m := mysqlTreeLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		step := &mysqlTreeStep{
+			indent: len(m[1]),
+			node: models.PlanNode{
+				Operation:     m[2],
+				Cost:          parsePlanNumber(m[3]),
+				EstimatedRows: parsePlanNumber(m[4]),
+				ActualTime:    parsePlanNumber(m[5]),
+				ActualRows:    parsePlanNumber(m[6]),
+				Loops:         parsePlanNumber(m[7]),
+			},
+		}
+		if m[8] != "" {
+			zero := 0.0
+			step.node.Loops = &zero
+		}
+		if object := mysqlTreeObject.FindStringSubmatch(m[2]); object != nil {
+			step.node.Object = object[1]
+			step.node.Index = object[2]
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= step.indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, step)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, step)
+		}
+		stack = append(stack, step)
+	}
+
+	plans := make([]models.PlanNode, 0, len(roots))
+	for _, root := range roots {
+		plans = append(plans, root.planNode())
+	}
+	return plans
+}
+
+// sqliteStep is a row of EXPLAIN QUERY PLAN
+type sqliteStep struct {
+	id     int64
+	parent int64
+	detail string
+}
+
+// sqliteAccess splits a SQLite step such as "SEARCH t USING INDEX i (a=?)".
+// Automatic indexes have no name, only the constraint in parentheses.
+var sqliteAccess = regexp.MustCompile(`^(SCAN|SEARCH)(?: TABLE)? (\S+)(?: AS \S+)?(?: USING (?:COVERING |AUTOMATIC (?:PARTIAL )?COVERING )?INDEX ([^\s(]\S*))?`)
+
+// sqlitePlan builds the tree of EXPLAIN QUERY PLAN rows from their parent
+// IDs, where 0 is the root
+func sqlitePlan(steps []sqliteStep) []models.PlanNode {
+	children := map[int64][]sqliteStep{}
+	for _, step := range steps {
+		children[step.parent] = append(children[step.parent], step)
+	}
+
+	var build func(parent int64) []models.PlanNode
+	build = func(parent int64) []models.PlanNode {
+		var nodes []models.PlanNode
+		for _, step := range children[parent] {
+			node := models.PlanNode{Operation: step.detail}
+			if m := sqliteAccess.FindStringSubmatch(step.detail); m != nil {
+				node.Operation = strings.ToLower(m[1])
+				node.Operation = strings.ToUpper(node.Operation[:1]) + node.Operation[1:]
+				node.Object = m[2]
+				node.Index = m[3]
+				node.Details = map[string]interface{}{"detail": step.detail}
+			}
+			if step.id != parent {
+				node.Children = build(step.id)
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	plans := build(0)
+	if plans == nil {
+		plans = []models.PlanNode{}
+	}
+	return plans
+}
+
+// xmlElement is an element of a showplan document
+type xmlElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr   `xml:",any,attr"`
+	Elements []xmlElement `xml:",any"`
+}
+
+func (e *xmlElement) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// parseShowplan reads a SQL Server showplan document into a tree per
+// statement, with the operators (RelOp elements) of its plan below it
+func parseShowplan(doc string) ([]models.PlanNode, error) {
+	var root xmlElement
+	decoder := xml.NewDecoder(strings.NewReader(doc))
+	// The document declares UTF-16, its encoding on the server, but the
+	// driver has already decoded it
+	decoder.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) {
+		return r, nil
+	}
+	if err := decoder.Decode(&root); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	return showplanChildren(&root), nil
+}
+
+// showplanChildren finds the statements and operators below an element,
+// without descending into them
+func showplanChildren(e *xmlElement) []models.PlanNode {
+	var nodes []models.PlanNode
+	for i := range e.Elements {
+		child := &e.Elements[i]
+		switch {
+		case child.XMLName.Local == "RelOp":
+			nodes = append(nodes, showplanOperator(child))
+		case strings.HasPrefix(child.XMLName.Local, "Stmt") && child.attr("StatementType") != "":
+			node := models.PlanNode{
+				Operation:     child.attr("StatementType"),
+				EstimatedRows: parsePlanNumber(child.attr("StatementEstRows")),
+				Cost:          parsePlanNumber(child.attr("StatementSubTreeCost")),
+				Children:      showplanChildren(child),
+			}
+			if text := child.attr("StatementText"); text != "" {
+				node.Details = map[string]interface{}{"statement": strings.TrimSpace(text)}
+			}
+			nodes = append(nodes, node)
+		default:
+			nodes = append(nodes, showplanChildren(child)...)
+		}
+	}
+	return nodes
+}
+
+// showplanOperator converts a RelOp element. Actual figures are summed
+// over the threads that ran the operator and given per execution.
+func showplanOperator(e *xmlElement) models.PlanNode {
+	node := models.PlanNode{
+		Operation:     e.attr("PhysicalOp"),
+		EstimatedRows: parsePlanNumber(e.attr("EstimateRows")),
+		Cost:          parsePlanNumber(e.attr("EstimatedTotalSubtreeCost")),
+		Children:      showplanChildren(e),
+	}
+
+	details := map[string]interface{}{}
+	for _, a := range e.Attrs {
+		switch a.Name.Local {
+		case "PhysicalOp", "EstimateRows", "EstimatedTotalSubtreeCost", "NodeId":
+		default:
+			details[a.Name.Local] = a.Value
+		}
+	}
+	if len(details) > 0 {
+		node.Details = details
+	}
+
+	if object := findElement(e, "Object"); object != nil {
+		node.Object = strings.Trim(object.attr("Table"), "[]")
+		if schema := strings.Trim(object.attr("Schema"), "[]"); schema != "" && node.Object != "" {
+			node.Object = schema + "." + node.Object
+		}
+		node.Index = strings.Trim(object.attr("Index"), "[]")
+	}
+
+	if runtime := findElement(e, "RunTimeInformation"); runtime != nil {
+		var rows, executions, elapsed float64
+		timed := false
+		for i := range runtime.Elements {
+			thread := &runtime.Elements[i]
+			if n := parsePlanNumber(thread.attr("ActualRows")); n != nil {
+				rows += *n
+			}
+			if n := parsePlanNumber(thread.attr("ActualExecutions")); n != nil {
+				executions += *n
+			}
+			if n := parsePlanNumber(thread.attr("ActualElapsedms")); n != nil {
+				timed = true
+				if *n > elapsed {
+					elapsed = *n
+				}
+			}
+		}
+		node.Loops = &executions
+		if executions > 0 {
+			rows /= executions
+			elapsed /= executions
+		}
+		node.ActualRows = &rows
+		if timed {
+			node.ActualTime = &elapsed
+		}
+	}
+	return node
+}
+
+// findElement returns the first element with the given name below e that
+// belongs to e rather than to a nested operator
+func findElement(e *xmlElement, name string) *xmlElement {
+	for i := range e.Elements {
+		child := &e.Elements[i]
+		if child.XMLName.Local == name {
+			return child
+		}
+		if child.XMLName.Local == "RelOp" {
+			continue
+		}
+		if found := findElement(child, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// oracleStep is a row of PLAN_TABLE
+type oracleStep struct {
+	id        int64
+	parent    sql.NullInt64
+	operation string
+	options   sql.NullString
+	owner     sql.NullString
+	object    sql.NullString
+	rows      sql.NullFloat64
+	cost      sql.NullFloat64
+	time      sql.NullFloat64 // seconds
+}
+
+// oraclePlan builds the tree of PLAN_TABLE rows from their parent IDs
+func oraclePlan(steps []oracleStep) []models.PlanNode {
+	children := map[int64][]oracleStep{}
+	var roots []oracleStep
+	for _, step := range steps {
+		if step.parent.Valid {
+			children[step.parent.Int64] = append(children[step.parent.Int64], step)
+		} else {
+			roots = append(roots, step)
+		}
+	}
+
+	var build func(step oracleStep) models.PlanNode
+	build = func(step oracleStep) models.PlanNode {
+		node := models.PlanNode{Operation: step.operation}
+		if step.options.String != "" {
+			node.Operation += " " + step.options.String
+		}
+		if step.object.String != "" {
+			node.Object = step.object.String
+			if step.owner.String != "" {
+				node.Object = step.owner.String + "." + node.Object
+			}
+		}
+		if step.rows.Valid {
+			node.EstimatedRows = &step.rows.Float64
+		}
+		if step.cost.Valid {
+			node.Cost = &step.cost.Float64
+		}
+		if step.time.Valid {
+			node.Details = map[string]interface{}{"estimatedSeconds": step.time.Float64}
+		}
+		for _, child := range children[step.id] {
+			node.Children = append(node.Children, build(child))
+		}
+		return node
+	}
+
+	plans := make([]models.PlanNode, 0, len(roots))
+	for _, root := range roots {
+		plans = append(plans, build(root))
+	}
+	return plans
+}
+
+// planDetails returns the properties of a plan object other than the
+// given ones, or nil when none are left
+func planDetails(raw map[string]interface{}, skip ...string) map[string]interface{} {
+	details := map[string]interface{}{}
+	for key, value := range raw {
+		details[key] = value
+	}
+	for _, key := range skip {
+		delete(details, key)
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+func planString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func planArray(v interface{}) []interface{} {
+	a, _ := v.([]interface{})
+	return a
+}
+
+// planNumber reads a figure that plans give as a JSON number or a string
+func planNumber(v interface{}) *float64 {
+	switch v := v.(type) {
+	case float64:
+		return &v
+	case string:
+		return parsePlanNumber(v)
+	}
+	return nil
+}
+
+func parsePlanNumber(s string) *float64 {
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
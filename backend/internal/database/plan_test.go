@@ -0,0 +1,292 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"testing"
+
+	"opendbm/internal/models"
+)
+
+// planTree spells plan nodes as operation[object index](figures){children},
+// leaving out what a node does not set
+func planTree(nodes []models.PlanNode) string {
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		var b strings.Builder
+		b.WriteString(node.Operation)
+		if node.Object != "" || node.Index != "" {
+			b.WriteString("[" + strings.TrimSpace(node.Object+" "+node.Index) + "]")
+		}
+		var figures []string
+		for _, f := range []struct {
+			name  string
+			value *float64
+		}{
+			{"est", node.EstimatedRows}, {"rows", node.ActualRows}, {"cost", node.Cost},
+			{"time", node.ActualTime}, {"loops", node.Loops},
+		} {
+			if f.value != nil {
+				figures = append(figures, f.name+"="+strconv.FormatFloat(*f.value, 'g', -1, 64))
+			}
+		}
+		if len(figures) > 0 {
+			b.WriteString("(" + strings.Join(figures, " ") + ")")
+		}
+		if len(node.Children) > 0 {
+			b.WriteString("{" + planTree(node.Children) + "}")
+		}
+		parts[i] = b.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestParsePostgresPlan(t *testing.T) {
+	plan := &models.QueryPlan{Raw: `[{
+		"Plan": {
+			"Node Type": "Hash Join", "Total Cost": 10.5, "Plan Rows": 3, "Actual Rows": 2,
+			"Actual Total Time": 0.5, "Actual Loops": 1, "Hash Cond": "(a.id = b.a_id)",
+			"Plans": [
+				{"Node Type": "Seq Scan", "Schema": "public", "Relation Name": "a", "Plan Rows": 10},
+				{"Node Type": "Hash", "Plans": [
+					{"Node Type": "Index Scan", "Relation Name": "b", "Index Name": "b_pkey"},
+					{"Node Type": "Function Scan", "Function Name": "generate_series"}
+				]}
+			]
+		},
+		"Planning Time": 0.25,
+		"Execution Time": 0.7
+	}]`}
+	if err := parsePostgresPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	want := "Hash Join(est=3 rows=2 cost=10.5 time=0.5 loops=1){Seq Scan[public.a](est=10) Hash{Index Scan[b b_pkey] Function Scan[generate_series]}}"
+	if got := planTree(plan.Plans); got != want {
+		t.Errorf("plan = %s, want %s", got, want)
+	}
+	if plan.PlanningTime == nil || *plan.PlanningTime != 0.25 {
+		t.Errorf("planning time = %v, want 0.25", plan.PlanningTime)
+	}
+	if details := plan.Plans[0].Details; len(details) != 1 || details["Hash Cond"] != "(a.id = b.a_id)" {
+		t.Errorf("details = %v, want the hash condition only", details)
+	}
+
+	if err := parsePostgresPlan(&models.QueryPlan{Raw: "Seq Scan on a"}); err == nil {
+		t.Error("parsing a text plan as JSON succeeded")
+	}
+}
+
+func TestParseMySQLPlan(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{
+			"join",
+			`{"query_block": {"select_id": 1, "cost_info": {"query_cost": "2.40"}, "ordering_operation": {"using_filesort": true, "nested_loop": [
+				{"table": {"table_name": "a", "access_type": "ALL", "rows_examined_per_scan": 10, "rows_produced_per_join": 8, "cost_info": {"prefix_cost": "1.25"}}},
+				{"table": {"table_name": "b", "access_type": "eq_ref", "key": "PRIMARY", "rows_examined_per_scan": 1, "cost_info": {"prefix_cost": "2.40"},
+					"attached_subqueries": [{"query_block": {"select_id": 2, "table": {"table_name": "c", "access_type": "const"}}}]}}
+			]}}}`,
+			"Query Block(cost=2.4){Order By{Nested Loop{Full Table Scan[a](est=8 cost=1.25) Unique Index Lookup[b PRIMARY](est=1 cost=2.4){Subquery{Query Block{Constant Lookup[c]}}}}}}",
+		},
+		{
+			"union",
+			`{"query_block": {"union_result": {"table_name": "<union1,2>", "access_type": "ALL", "query_specifications": [
+				{"query_block": {"select_id": 1, "table": {"table_name": "a", "access_type": "index", "key": "a_x"}}},
+				{"query_block": {"select_id": 2, "message": "No tables used"}}
+			]}}}`,
+			"Query Block{Union[<union1,2>]{Query Block{Full Index Scan[a a_x]} Query Block}}",
+		},
+		{
+			"unknown access type",
+			`{"query_block": {"table": {"table_name": "t", "access_type": "skip_scan"}}}`,
+			"Query Block{Table Access[t]}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &models.QueryPlan{Raw: tt.raw}
+			if err := parseMySQLPlan(plan); err != nil {
+				t.Fatal(err)
+			}
+			if got := planTree(plan.Plans); got != tt.want {
+				t.Errorf("plan = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	plan := &models.QueryPlan{Raw: `{"query_block": {"select_id": 1, "ordering_operation": {"using_filesort": true, "table": {"table_name": "a", "access_type": "ALL", "filtered": "100.00"}}}}`}
+	if err := parseMySQLPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	block := plan.Plans[0]
+	if block.Details["select_id"] != float64(1) || block.Children[0].Details["using_filesort"] != true ||
+		block.Children[0].Children[0].Details["filtered"] != "100.00" {
+		t.Errorf("details are not kept: %+v", block)
+	}
+
+	if err := parseMySQLPlan(&models.QueryPlan{Raw: "[1"}); err == nil {
+		t.Error("parsing malformed JSON succeeded")
+	}
+}
+
+func TestParseMySQLTree(t *testing.T) {
+	raw := strings.Join([]string{
+		"-> Sort: a.name  (cost=2.5 rows=10) (actual time=0.1..0.2 rows=10 loops=1)",
+		"    -> Nested loop inner join  (cost=1.5..2.25 rows=10) (actual time=0.05..0.15 rows=10 loops=1)",
+		"        -> Table scan on a  (cost=1.25 rows=10) (actual time=0.03..0.04 rows=10 loops=1)",
+		"        -> Filter: (b.x > 1)",
+		"            (cost=0.35 rows=1) (actual time=0.01..0.01 rows=1 loops=10)",
+		"            -> Index lookup on b using idx_x (x=a.x)  (cost=0.35 rows=1) (never executed)",
+		"",
+		"-> Rows fetched before execution  (cost=0..0 rows=1) (actual time=0..0 rows=1 loops=1)",
+	}, "\n")
+	want := "Sort: a.name(est=10 rows=10 cost=2.5 time=0.2 loops=1){" +
+		"Nested loop inner join(est=10 rows=10 cost=2.25 time=0.15 loops=1){" +
+		"Table scan on a[a](est=10 rows=10 cost=1.25 time=0.04 loops=1) " +
+		"Filter: (b.x > 1)(est=1 rows=1 cost=0.35 time=0.01 loops=10){" +
+		"Index lookup on b using idx_x (x=a.x)[b idx_x](est=1 cost=0.35 loops=0)}}} " +
+		"Rows fetched before execution(est=1 rows=1 cost=0 time=0 loops=1)"
+	if got := planTree(parseMySQLTree(raw)); got != want {
+		t.Errorf("plan = %s\nwant %s", got, want)
+	}
+	if plans := parseMySQLTree(""); len(plans) != 0 {
+		t.Errorf("empty output = %v, want no plans", plans)
+	}
+}
+
+func TestSQLitePlan(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []sqliteStep
+		want  string
+	}{
+		{"none", nil, ""},
+		{
+			"join",
+			[]sqliteStep{
+				{2, 0, "SCAN a"},
+				{4, 0, "SEARCH b USING INDEX b_a (a_id=?)"},
+				{7, 0, "USE TEMP B-TREE FOR ORDER BY"},
+			},
+			"Scan[a] Search[b b_a] USE TEMP B-TREE FOR ORDER BY",
+		},
+		{
+			"older format",
+			[]sqliteStep{
+				{1, 0, "SCAN TABLE a AS x"},
+				{2, 0, "SEARCH TABLE b AS y USING COVERING INDEX b_a (a_id=?)"},
+				{3, 0, "SEARCH c USING AUTOMATIC PARTIAL COVERING INDEX (k=?)"},
+			},
+			"Scan[a] Search[b b_a] Search[c]",
+		},
+		{
+			"nested",
+			[]sqliteStep{
+				{1, 0, "COMPOUND QUERY"},
+				{2, 1, "LEFT-MOST SUBQUERY"},
+				{3, 2, "SCAN a"},
+				{5, 1, "UNION ALL"},
+				{6, 5, "SCAN b"},
+			},
+			"COMPOUND QUERY{LEFT-MOST SUBQUERY{Scan[a]} UNION ALL{Scan[b]}}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plans := sqlitePlan(tt.steps)
+			if plans == nil {
+				t.Fatal("plans are nil, want an empty list")
+			}
+			if got := planTree(plans); got != tt.want {
+				t.Errorf("plan = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	plans := sqlitePlan([]sqliteStep{{2, 0, "SEARCH b USING INDEX b_a (a_id=?)"}})
+	if plans[0].Details["detail"] != "SEARCH b USING INDEX b_a (a_id=?)" {
+		t.Errorf("details = %v, want the step's text", plans[0].Details)
+	}
+}
+
+func TestParseShowplan(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="utf-16"?>
+<ShowPlanXML xmlns="http://schemas.microsoft.com/sqlserver/2004/07/showplan" Version="1.5">
+  <BatchSequence><Batch><Statements>
+    <StmtSimple StatementText="SELECT * FROM t JOIN u ON u.t_id = t.id " StatementType="SELECT" StatementSubTreeCost="0.0065" StatementEstRows="2">
+      <QueryPlan>
+        <RelOp NodeId="0" PhysicalOp="Nested Loops" LogicalOp="Inner Join" EstimateRows="2" EstimatedTotalSubtreeCost="0.0065">
+          <RunTimeInformation>
+            <RunTimeCountersPerThread Thread="0" ActualRows="2" ActualExecutions="1" ActualElapsedms="3" />
+          </RunTimeInformation>
+          <NestedLoops>
+            <RelOp NodeId="1" PhysicalOp="Clustered Index Scan" LogicalOp="Clustered Index Scan" EstimateRows="2" EstimatedTotalSubtreeCost="0.0032">
+              <IndexScan><Object Database="[db]" Schema="[dbo]" Table="[t]" Index="[PK_t]" /></IndexScan>
+            </RelOp>
+            <RelOp NodeId="2" PhysicalOp="Index Seek" LogicalOp="Index Seek" EstimateRows="1" EstimatedTotalSubtreeCost="0.0031">
+              <RunTimeInformation>
+                <RunTimeCountersPerThread Thread="1" ActualRows="3" ActualExecutions="2" ActualElapsedms="1" />
+                <RunTimeCountersPerThread Thread="2" ActualRows="1" ActualExecutions="2" ActualElapsedms="2" />
+              </RunTimeInformation>
+              <IndexScan><Object Schema="[dbo]" Table="[u]" Index="[IX_u_t]" /></IndexScan>
+            </RelOp>
+          </NestedLoops>
+        </RelOp>
+      </QueryPlan>
+    </StmtSimple>
+    <StmtSimple StatementText="SET NOCOUNT ON" StatementType="SET ON/OFF" />
+  </Statements></Batch></BatchSequence>
+</ShowPlanXML>`
+	plans, err := parseShowplan(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT(est=2 cost=0.0065){Nested Loops(est=2 rows=2 cost=0.0065 time=3 loops=1){" +
+		"Clustered Index Scan[dbo.t PK_t](est=2 cost=0.0032) " +
+		"Index Seek[dbo.u IX_u_t](est=1 rows=1 cost=0.0031 time=0.5 loops=4)}} SET ON/OFF"
+	if got := planTree(plans); got != want {
+		t.Errorf("plan = %s\nwant %s", got, want)
+	}
+	if plans[0].Details["statement"] != "SELECT * FROM t JOIN u ON u.t_id = t.id" {
+		t.Errorf("statement details = %v", plans[0].Details)
+	}
+	join := plans[0].Children[0]
+	if len(join.Details) != 1 || join.Details["LogicalOp"] != "Inner Join" {
+		t.Errorf("operator details = %v, want the logical operator only", join.Details)
+	}
+
+	if _, err := parseShowplan("<ShowPlanXML><Unclosed>"); err == nil {
+		t.Error("parsing malformed XML succeeded")
+	}
+}
+
+func TestOraclePlan(t *testing.T) {
+	steps := []oracleStep{
+		{id: 0, operation: "SELECT STATEMENT", rows: sql.NullFloat64{Float64: 1, Valid: true}, cost: sql.NullFloat64{Float64: 2, Valid: true}},
+		{
+			id: 1, parent: sql.NullInt64{Int64: 0, Valid: true}, operation: "TABLE ACCESS",
+			options: sql.NullString{String: "BY INDEX ROWID", Valid: true},
+			owner:   sql.NullString{String: "HR", Valid: true}, object: sql.NullString{String: "EMP", Valid: true},
+			time: sql.NullFloat64{Float64: 1, Valid: true},
+		},
+		{
+			id: 2, parent: sql.NullInt64{Int64: 1, Valid: true}, operation: "INDEX",
+			options: sql.NullString{String: "UNIQUE SCAN", Valid: true}, object: sql.NullString{String: "EMP_PK", Valid: true},
+		},
+	}
+	want := "SELECT STATEMENT(est=1 cost=2){TABLE ACCESS BY INDEX ROWID[HR.EMP]{INDEX UNIQUE SCAN[EMP_PK]}}"
+	plans := oraclePlan(steps)
+	if got := planTree(plans); got != want {
+		t.Errorf("plan = %s, want %s", got, want)
+	}
+	if plans[0].Children[0].Details["estimatedSeconds"] != float64(1) {
+		t.Errorf("details = %v, want the estimated time", plans[0].Children[0].Details)
+	}
+	if plans := oraclePlan(nil); plans == nil || len(plans) != 0 {
+		t.Errorf("no steps = %v, want an empty list", plans)
+	}
+}
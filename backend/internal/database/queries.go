@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrQueryNotFound is returned when canceling a query that is not running
+var ErrQueryNotFound = errors.New("query not found")
+
+// RunningQuery describes a query that is currently executing
+type RunningQuery struct {
+	ID           string    `json:"id"`
+	ConnectionID string    `json:"connectionId"`
+	SQL          string    `json:"sql"`
+	StartedAt    time.Time `json:"startedAt"`
+	Timeout      int       `json:"timeout,omitempty"` // seconds
+	cancel       context.CancelFunc
+}
+
+// QueryRegistry tracks running queries by ID so they can be listed and
+// canceled from another request. Canceling only ends the query's context;
+// drivers watch that context and stop the statement on the server.
+type QueryRegistry struct {
+	queries map[string]*RunningQuery
+	mu      sync.Mutex
+}
+
+// NewQueryRegistry creates an empty query registry
+func NewQueryRegistry() *QueryRegistry {
+	return &QueryRegistry{queries: make(map[string]*RunningQuery)}
+}
+
+// Start registers a query and returns the context it must run under, which
+// ends when parent ends, the timeout passes or the query is canceled. An
+// empty queryID gets a generated one. Call done once the query finishes.
+func (r *QueryRegistry) Start(parent context.Context, queryID string, connectionID string, sql string, timeout time.Duration) (ctx context.Context, id string, done func(), err error) {
+	if queryID == "" {
+		queryID = uuid.New().String()
+	}
+
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.queries[queryID]; exists {
+		cancel()
+		return nil, "", nil, fmt.Errorf("query %s is already running", queryID)
+	}
+	r.queries[queryID] = &RunningQuery{
+		ID:           queryID,
+		ConnectionID: connectionID,
+		SQL:          sql,
+		StartedAt:    time.Now(),
+		Timeout:      int(timeout / time.Second),
+		cancel:       cancel,
+	}
+
+	done = func() {
+		r.mu.Lock()
+		delete(r.queries, queryID)
+		r.mu.Unlock()
+		cancel()
+	}
+	return ctx, queryID, done, nil
+}
+
+// Cancel stops a running query
+func (r *QueryRegistry) Cancel(queryID string) error {
+	r.mu.Lock()
+	q, exists := r.queries[queryID]
+	r.mu.Unlock()
+
+	if !exists {
+		return ErrQueryNotFound
+	}
+	q.cancel()
+	return nil
+}
+
+// List returns the running queries, oldest first
+func (r *QueryRegistry) List() []RunningQuery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]RunningQuery, 0, len(r.queries))
+	for _, q := range r.queries {
+		result = append(result, *q)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartedAt.Before(result[j].StartedAt)
+	})
+	return result
+}
+
+// queryError describes a failed statement, naming the reason when its
+// context ended rather than the driver's generic error
+func queryError(ctx context.Context, err error) string {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return "query timed out and was canceled"
+	case context.Canceled:
+		return "query was canceled"
+	}
+	return err.Error()
+}
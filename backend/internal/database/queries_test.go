@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueryRegistry(t *testing.T) {
+	r := NewQueryRegistry()
+	ctx, id, done, err := r.Start(context.Background(), "q1", "conn", "SELECT 1", 0)
+	if err != nil || id != "q1" {
+		t.Fatalf("Start() = %q, %v", id, err)
+	}
+	if _, _, _, err := r.Start(context.Background(), "q1", "conn", "SELECT 2", 0); err == nil {
+		t.Error("a second query with the same ID started")
+	}
+
+	_, generated, doneGenerated, err := r.Start(context.Background(), "", "conn", "SELECT 3", 5*time.Second)
+	if err != nil || generated == "" {
+		t.Fatalf("Start() without an ID = %q, %v", generated, err)
+	}
+	defer doneGenerated()
+
+	running := r.List()
+	if len(running) != 2 || running[0].ID != "q1" || running[1].ID != generated || running[1].Timeout != 5 {
+		t.Errorf("List() = %+v, want q1 then %s with a 5s timeout", running, generated)
+	}
+
+	if err := r.Cancel("q1"); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("canceled query context error = %v", ctx.Err())
+	}
+	done()
+	if err := r.Cancel("q1"); !errors.Is(err, ErrQueryNotFound) {
+		t.Errorf("Cancel() after done = %v, want ErrQueryNotFound", err)
+	}
+	if running := r.List(); len(running) != 1 {
+		t.Errorf("List() after done = %+v, want one query", running)
+	}
+}
+
+func TestQueryRegistryTimeout(t *testing.T) {
+	r := NewQueryRegistry()
+	ctx, _, done, err := r.Start(context.Background(), "", "conn", "SELECT 1", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+	<-ctx.Done()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("context error = %v, want the deadline", ctx.Err())
+	}
+}
+
+func TestStartQueryConnectionTimeout(t *testing.T) {
+	m, id := newTestManager(t)
+	m.mu.Lock()
+	m.connections[id].Config.QueryTimeout = 7
+	m.mu.Unlock()
+
+	tests := []struct {
+		timeout time.Duration
+		want    int
+	}{
+		{0, 7},
+		{3 * time.Second, 3},
+	}
+	for _, tt := range tests {
+		_, queryID, done, err := m.StartQuery(context.Background(), id, "", "SELECT 1", tt.timeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		running := m.Queries().List()
+		done()
+		if len(running) != 1 || running[0].ID != queryID || running[0].Timeout != tt.want {
+			t.Errorf("StartQuery(%v) registered %+v, want a %ds timeout", tt.timeout, running, tt.want)
+		}
+	}
+}
+
+// slowQuery counts far enough that only canceling ends it
+const slowQuery = "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT max(i) FROM n"
+
+func TestExecuteQueryCanceled(t *testing.T) {
+	d, id := newTestSQLite(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := d.ExecuteQuery(ctx, id, "", slowQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "query timed out and was canceled" {
+		t.Errorf("error after the timeout = %q", result.Error)
+	}
+
+	r := NewQueryRegistry()
+	ctx, queryID, done, err := r.Start(context.Background(), "", id, slowQuery, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+	time.AfterFunc(50*time.Millisecond, func() { r.Cancel(queryID) })
+	result, err = d.ExecuteQuery(ctx, id, "", slowQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "query was canceled" {
+		t.Errorf("error after canceling = %q", result.Error)
+	}
+
+	// The connection is usable once the statement has stopped
+	result, err = d.ExecuteQuery(context.Background(), id, "", "SELECT 1")
+	if err != nil || result.Error != "" {
+		t.Errorf("query after canceling = %v, %s", err, result.Error)
+	}
+}
@@ -0,0 +1,845 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"opendbm/internal/models"
+)
+
+const (
+	redisTimeout      = 30 * time.Second
+	redisConnectLimit = 10 * time.Second
+	defaultKeyCount   = 100
+	maxKeyCount       = 1000
+)
+
+// RedisDriverImpl implements KeyValueDriver for Redis. Every logical
+// database gets its own client, created on first use from the options the
+// connection was opened with.
+type RedisDriverImpl struct {
+	options  map[string]*redis.Options
+	clients  map[string]map[int]*redis.Client
+	forwards map[string]*Forward
+	secrets  SecretResolver
+	tunnels  *TunnelManager
+	mu       sync.RWMutex
+}
+
+var _ KeyValueDriver = (*RedisDriverImpl)(nil)
+
+func init() {
+	Register("redis", func(env DriverEnv) Driver {
+		return NewRedisDriver(env.Secrets, env.Tunnels)
+	})
+}
+
+// NewRedisDriver creates a new Redis driver instance
+func NewRedisDriver(secrets SecretResolver, tunnels *TunnelManager) *RedisDriverImpl {
+	return &RedisDriverImpl{
+		options:  make(map[string]*redis.Options),
+		clients:  make(map[string]map[int]*redis.Client),
+		forwards: make(map[string]*Forward),
+		secrets:  secrets,
+		tunnels:  tunnels,
+	}
+}
+
+// Connect establishes a new Redis connection from a redis:// or rediss://
+// URL or from host, port and credentials
+func (d *RedisDriverImpl) Connect(config models.ConnectionConfig) (string, error) {
+	id := config.ID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	password, err := resolvePassword(d.secrets, config)
+	if err != nil {
+		return "", err
+	}
+
+	tlsConfig, err := buildTLSConfig(d.secrets, config, config.Host)
+	if err != nil {
+		return "", err
+	}
+
+	var opts *redis.Options
+	var forward *Forward
+	if config.URI != "" {
+		if config.UseSSH {
+			return "", fmt.Errorf("ssh tunnels require host and port instead of a connection string")
+		}
+		opts, err = redis.ParseURL(uriWithPassword(config.URI, password))
+		if err != nil {
+			return "", fmt.Errorf("invalid connection string: %w", err)
+		}
+	} else {
+		db := 0
+		if config.Database != "" {
+			if db, err = strconv.Atoi(config.Database); err != nil || db < 0 {
+				return "", fmt.Errorf("invalid database index: %s", config.Database)
+			}
+		}
+
+		host, port := config.Host, config.Port
+		if port == 0 {
+			port = 6379
+		}
+		if config.UseSSH {
+			forward, err = openForward(d.tunnels, config)
+			if err != nil {
+				return "", err
+			}
+			host, port = forward.LocalAddr()
+		}
+
+		opts = &redis.Options{
+			Addr:     host + ":" + strconv.Itoa(port),
+			Username: config.Username,
+			Password: password,
+			DB:       db,
+		}
+	}
+	if tlsConfig != nil {
+		opts.TLSConfig = tlsConfig
+	}
+	opts.DialTimeout = redisConnectLimit
+	opts.ContextTimeoutEnabled = true
+
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisConnectLimit)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		if forward != nil {
+			forward.Close()
+		}
+		return "", fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	d.mu.Lock()
+	d.close(id)
+	d.options[id] = opts
+	d.clients[id] = map[int]*redis.Client{opts.DB: client}
+	if forward != nil {
+		d.forwards[id] = forward
+	}
+	d.mu.Unlock()
+
+	return id, nil
+}
+
+// Disconnect closes a Redis connection
+func (d *RedisDriverImpl) Disconnect(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.close(id)
+	return nil
+}
+
+// close releases the clients and SSH forward of a connection; callers must hold d.mu
+func (d *RedisDriverImpl) close(id string) {
+	for _, client := range d.clients[id] {
+		client.Close()
+	}
+	if forward, exists := d.forwards[id]; exists {
+		forward.Close()
+	}
+
+	delete(d.options, id)
+	delete(d.clients, id)
+	delete(d.forwards, id)
+}
+
+// Ping checks if connection is alive
+func (d *RedisDriverImpl) Ping(id string) error {
+	client, err := d.client(id, nil)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisConnectLimit)
+	defer cancel()
+	return client.Ping(ctx).Err()
+}
+
+// ListDatabases lists the logical database indexes of the server
+func (d *RedisDriverImpl) ListDatabases(id string) ([]string, error) {
+	client, err := d.client(id, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	databases := make([]string, databaseCount(ctx, client))
+	for i := range databases {
+		databases[i] = strconv.Itoa(i)
+	}
+	return databases, nil
+}
+
+// ScanKeys returns one page of keys with their types and TTLs using SCAN,
+// so that large keyspaces never block the server
+func (d *RedisDriverImpl) ScanKeys(id string, req models.KeyScanRequest) (*models.KeyScanResult, error) {
+	client, err := d.client(id, req.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	count := pageSize(req.Count)
+	var keys []string
+	var cursor uint64
+	if req.Type != "" {
+		keys, cursor, err = client.ScanType(ctx, req.Cursor, req.Match, count, req.Type).Result()
+	} else {
+		keys, cursor, err = client.Scan(ctx, req.Cursor, req.Match, count).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := describeKeys(ctx, client, keys)
+	if err != nil {
+		return nil, err
+	}
+	return &models.KeyScanResult{Keys: infos, Cursor: cursor}, nil
+}
+
+// GetKey reads a key according to its type
+func (d *RedisDriverImpl) GetKey(id string, req models.KeyGetRequest) (*models.KeyValue, error) {
+	client, err := d.client(id, req.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	key := string(req.Key)
+	infos, err := describeKeys(ctx, client, []string{key})
+	if err != nil {
+		return nil, err
+	}
+	info := infos[0]
+	if info.Type == "none" {
+		return nil, fmt.Errorf("key not found: %s", key)
+	}
+
+	value := &models.KeyValue{Key: info.Key, Type: info.Type, TTL: info.TTL}
+	count := pageSize(req.Count)
+
+	switch info.Type {
+	case "string":
+		text, err := client.Get(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		value.Value = models.BinaryString(text)
+		value.Length = int64(len(text))
+
+	case "hash", "set":
+		cursor, err := scanCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		var items []string
+		var next uint64
+		if info.Type == "hash" {
+			items, next, err = client.HScan(ctx, key, cursor, "", count).Result()
+			if err == nil {
+				value.Length, err = client.HLen(ctx, key).Result()
+			}
+			value.Fields = make([]models.HashField, 0, len(items)/2)
+			for i := 0; i+1 < len(items); i += 2 {
+				value.Fields = append(value.Fields, models.HashField{
+					Field: models.BinaryString(items[i]),
+					Value: models.BinaryString(items[i+1]),
+				})
+			}
+		} else {
+			items, next, err = client.SScan(ctx, key, cursor, "", count).Result()
+			if err == nil {
+				value.Length, err = client.SCard(ctx, key).Result()
+			}
+			value.Items = binaryStrings(items)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if next != 0 {
+			value.Cursor = strconv.FormatUint(next, 10)
+		}
+
+	case "list", "zset":
+		offset, err := offsetCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		var read int64
+		if info.Type == "list" {
+			var items []string
+			items, err = client.LRange(ctx, key, offset, offset+count-1).Result()
+			if err == nil {
+				value.Length, err = client.LLen(ctx, key).Result()
+			}
+			value.Items = binaryStrings(items)
+			read = int64(len(items))
+		} else {
+			var members []redis.Z
+			members, err = client.ZRangeWithScores(ctx, key, offset, offset+count-1).Result()
+			if err == nil {
+				value.Length, err = client.ZCard(ctx, key).Result()
+			}
+			value.Members = make([]models.ZMember, len(members))
+			for i, m := range members {
+				value.Members[i] = models.ZMember{Member: models.BinaryString(fmt.Sprint(m.Member)), Score: m.Score}
+			}
+			read = int64(len(members))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if offset+read < value.Length {
+			value.Cursor = strconv.FormatInt(offset+read, 10)
+		}
+
+	case "stream":
+		start := "-"
+		if req.Cursor != "" {
+			start = req.Cursor
+		}
+		messages, err := client.XRangeN(ctx, key, start, "+", count).Result()
+		if err != nil {
+			return nil, err
+		}
+		if value.Length, err = client.XLen(ctx, key).Result(); err != nil {
+			return nil, err
+		}
+		value.Entries = make([]models.StreamEntry, len(messages))
+		for i, m := range messages {
+			value.Entries[i] = models.StreamEntry{ID: m.ID, Fields: streamFields(m.Values)}
+		}
+		if int64(len(messages)) == count {
+			value.Cursor = nextStreamID(messages[len(messages)-1].ID)
+		}
+
+	default:
+		return nil, fmt.Errorf("reading %s keys is not supported; use the console instead", info.Type)
+	}
+
+	return value, nil
+}
+
+// WriteKey applies a type specific write to a key
+func (d *RedisDriverImpl) WriteKey(id string, req models.KeyWriteRequest) error {
+	client, err := d.client(id, req.Database)
+	if err != nil {
+		return err
+	}
+	if req.Key == "" {
+		return fmt.Errorf("key is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	key := string(req.Key)
+	names := make([]string, len(req.Values))
+	values := make([]interface{}, len(req.Values))
+	for i, v := range req.Values {
+		names[i] = string(v)
+		values[i] = names[i]
+	}
+	needValues := func() error {
+		if len(values) == 0 {
+			return fmt.Errorf("%s requires values", req.Op)
+		}
+		return nil
+	}
+
+	switch req.Op {
+	case "set":
+		// Editing a value keeps its expiry unless a new TTL is given
+		err = client.SetArgs(ctx, key, string(req.Value), redis.SetArgs{KeepTTL: true}).Err()
+	case "hset":
+		if len(req.Fields) == 0 {
+			return fmt.Errorf("hset requires fields")
+		}
+		pairs := make([]interface{}, 0, len(req.Fields)*2)
+		for _, f := range req.Fields {
+			pairs = append(pairs, string(f.Field), string(f.Value))
+		}
+		err = client.HSet(ctx, key, pairs...).Err()
+	case "hdel":
+		if err = needValues(); err == nil {
+			err = client.HDel(ctx, key, names...).Err()
+		}
+	case "rpush":
+		if err = needValues(); err == nil {
+			err = client.RPush(ctx, key, values...).Err()
+		}
+	case "lpush":
+		if err = needValues(); err == nil {
+			err = client.LPush(ctx, key, values...).Err()
+		}
+	case "lset":
+		err = client.LSet(ctx, key, req.Index, string(req.Value)).Err()
+	case "lrem":
+		// LREM removes by value, so mark the element at Index with a unique
+		// placeholder first to remove exactly that one
+		placeholder := "__opendbm_removed_" + uuid.New().String()
+		_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.LSet(ctx, key, req.Index, placeholder)
+			pipe.LRem(ctx, key, 1, placeholder)
+			return nil
+		})
+	case "sadd":
+		if err = needValues(); err == nil {
+			err = client.SAdd(ctx, key, values...).Err()
+		}
+	case "srem":
+		if err = needValues(); err == nil {
+			err = client.SRem(ctx, key, values...).Err()
+		}
+	case "zadd":
+		if len(req.Members) == 0 {
+			return fmt.Errorf("zadd requires members")
+		}
+		members := make([]redis.Z, len(req.Members))
+		for i, m := range req.Members {
+			members[i] = redis.Z{Member: string(m.Member), Score: m.Score}
+		}
+		err = client.ZAdd(ctx, key, members...).Err()
+	case "zrem":
+		if err = needValues(); err == nil {
+			err = client.ZRem(ctx, key, values...).Err()
+		}
+	case "xadd":
+		if len(req.Fields) == 0 {
+			return fmt.Errorf("xadd requires fields")
+		}
+		pairs := make([]interface{}, 0, len(req.Fields)*2)
+		for _, f := range req.Fields {
+			pairs = append(pairs, string(f.Field), string(f.Value))
+		}
+		streamID := req.StreamID
+		if streamID == "" {
+			streamID = "*"
+		}
+		err = client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: streamID, Values: pairs}).Err()
+	case "xdel":
+		if err = needValues(); err == nil {
+			err = client.XDel(ctx, key, names...).Err()
+		}
+	default:
+		return fmt.Errorf("unsupported operation: %s", req.Op)
+	}
+	if err != nil {
+		return err
+	}
+
+	if req.TTL != nil {
+		return setTTL(ctx, client, key, *req.TTL)
+	}
+	return nil
+}
+
+// DeleteKeys deletes keys and returns how many existed
+func (d *RedisDriverImpl) DeleteKeys(id string, req models.KeyDeleteRequest) (int64, error) {
+	client, err := d.client(id, req.Database)
+	if err != nil {
+		return 0, err
+	}
+	if len(req.Keys) == 0 {
+		return 0, fmt.Errorf("keys are required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	keys := make([]string, len(req.Keys))
+	for i, key := range req.Keys {
+		keys[i] = string(key)
+	}
+	return client.Del(ctx, keys...).Result()
+}
+
+// SetTTL sets or removes the expiry of a key
+func (d *RedisDriverImpl) SetTTL(id string, req models.KeyTTLRequest) error {
+	client, err := d.client(id, req.Database)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return setTTL(ctx, client, string(req.Key), req.TTL)
+}
+
+// ExecuteCommand runs a raw command line as typed into redis-cli
+func (d *RedisDriverImpl) ExecuteCommand(id string, req models.KeyCommandRequest) (*models.KeyCommandResult, error) {
+	client, err := d.client(id, req.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	args, err := splitCommandLine(req.Command)
+	if err == nil && len(args) == 0 {
+		err = fmt.Errorf("empty command")
+	}
+	if err == nil {
+		err = consoleAllowed(args[0])
+	}
+	if err != nil {
+		return &models.KeyCommandResult{
+			ExecutionTime: time.Since(start).Milliseconds(),
+			Error:         err.Error(),
+		}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	cmdArgs := make([]interface{}, len(args))
+	for i, arg := range args {
+		cmdArgs[i] = arg
+	}
+	reply, err := client.Do(ctx, cmdArgs...).Result()
+
+	result := &models.KeyCommandResult{ExecutionTime: time.Since(start).Milliseconds()}
+	switch {
+	case errors.Is(err, redis.Nil):
+	case err != nil:
+		result.Error = err.Error()
+	default:
+		result.Result = consoleReply(reply)
+	}
+	return result, nil
+}
+
+// client returns the client for a logical database, or for the connection's
+// own when db is nil, creating it on first use. Indexes outside the server's
+// databases setting are rejected so that no client is kept for them.
+func (d *RedisDriverImpl) client(id string, db *int) (*redis.Client, error) {
+	d.mu.RLock()
+	base, exists := d.options[id]
+	var client, home *redis.Client
+	index := 0
+	if exists {
+		index = base.DB
+		if db != nil {
+			index = *db
+		}
+		client = d.clients[id][index]
+		home = d.clients[id][base.DB]
+	}
+	d.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("connection not found")
+	}
+	if client != nil {
+		return client, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if count := databaseCount(ctx, home); index < 0 || index >= count {
+		return nil, fmt.Errorf("invalid database index %d: the server has databases 0 to %d", index, count-1)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	base, exists = d.options[id]
+	if !exists {
+		return nil, fmt.Errorf("connection not found")
+	}
+	if client = d.clients[id][index]; client == nil {
+		opts := *base
+		opts.DB = index
+		client = redis.NewClient(&opts)
+		d.clients[id][index] = client
+	}
+	return client, nil
+}
+
+// databaseCount returns the number of logical databases the server has.
+// CONFIG is often disabled on managed servers; 16 is the server default.
+func databaseCount(ctx context.Context, client *redis.Client) int {
+	if config, err := client.ConfigGet(ctx, "databases").Result(); err == nil {
+		if n, err := strconv.Atoi(config["databases"]); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 16
+}
+
+// describeKeys looks up the type and TTL of keys in one round trip
+func describeKeys(ctx context.Context, client *redis.Client, keys []string) ([]models.KeyInfo, error) {
+	infos := make([]models.KeyInfo, len(keys))
+	if len(keys) == 0 {
+		return infos, nil
+	}
+
+	types := make([]*redis.StatusCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			types[i] = pipe.Type(ctx, key)
+			ttls[i] = pipe.TTL(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		ttl := ttls[i].Val()
+		if ttl > 0 {
+			ttl /= time.Second
+		}
+		infos[i] = models.KeyInfo{Key: models.BinaryString(key), Type: types[i].Val(), TTL: int64(ttl)}
+	}
+	return infos, nil
+}
+
+func setTTL(ctx context.Context, client *redis.Client, key string, seconds int64) error {
+	var ok bool
+	var err error
+	if seconds > 0 {
+		ok, err = client.Expire(ctx, key, time.Duration(seconds)*time.Second).Result()
+	} else {
+		ok, err = client.Persist(ctx, key).Result()
+		if err == nil && !ok {
+			// PERSIST also answers 0 for keys without an expiry
+			var exists int64
+			exists, err = client.Exists(ctx, key).Result()
+			ok = exists > 0
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("key not found: %s", key)
+	}
+	return nil
+}
+
+func pageSize(count int64) int64 {
+	if count <= 0 {
+		return defaultKeyCount
+	}
+	if count > maxKeyCount {
+		return maxKeyCount
+	}
+	return count
+}
+
+func scanCursor(cursor string) (uint64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor: %s", cursor)
+	}
+	return n, nil
+}
+
+func offsetCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid cursor: %s", cursor)
+	}
+	return n, nil
+}
+
+// nextStreamID returns the smallest ID after id, so paging works on servers
+// that predate exclusive XRANGE bounds
+func nextStreamID(id string) string {
+	ms, seq, ok := strings.Cut(id, "-")
+	if !ok {
+		return id
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return id
+	}
+	return ms + "-" + strconv.FormatUint(n+1, 10)
+}
+
+// binaryStrings converts the strings of a reply for JSON
+func binaryStrings(items []string) []models.BinaryString {
+	values := make([]models.BinaryString, len(items))
+	for i, item := range items {
+		values[i] = models.BinaryString(item)
+	}
+	return values
+}
+
+// streamFields keeps stream entry fields in a stable order
+func streamFields(values map[string]interface{}) []models.HashField {
+	fields := make([]models.HashField, 0, len(values))
+	for field, value := range values {
+		fields = append(fields, models.HashField{Field: models.BinaryString(field), Value: models.BinaryString(fmt.Sprint(value))})
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Field < fields[j].Field
+	})
+	return fields
+}
+
+// consoleAllowed rejects commands that would hijack a pooled connection
+func consoleAllowed(name string) error {
+	switch strings.ToUpper(name) {
+	case "SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE", "MONITOR", "SYNC", "PSYNC":
+		return fmt.Errorf("%s is not supported in the console", strings.ToUpper(name))
+	case "SELECT":
+		return fmt.Errorf("SELECT is not supported in the console; choose the database instead")
+	case "QUIT", "RESET", "AUTH", "HELLO":
+		return fmt.Errorf("%s would change the shared connection and is not supported in the console", strings.ToUpper(name))
+	}
+	return nil
+}
+
+// consoleReply converts a raw reply into JSON friendly values
+func consoleReply(reply interface{}) interface{} {
+	switch v := reply.(type) {
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = consoleReply(item)
+		}
+		return items
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = consoleReply(value)
+		}
+		return m
+	case string:
+		return models.BinaryString(v)
+	case error:
+		return "(error) " + v.Error()
+	default:
+		return v
+	}
+}
+
+// splitCommandLine splits a command line the way redis-cli does: arguments
+// are separated by spaces and may be "double quoted" with escapes such as
+// \n or \x00, or 'single quoted' where only \' is special
+func splitCommandLine(line string) ([]string, error) {
+	var args []string
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i >= len(line) {
+			return args, nil
+		}
+
+		var arg strings.Builder
+		inDouble, inSingle := false, false
+		for done := false; !done; {
+			if i >= len(line) {
+				if inDouble || inSingle {
+					return nil, fmt.Errorf("unbalanced quotes in command")
+				}
+				break
+			}
+			c := line[i]
+			switch {
+			case inDouble:
+				switch {
+				case c == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHex(line[i+2]) && isHex(line[i+3]):
+					n, _ := strconv.ParseUint(line[i+2:i+4], 16, 8)
+					arg.WriteByte(byte(n))
+					i += 3
+				case c == '\\' && i+1 < len(line):
+					i++
+					switch e := line[i]; e {
+					case 'n':
+						arg.WriteByte('\n')
+					case 'r':
+						arg.WriteByte('\r')
+					case 't':
+						arg.WriteByte('\t')
+					case 'b':
+						arg.WriteByte('\b')
+					case 'a':
+						arg.WriteByte('\a')
+					default:
+						arg.WriteByte(e)
+					}
+				case c == '"':
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, fmt.Errorf("closing quote must be followed by a space")
+					}
+					inDouble = false
+					done = true
+				default:
+					arg.WriteByte(c)
+				}
+			case inSingle:
+				switch {
+				case c == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					i++
+					arg.WriteByte('\'')
+				case c == '\'':
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, fmt.Errorf("closing quote must be followed by a space")
+					}
+					inSingle = false
+					done = true
+				default:
+					arg.WriteByte(c)
+				}
+			case isSpace(c):
+				done = true
+			case c == '"':
+				inDouble = true
+			case c == '\'':
+				inSingle = true
+			default:
+				arg.WriteByte(c)
+			}
+			i++
+		}
+		args = append(args, arg.String())
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,363 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"opendbm/internal/models"
+)
+
+// newTestRedis connects a driver to an in-process server, using database 2
+// as the connection's own
+func newTestRedis(t *testing.T) (*RedisDriverImpl, string, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	port, err := strconv.Atoi(server.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewRedisDriver(nil, nil)
+	id, err := d.Connect(models.ConnectionConfig{
+		ID:       "redis-test",
+		Host:     server.Host(),
+		Port:     port,
+		Database: "2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Disconnect(id) })
+	return d, id, server
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestRedisDefaultDatabase(t *testing.T) {
+	d, id, server := newTestRedis(t)
+	server.DB(2).Set("greeting", "hello")
+
+	value, err := d.GetKey(id, models.KeyGetRequest{Key: "greeting"})
+	if err != nil {
+		t.Fatalf("GetKey() without a database error = %v, want the connection's database 2", err)
+	}
+	if value.Value != "hello" {
+		t.Errorf("GetKey() = %q, want hello", value.Value)
+	}
+
+	if _, err := d.GetKey(id, models.KeyGetRequest{Database: intPtr(0), Key: "greeting"}); err == nil {
+		t.Error("GetKey() in database 0 found a key of database 2")
+	}
+}
+
+func TestRedisDatabaseRange(t *testing.T) {
+	d, id, _ := newTestRedis(t)
+
+	tests := []struct {
+		database int
+		wantErr  bool
+	}{
+		{0, false},
+		{15, false},
+		{16, true},
+		{1 << 20, true},
+		{-1, true},
+	}
+	for _, tt := range tests {
+		_, err := d.ScanKeys(id, models.KeyScanRequest{Database: intPtr(tt.database)})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ScanKeys() in database %d error = %v, want error %v", tt.database, err, tt.wantErr)
+		}
+	}
+
+	d.mu.RLock()
+	clients := len(d.clients[id])
+	d.mu.RUnlock()
+	if clients != 3 {
+		t.Errorf("driver keeps %d clients, want 3 for databases 2, 0 and 15", clients)
+	}
+}
+
+func TestRedisScanKeys(t *testing.T) {
+	d, id, server := newTestRedis(t)
+	db := server.DB(2)
+	for i := 0; i < 250; i++ {
+		db.Set(fmt.Sprintf("string:%d", i), "v")
+	}
+	db.HSet("hash:1", "f", "v")
+	db.SetTTL("hash:1", time.Minute)
+
+	seen := map[string]models.KeyInfo{}
+	var cursor uint64
+	for page := 0; ; page++ {
+		if page > 100 {
+			t.Fatal("SCAN never completed")
+		}
+		result, err := d.ScanKeys(id, models.KeyScanRequest{Cursor: cursor, Count: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, info := range result.Keys {
+			seen[string(info.Key)] = info
+		}
+		if cursor = result.Cursor; cursor == 0 {
+			break
+		}
+	}
+	if len(seen) != 251 {
+		t.Errorf("scanned %d keys, want 251", len(seen))
+	}
+	if info := seen["hash:1"]; info.Type != "hash" || info.TTL != 60 {
+		t.Errorf("hash:1 = %+v, want a hash with a 60 second TTL", info)
+	}
+
+	result, err := d.ScanKeys(id, models.KeyScanRequest{Type: "hash", Count: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Keys) != 1 || result.Keys[0].Key != "hash:1" {
+		t.Errorf("ScanKeys() of type hash = %+v, want hash:1", result.Keys)
+	}
+}
+
+func TestRedisGetKeyTypes(t *testing.T) {
+	d, id, server := newTestRedis(t)
+	db := server.DB(2)
+	db.Set("s", "text")
+	db.HSet("h", "b", "2", "a", "1")
+	db.Push("l", "x", "y", "z")
+	db.SetAdd("set", "only")
+	db.ZAdd("z", 2, "two")
+	db.ZAdd("z", 1, "one")
+	db.XAdd("st", "1-1", []string{"f", "v"})
+
+	tests := []struct {
+		key    string
+		typ    string
+		length int64
+		check  func(*models.KeyValue) bool
+	}{
+		{"s", "string", 4, func(v *models.KeyValue) bool { return v.Value == "text" }},
+		{"h", "hash", 2, func(v *models.KeyValue) bool { return len(v.Fields) == 2 }},
+		{"l", "list", 3, func(v *models.KeyValue) bool {
+			return reflect.DeepEqual(v.Items, []models.BinaryString{"x", "y", "z"})
+		}},
+		{"set", "set", 1, func(v *models.KeyValue) bool {
+			return reflect.DeepEqual(v.Items, []models.BinaryString{"only"})
+		}},
+		{"z", "zset", 2, func(v *models.KeyValue) bool {
+			return reflect.DeepEqual(v.Members, []models.ZMember{{Member: "one", Score: 1}, {Member: "two", Score: 2}})
+		}},
+		{"st", "stream", 1, func(v *models.KeyValue) bool {
+			return len(v.Entries) == 1 && v.Entries[0].ID == "1-1" && v.Entries[0].Fields[0] == models.HashField{Field: "f", Value: "v"}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			value, err := d.GetKey(id, models.KeyGetRequest{Key: models.BinaryString(tt.key)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value.Type != tt.typ || value.Length != tt.length || value.TTL != -1 || !tt.check(value) {
+				t.Errorf("GetKey(%s) = %+v", tt.key, value)
+			}
+		})
+	}
+
+	if _, err := d.GetKey(id, models.KeyGetRequest{Key: "missing"}); err == nil {
+		t.Error("GetKey() of a missing key succeeded")
+	}
+}
+
+func TestRedisBinaryValues(t *testing.T) {
+	d, id, server := newTestRedis(t)
+	binary := "\xff\x00\xfe"
+	server.DB(2).Set("bin", binary)
+
+	value, err := d.GetKey(id, models.KeyGetRequest{Key: "bin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"value":{"$base64":"/wD+"}`) {
+		t.Errorf("binary value encoded as %s, want a $base64 marker", data)
+	}
+
+	// Values sent back in the same form are written byte for byte
+	var req models.KeyWriteRequest
+	if err := json.Unmarshal([]byte(`{"key":{"$base64":"/wD+"},"op":"set","value":{"$base64":"AAE="}}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteKey(id, req); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := server.DB(2).Get(binary); got != "\x00\x01" {
+		t.Errorf("written value = %q, want the decoded bytes", got)
+	}
+}
+
+func TestRedisTTL(t *testing.T) {
+	d, id, server := newTestRedis(t)
+	server.DB(2).Set("k", "v")
+
+	if err := d.SetTTL(id, models.KeyTTLRequest{Key: "k", TTL: 60}); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := server.DB(2).TTL("k"); ttl != time.Minute {
+		t.Errorf("TTL after SetTTL(60) = %v, want 1m", ttl)
+	}
+
+	// Editing the value keeps the expiry
+	if err := d.WriteKey(id, models.KeyWriteRequest{Key: "k", Op: "set", Value: "changed"}); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := server.DB(2).TTL("k"); ttl != time.Minute {
+		t.Errorf("TTL after set = %v, want it kept", ttl)
+	}
+
+	if err := d.SetTTL(id, models.KeyTTLRequest{Key: "k", TTL: 0}); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := server.DB(2).TTL("k"); ttl != 0 {
+		t.Errorf("TTL after SetTTL(0) = %v, want none", ttl)
+	}
+	// Persisting a key without an expiry is not an error
+	if err := d.SetTTL(id, models.KeyTTLRequest{Key: "k", TTL: 0}); err != nil {
+		t.Errorf("SetTTL(0) of a persistent key error = %v", err)
+	}
+	if err := d.SetTTL(id, models.KeyTTLRequest{Key: "missing", TTL: 60}); err == nil {
+		t.Error("SetTTL() of a missing key succeeded")
+	}
+}
+
+func TestRedisListRemoveAt(t *testing.T) {
+	d, id, server := newTestRedis(t)
+	server.DB(2).Push("l", "a", "b", "a", "c")
+
+	if err := d.WriteKey(id, models.KeyWriteRequest{Key: "l", Op: "lrem", Index: 2}); err != nil {
+		t.Fatal(err)
+	}
+	list, err := server.DB(2).List("l")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(list, want) {
+		t.Errorf("list after lrem of index 2 = %v, want %v", list, want)
+	}
+}
+
+func TestRedisStreamPaging(t *testing.T) {
+	d, id, _ := newTestRedis(t)
+
+	for _, streamID := range []string{"1-1", "1-2", "2-0", "5-7", ""} {
+		err := d.WriteKey(id, models.KeyWriteRequest{
+			Key:      "events",
+			Op:       "xadd",
+			StreamID: streamID,
+			Fields:   []models.HashField{{Field: "n", Value: models.BinaryString(streamID)}},
+		})
+		if err != nil {
+			t.Fatalf("xadd %q: %v", streamID, err)
+		}
+	}
+	if err := d.WriteKey(id, models.KeyWriteRequest{Key: "events", Op: "xadd"}); err == nil {
+		t.Error("xadd without fields succeeded")
+	}
+
+	var ids []string
+	cursor := ""
+	for page := 0; ; page++ {
+		if page > 10 {
+			t.Fatal("stream paging never completed")
+		}
+		value, err := d.GetKey(id, models.KeyGetRequest{Key: "events", Cursor: cursor, Count: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value.Length != 5 {
+			t.Errorf("stream length = %d, want 5", value.Length)
+		}
+		for _, entry := range value.Entries {
+			ids = append(ids, entry.ID)
+		}
+		if cursor = value.Cursor; cursor == "" {
+			break
+		}
+	}
+	if len(ids) != 5 || !reflect.DeepEqual(ids[:4], []string{"1-1", "1-2", "2-0", "5-7"}) {
+		t.Errorf("paged stream IDs = %v, want each entry once in order", ids)
+	}
+}
+
+func TestRedisConsole(t *testing.T) {
+	d, id, _ := newTestRedis(t)
+
+	run := func(command string) *models.KeyCommandResult {
+		t.Helper()
+		result, err := d.ExecuteCommand(id, models.KeyCommandRequest{Command: command})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result := run(`SET "two words" "\xff"`); result.Error != "" {
+		t.Fatalf("SET error = %s", result.Error)
+	}
+	data, _ := json.Marshal(run(`GET 'two words'`).Result)
+	if string(data) != `{"$base64":"/w=="}` {
+		t.Errorf("GET reply = %s, want the binary value as $base64", data)
+	}
+	if result := run("SELECT 1"); result.Error == "" {
+		t.Error("SELECT was allowed in the console")
+	}
+	if result := run(`GET "unterminated`); result.Error == "" {
+		t.Error("an unbalanced quote was accepted")
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"  PING  ", []string{"PING"}, false},
+		{"SET key value", []string{"SET", "key", "value"}, false},
+		{"SET\tkey\r\nvalue", []string{"SET", "key", "value"}, false},
+		{`SET "two words" 'single quoted'`, []string{"SET", "two words", "single quoted"}, false},
+		{`SET k "a\nb\t\"c\""`, []string{"SET", "k", "a\nb\t\"c\""}, false},
+		{`SET k "\x00\xffz"`, []string{"SET", "k", "\x00\xffz"}, false},
+		{`SET k "\xZZ"`, []string{"SET", "k", "xZZ"}, false},
+		{`SET k 'it\'s \n'`, []string{"SET", "k", `it's \n`}, false},
+		{`SET k ""`, []string{"SET", "k", ""}, false},
+		{`SET k mid"quoted part"`, []string{"SET", "k", "midquoted part"}, false},
+		{`SET k mid"quote`, nil, true},
+		{`SET k "open`, nil, true},
+		{`SET k 'open`, nil, true},
+		{`SET k "closed"x`, nil, true},
+		{`SET k 'closed'x`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := splitCommandLine(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitCommandLine(%q) error = %v, want error %v", tt.line, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommandLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Capabilities a driver can offer, checked with type assertions. The ones
+// after CapabilityKeyValue are the optional parts of a SQL driver.
+const (
+	CapabilitySQL         = "sql"
+	CapabilityDocument    = "document"
+	CapabilityKeyValue    = "keyvalue"
+	CapabilityParams      = "params"
+	CapabilityStreaming   = "streaming"
+	CapabilityScripts     = "scripts"
+	CapabilityExplain     = "explain"
+	CapabilitySessions    = "sessions"
+	CapabilitySchemas     = "schemas"
+	CapabilityTableStats  = "table_stats"
+	CapabilityTableDetail = "table_detail"
+	CapabilityDefinitions = "definitions"
+	CapabilityDDL         = "ddl"
+	CapabilityTableData   = "table_data"
+)
+
+// DriverEnv gives drivers access to services shared by all connections
+type DriverEnv struct {
+	Secrets SecretResolver
+	Tunnels *TunnelManager
+}
+
+// Factory creates a driver for one database type. The manager calls it once
+// per type and routes every connection of that type to the returned driver.
+type Factory func(env DriverEnv) Driver
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a driver available for a ConnectionConfig.Type. Third-party
+// drivers call it from an init function, e.g. database.Register("clickhouse", ...).
+// It panics if a type is registered twice.
+func Register(dbType string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("database: Register factory is nil for " + dbType)
+	}
+	if _, exists := registry[dbType]; exists {
+		panic("database: Register called twice for " + dbType)
+	}
+	registry[dbType] = factory
+}
+
+// RegisteredTypes returns the database types that have a driver
+func RegisteredTypes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]string, 0, len(registry))
+	for t := range registry {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func lookupFactory(dbType string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	factory, exists := registry[dbType]
+	return factory, exists
+}
+
+// capabilityChecks pairs each capability with the interface that provides it
+var capabilityChecks = []struct {
+	name string
+	has  func(Driver) bool
+}{
+	{CapabilitySQL, implements[SQLDriver]},
+	{CapabilityDocument, implements[DocumentDriver]},
+	{CapabilityKeyValue, implements[KeyValueDriver]},
+	{CapabilityParams, implements[ParamBinder]},
+	{CapabilityStreaming, implements[QueryStreamer]},
+	{CapabilityScripts, implements[ScriptRunner]},
+	{CapabilityExplain, implements[Explainer]},
+	{CapabilitySessions, implements[SessionDriver]},
+	{CapabilitySchemas, implements[SchemaLister]},
+	{CapabilityTableStats, implements[TableStatsLister]},
+	{CapabilityTableDetail, implements[TableDescriber]},
+	{CapabilityDefinitions, implements[DefinitionReader]},
+	{CapabilityDDL, implements[DDLGenerator]},
+	{CapabilityTableData, implements[TableDataReader]},
+}
+
+func implements[T any](driver Driver) bool {
+	_, ok := driver.(T)
+	return ok
+}
+
+// Capabilities lists the optional interfaces a driver implements
+func Capabilities(driver Driver) []string {
+	var caps []string
+	for _, check := range capabilityChecks {
+		if check.has(driver) {
+			caps = append(caps, check.name)
+		}
+	}
+	return caps
+}
+
+// CapabilityError is returned when a connection's driver lacks a capability
+type CapabilityError struct {
+	Type         string   `json:"type"`
+	Capability   string   `json:"capability"`
+	Capabilities []string `json:"capabilities"`
+}
+
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("%s connections do not support %s operations (supported: %s)",
+		e.Type, e.Capability, strings.Join(e.Capabilities, ", "))
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver Driver
+		want   []string
+	}{
+		{"sql", NewSQLDriver(nil, nil), []string{
+			CapabilitySQL, CapabilityParams, CapabilityStreaming, CapabilityScripts, CapabilityExplain,
+			CapabilitySessions, CapabilitySchemas, CapabilityTableStats, CapabilityTableDetail,
+			CapabilityDefinitions, CapabilityDDL, CapabilityTableData,
+		}},
+		{"plain", testBlockingDriver, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Capabilities(tt.driver); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Capabilities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"opendbm/internal/models"
+)
+
+// JSON type hints of result columns; see models.ResultColumn
+const (
+	jsonInteger  = "integer"
+	jsonNumber   = "number"
+	jsonDecimal  = "decimal"
+	jsonBoolean  = "boolean"
+	jsonString   = "string"
+	jsonDatetime = "datetime"
+	jsonJSON     = "json"
+	jsonBinary   = "binary"
+	jsonUnknown  = "unknown"
+)
+
+// typeHints maps database type names, as drivers report them, to JSON type
+// hints. Names missing here fall back to the driver's scan type.
+var typeHints = map[string]string{
+	"TINYINT": jsonInteger, "SMALLINT": jsonInteger, "MEDIUMINT": jsonInteger,
+	"INT": jsonInteger, "INTEGER": jsonInteger, "BIGINT": jsonInteger,
+	"INT2": jsonInteger, "INT4": jsonInteger, "INT8": jsonInteger,
+	"UNSIGNED TINYINT": jsonInteger, "UNSIGNED SMALLINT": jsonInteger, "UNSIGNED MEDIUMINT": jsonInteger,
+	"UNSIGNED INT": jsonInteger, "UNSIGNED BIGINT": jsonInteger, "YEAR": jsonInteger,
+	"OID": jsonInteger,
+
+	"FLOAT": jsonNumber, "FLOAT4": jsonNumber, "FLOAT8": jsonNumber, "REAL": jsonNumber,
+	"DOUBLE": jsonNumber, "DOUBLE PRECISION": jsonNumber,
+	"BINARY_FLOAT": jsonNumber, "BINARY_DOUBLE": jsonNumber, "IBFLOAT": jsonNumber, "IBDOUBLE": jsonNumber,
+
+	"DECIMAL": jsonDecimal, "NUMERIC": jsonDecimal, "NUMBER": jsonDecimal,
+	"MONEY": jsonDecimal, "SMALLMONEY": jsonDecimal,
+
+	"BOOL": jsonBoolean, "BOOLEAN": jsonBoolean,
+
+	"DATE": jsonDatetime, "TIME": jsonDatetime, "TIMETZ": jsonDatetime,
+	"DATETIME": jsonDatetime, "DATETIME2": jsonDatetime, "SMALLDATETIME": jsonDatetime,
+	"DATETIMEOFFSET": jsonDatetime, "TIMESTAMP": jsonDatetime, "TIMESTAMPTZ": jsonDatetime,
+	"TIMESTAMPDTY": jsonDatetime, "TIMESTAMPTZ_DTY": jsonDatetime, "TIMESTAMPLTZ_DTY": jsonDatetime,
+
+	"JSON": jsonJSON, "JSONB": jsonJSON,
+
+	"BYTEA": jsonBinary, "BLOB": jsonBinary, "TINYBLOB": jsonBinary, "MEDIUMBLOB": jsonBinary,
+	"LONGBLOB": jsonBinary, "BINARY": jsonBinary, "VARBINARY": jsonBinary, "IMAGE": jsonBinary,
+	"RAW": jsonBinary, "LONG RAW": jsonBinary, "LONGRAW": jsonBinary, "BFILE": jsonBinary,
+	"GEOMETRY": jsonBinary,
+
+	"UNIQUEIDENTIFIER": jsonString, "UUID": jsonString,
+}
+
+// resultColumns describes the columns of a result set
+func resultColumns(types []*sql.ColumnType) []models.ResultColumn {
+	columns := make([]models.ResultColumn, len(types))
+	for i, ct := range types {
+		col := models.ResultColumn{
+			Name: ct.Name(),
+			Type: ct.DatabaseTypeName(),
+		}
+		if nullable, ok := ct.Nullable(); ok {
+			col.Nullable = &nullable
+		}
+		if precision, scale, ok := ct.DecimalSize(); ok {
+			col.Precision = &precision
+			col.Scale = &scale
+		}
+		// Unbounded types such as TEXT report the largest length
+		if length, ok := ct.Length(); ok && length != math.MaxInt64 && length != math.MaxInt32 {
+			col.Length = &length
+		}
+		col.JSONType = jsonType(col.Type, ct.ScanType())
+		columns[i] = col
+	}
+	return columns
+}
+
+// jsonType picks the JSON type hint of a column
+func jsonType(dbType string, scanType reflect.Type) string {
+	name := strings.ToUpper(strings.TrimSpace(dbType))
+	// SQLite reports declared types verbatim, e.g. VARCHAR(20)
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = strings.TrimSpace(name[:i])
+	}
+
+	if hint, ok := typeHints[name]; ok {
+		return hint
+	}
+	switch {
+	case name == "BIT":
+		// SQL Server BIT is a boolean, MySQL BIT(n) a bit field
+		if scanType != nil && scanType.Kind() == reflect.Bool {
+			return jsonBoolean
+		}
+		return jsonBinary
+	case strings.HasPrefix(name, "TIMESTAMP"), strings.HasPrefix(name, "INTERVAL"):
+		// Oracle spells out TIMESTAMP WITH TIME ZONE and INTERVAL ... TO ...
+		return jsonDatetime
+	case strings.HasPrefix(name, "_"):
+		// PostgreSQL arrays arrive as their text form
+		return jsonString
+	}
+
+	if scanType == nil {
+		return jsonUnknown
+	}
+	if scanType == reflect.TypeOf(time.Time{}) || scanType == reflect.TypeOf(sql.NullTime{}) {
+		return jsonDatetime
+	}
+	switch scanType.Kind() {
+	case reflect.Bool:
+		return jsonBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonInteger
+	case reflect.Float32, reflect.Float64:
+		return jsonNumber
+	case reflect.String:
+		return jsonString
+	case reflect.Struct:
+		switch scanType {
+		case reflect.TypeOf(sql.NullBool{}):
+			return jsonBoolean
+		case reflect.TypeOf(sql.NullInt16{}), reflect.TypeOf(sql.NullInt32{}), reflect.TypeOf(sql.NullInt64{}):
+			return jsonInteger
+		case reflect.TypeOf(sql.NullFloat64{}):
+			return jsonNumber
+		case reflect.TypeOf(sql.NullString{}):
+			return jsonString
+		}
+	}
+	if name != "" {
+		return jsonString
+	}
+	return jsonUnknown
+}
+
+// cellValue converts a scanned value to its JSON form for col. Drivers using
+// a text protocol return most values as bytes, so numbers are parsed back
+// and only binary columns, or bytes that are not valid UTF-8, are base64
+// encoded.
+func cellValue(v interface{}, col *models.ResultColumn) interface{} {
+	switch val := v.(type) {
+	case []byte:
+		switch col.JSONType {
+		case jsonBinary:
+			return binaryValue(val)
+		case jsonInteger:
+			if n, err := strconv.ParseInt(string(val), 10, 64); err == nil {
+				return n
+			}
+			if n, err := strconv.ParseUint(string(val), 10, 64); err == nil {
+				return n
+			}
+		case jsonNumber:
+			if f, err := strconv.ParseFloat(string(val), 64); err == nil {
+				return floatValue(f)
+			}
+		}
+		if strings.EqualFold(col.Type, "UNIQUEIDENTIFIER") && len(val) == 16 {
+			return mssqlUUID(val)
+		}
+		if !utf8.Valid(val) {
+			return binaryValue(val)
+		}
+		return string(val)
+	case float64:
+		if col.JSONType == jsonDecimal {
+			return strconv.FormatFloat(val, 'f', -1, 64)
+		}
+		return floatValue(val)
+	case float32:
+		return floatValue(float64(val))
+	case int64:
+		if col.JSONType == jsonDecimal {
+			return strconv.FormatInt(val, 10)
+		}
+	}
+	return v
+}
+
+func binaryValue(b []byte) models.BinaryValue {
+	return models.BinaryValue{Base64: base64.StdEncoding.EncodeToString(b)}
+}
+
+// floatValue keeps NaN and infinities, which JSON numbers cannot hold, as
+// strings
+func floatValue(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return f
+}
+
+// mssqlUUID formats a SQL Server uniqueidentifier, whose first three groups
+// are stored little endian
+func mssqlUUID(b []byte) string {
+	u, _ := uuid.FromBytes([]byte{
+		b[3], b[2], b[1], b[0],
+		b[5], b[4],
+		b[7], b[6],
+		b[8], b[9], b[10], b[11], b[12], b[13], b[14], b[15],
+	})
+	return u.String()
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"opendbm/internal/models"
+)
+
+func TestJSONType(t *testing.T) {
+	tests := []struct {
+		dbType   string
+		scanType reflect.Type
+		want     string
+	}{
+		{"INT4", nil, jsonInteger},
+		{"unsigned bigint", nil, jsonInteger},
+		{"VARCHAR(20)", reflect.TypeOf(""), jsonString},
+		{"NUMERIC", reflect.TypeOf(float64(0)), jsonDecimal},
+		{"DOUBLE PRECISION", nil, jsonNumber},
+		{"TIMESTAMP WITH TIME ZONE", nil, jsonDatetime},
+		{"INTERVAL DAY TO SECOND", nil, jsonDatetime},
+		{"JSONB", nil, jsonJSON},
+		{"BYTEA", nil, jsonBinary},
+		{"UNIQUEIDENTIFIER", nil, jsonString},
+		{"_INT4", nil, jsonString},
+		{"BIT", reflect.TypeOf(true), jsonBoolean},
+		{"BIT", reflect.TypeOf([]byte{}), jsonBinary},
+		{"", reflect.TypeOf(sql.NullInt64{}), jsonInteger},
+		{"", reflect.TypeOf(sql.NullTime{}), jsonDatetime},
+		{"", reflect.TypeOf(uint16(0)), jsonInteger},
+		{"CITEXT", reflect.TypeOf([]byte{}), jsonString},
+		{"", nil, jsonUnknown},
+	}
+	for _, tt := range tests {
+		if got := jsonType(tt.dbType, tt.scanType); got != tt.want {
+			t.Errorf("jsonType(%q, %v) = %q, want %q", tt.dbType, tt.scanType, got, tt.want)
+		}
+	}
+}
+
+func TestCellValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		col   models.ResultColumn
+		want  interface{}
+	}{
+		{"integer text", []byte("-42"), models.ResultColumn{JSONType: jsonInteger}, int64(-42)},
+		{"unsigned text", []byte("18446744073709551615"), models.ResultColumn{JSONType: jsonInteger}, uint64(math.MaxUint64)},
+		{"number text", []byte("1.5"), models.ResultColumn{JSONType: jsonNumber}, 1.5},
+		{"decimal text", []byte("12345678901234567890.01"), models.ResultColumn{JSONType: jsonDecimal}, "12345678901234567890.01"},
+		{"decimal float", 0.1, models.ResultColumn{JSONType: jsonDecimal}, "0.1"},
+		{"decimal integer", int64(7), models.ResultColumn{JSONType: jsonDecimal}, "7"},
+		{"binary", []byte{0, 1, 2}, models.ResultColumn{JSONType: jsonBinary}, models.BinaryValue{Base64: "AAEC"}},
+		{"invalid UTF-8", []byte{0xff, 'a'}, models.ResultColumn{JSONType: jsonString}, models.BinaryValue{Base64: "/2E="}},
+		{"text", []byte("héllo"), models.ResultColumn{JSONType: jsonString}, "héllo"},
+		{"NaN", math.NaN(), models.ResultColumn{JSONType: jsonNumber}, "NaN"},
+		{"infinity", float32(math.Inf(-1)), models.ResultColumn{JSONType: jsonNumber}, "-Inf"},
+		{
+			"uniqueidentifier", []byte{0xff, 0x19, 0x96, 0x6f, 0x86, 0x8b, 0x11, 0xd0, 0xb4, 0x2d, 0x00, 0xc0, 0x4f, 0xc9, 0x64, 0xff},
+			models.ResultColumn{Type: "UNIQUEIDENTIFIER", JSONType: jsonString}, "6f9619ff-8b86-d011-b42d-00c04fc964ff",
+		},
+		{"nil", nil, models.ResultColumn{JSONType: jsonString}, nil},
+	}
+	for _, tt := range tests {
+		col := tt.col
+		if got := cellValue(tt.value, &col); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: cellValue(%#v) = %#v, want %#v", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResultColumns(t *testing.T) {
+	d, id := newTestSQLite(t)
+	ctx := context.Background()
+	for _, stmt := range []string{
+		"CREATE TABLE t (n INTEGER NOT NULL, price DECIMAL(10,2), label VARCHAR(20), data BLOB, at DATETIME, ok BOOLEAN)",
+		"INSERT INTO t VALUES (1, 9.5, 'x', x'0001', '2024-01-02 03:04:05', 1)",
+	} {
+		if result, err := d.ExecuteSQL(ctx, id, "", stmt); err != nil || result.Error != "" {
+			t.Fatal(err, result.Error)
+		}
+	}
+
+	result, err := d.ExecuteQuery(ctx, id, "", "SELECT ok, n, price, label, data, at, n AS n FROM t")
+	if err != nil || result.Error != "" {
+		t.Fatal(err, result.Error)
+	}
+	wantColumns := []struct{ name, jsonType string }{
+		{"ok", jsonBoolean}, {"n", jsonInteger}, {"price", jsonDecimal}, {"label", jsonString},
+		{"data", jsonBinary}, {"at", jsonDatetime}, {"n", jsonInteger},
+	}
+	if len(result.Columns) != len(wantColumns) {
+		t.Fatalf("columns = %+v", result.Columns)
+	}
+	for i, want := range wantColumns {
+		if col := result.Columns[i]; col.Name != want.name || col.JSONType != want.jsonType {
+			t.Errorf("column %d = %s %s, want %s %s", i, col.Name, col.JSONType, want.name, want.jsonType)
+		}
+	}
+
+	row := result.Rows[0]
+	at, _ := row[5].(time.Time)
+	if row[1] != int64(1) || row[2] != "9.5" || row[3] != "x" || row[4] != (models.BinaryValue{Base64: "AAE="}) ||
+		!at.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) || row[6] != int64(1) {
+		t.Errorf("row = %#v", row)
+	}
+
+	// Rows keyed by name still list every column name in order
+	mapped := result.MapResult()
+	if len(mapped.Columns) != 7 || mapped.Rows[0]["label"] != "x" {
+		t.Errorf("map result = %+v", mapped)
+	}
+}
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"opendbm/internal/models"
+)
+
+var (
+	// rowStatement matches the leading keyword of statements that return rows
+	rowStatement = regexp.MustCompile(`(?i)^(?:SELECT|WITH|SHOW|DESCRIBE|DESC|EXPLAIN|PRAGMA|VALUES|TABLE|CALL|EXEC|EXECUTE|HELP|CHECK|CHECKSUM|ANALYZE|OPTIMIZE|REPAIR)\b`)
+	// returningClause matches DML that also returns rows
+	returningClause = regexp.MustCompile(`(?i)\b(?:RETURNING|OUTPUT)\b`)
+	// batchQuery matches a SQL Server batch that may produce a result set
+	// anywhere, since batches are not split into statements
+	batchQuery = regexp.MustCompile(`(?i)\b(?:SELECT|EXEC|EXECUTE)\b`)
+	// dmlStatement matches the leading keyword of statements that change data
+	dmlStatement = regexp.MustCompile(`(?i)^(?:INSERT|UPDATE|DELETE|MERGE|REPLACE|UPSERT|COPY|LOAD)\b`)
+	// ddlStatement matches the leading keyword of statements that change the
+	// schema or privileges
+	ddlStatement = regexp.MustCompile(`(?i)^(?:CREATE|ALTER|DROP|TRUNCATE|RENAME|COMMENT|GRANT|REVOKE)\b`)
+	// maintenanceStatement matches statements that maintain tables, which
+	// MySQL answers with rows of status but which are not queries
+	maintenanceStatement = regexp.MustCompile(`(?i)^(?:ANALYZE|OPTIMIZE|REPAIR|CHECK)\b`)
+	// insertStatement matches statements that may generate a row ID
+	insertStatement = regexp.MustCompile(`(?i)^(?:INSERT|REPLACE)\b`)
+)
+
+// ExecuteScript splits a script into statements and runs them in order on
+// one connection, or on the session when sessionID is set, so session state
+// such as USE, SET and temporary tables carries over. Unless continueOnError
+// is set, the first failing statement ends the script. Each statement keeps
+// at most MaxQueryRows rows per result set. Named params are bound to the
+// statements that use them.
+func (d *SQLDriverImpl) ExecuteScript(ctx context.Context, id string, sessionID string, script string, continueOnError bool, params []models.QueryParam) (*models.ScriptResult, error) {
+	_, dbType, err := d.pool(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range params {
+		if p.Name == "" {
+			return nil, fmt.Errorf("scripts take named parameters only")
+		}
+	}
+	statements, err := SplitStatements(script, dbType)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result := &models.ScriptResult{Statements: []models.StatementResult{}}
+	ctx, _ = withMessages(ctx)
+
+	q, release, err := d.acquire(ctx, id, sessionID)
+	if err != nil {
+		if isLookupError(err) {
+			return nil, err
+		}
+		result.Error = queryError(ctx, err)
+		result.ExecutionTime = time.Since(start).Milliseconds()
+		return result, nil
+	}
+	defer release()
+
+	for _, stmt := range statements {
+		results := runStatement(ctx, q, stmt, dbType, params)
+		result.Statements = append(result.Statements, results...)
+
+		failed := results[len(results)-1].Error != ""
+		if failed {
+			result.Failed++
+		}
+		if failed && !continueOnError || ctx.Err() != nil {
+			break
+		}
+	}
+
+	result.ExecutionTime = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// runStatement runs one statement of a script, giving a result per result
+// set it produces. The server's messages go with the last one.
+func runStatement(ctx context.Context, q queryer, stmt Statement, dbType string, params []models.QueryParam) []models.StatementResult {
+	start := time.Now()
+	newResult := func() models.StatementResult {
+		return models.StatementResult{
+			Statement: stmt.SQL,
+			Line:      stmt.Line,
+			ResultSet: models.ResultSet{
+				Columns:       []models.ResultColumn{},
+				Rows:          [][]interface{}{},
+				StatementType: statementType(stmt.SQL, dbType),
+			},
+		}
+	}
+	failed := func(r models.StatementResult, err error) models.StatementResult {
+		r.Columns = []models.ResultColumn{}
+		r.Rows = [][]interface{}{}
+		r.RowCount = 0
+		r.Error = queryError(ctx, err)
+		r.ExecutionTime = time.Since(start).Milliseconds()
+		return r
+	}
+	finish := func(results []models.StatementResult, err error) []models.StatementResult {
+		last := &results[len(results)-1]
+		last.Messages = append(last.Messages, statementMessages(ctx, q, dbType, err)...)
+		return results
+	}
+
+	query, args, _, err := bindParams(stmt.SQL, dbType, params)
+	if err != nil {
+		return []models.StatementResult{failed(newResult(), err)}
+	}
+
+	if !returnsRows(stmt.SQL, dbType) {
+		r := newResult()
+		if err := execStatement(ctx, q, dbType, query, args, &r.ResultSet); err != nil {
+			return finish([]models.StatementResult{failed(r, err)}, err)
+		}
+		r.ExecutionTime = time.Since(start).Milliseconds()
+		return finish([]models.StatementResult{r}, nil)
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return finish([]models.StatementResult{failed(newResult(), err)}, err)
+	}
+	// A truncated result set is drained when the rows are closed; canceling
+	// instead would end the connection the rest of the script runs on
+	defer rows.Close()
+
+	var results []models.StatementResult
+	for {
+		r := newResult()
+		count, truncated, err := scanRows(rows, MaxQueryRows, resultBuffer{&r.ResultSet})
+		if err != nil {
+			rows.Close()
+			return finish(append(results, failed(r, err)), err)
+		}
+		r.RowCount = count
+		r.Truncated = truncated
+		r.ExecutionTime = time.Since(start).Milliseconds()
+		results = append(results, r)
+
+		if !rows.NextResultSet() {
+			break
+		}
+		start = time.Now()
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return finish(append(results, failed(newResult(), err)), err)
+	}
+	rows.Close()
+	return finish(results, nil)
+}
+
+// statementType classifies a statement by its leading keyword: dml, ddl,
+// query for other statements returning rows, or other
+func statementType(stmt string, dbType string) string {
+	text := strings.TrimLeft(stripLeadingComments(stmt), "( \t\r\n")
+	switch {
+	case dmlStatement.MatchString(text):
+		return "dml"
+	case ddlStatement.MatchString(text):
+		return "ddl"
+	case maintenanceStatement.MatchString(text):
+		return "other"
+	case returnsRows(stmt, dbType):
+		return "query"
+	}
+	return "other"
+}
+
+// returnsRows guesses from its text whether a statement produces rows.
+// Statements that do are queried; the rest are executed so that the number
+// of affected rows is known.
+func returnsRows(stmt string, dbType string) bool {
+	text := strings.TrimLeft(stripLeadingComments(stmt), "( \t\r\n")
+	if rowStatement.MatchString(text) || returningClause.MatchString(text) {
+		return true
+	}
+	return dbType == "sqlserver" && batchQuery.MatchString(text)
+}
+
+// stripLeadingComments removes comments and whitespace before the first
+// keyword of a statement
+func stripLeadingComments(stmt string) string {
+	for {
+		stmt = strings.TrimSpace(stmt)
+		switch {
+		case strings.HasPrefix(stmt, "--"), strings.HasPrefix(stmt, "#"):
+			end := strings.IndexByte(stmt, '\n')
+			if end < 0 {
+				return ""
+			}
+			stmt = stmt[end+1:]
+		case strings.HasPrefix(stmt, "/*"):
+			end := strings.Index(stmt, "*/")
+			if end < 0 {
+				return ""
+			}
+			stmt = stmt[end+2:]
+		default:
+			return stmt
+		}
+	}
+}
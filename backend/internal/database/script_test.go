@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"opendbm/internal/models"
+)
+
+// newTestSQLite connects a SQL driver to a fresh SQLite database file
+func newTestSQLite(t *testing.T) (*SQLDriverImpl, string) {
+	t.Helper()
+	d := NewSQLDriver(nil, nil)
+	id, err := d.Connect(models.ConnectionConfig{
+		ID:       "sqlite-test",
+		Type:     "sqlite",
+		Database: filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Disconnect(id) })
+	return d, id
+}
+
+func TestExecuteScript(t *testing.T) {
+	script := "CREATE TABLE a (x INTEGER);\nINSERT INTO a VALUES (1), (2);\nbogus;\nSELECT count(*) FROM a;"
+	tests := []struct {
+		name            string
+		continueOnError bool
+		statements      int
+	}{
+		{"stop", false, 3},
+		{"continue", true, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, id := newTestSQLite(t)
+			result, err := d.ExecuteScript(context.Background(), id, "", script, tt.continueOnError, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Statements) != tt.statements || result.Failed != 1 {
+				t.Fatalf("ran %d statements with %d failed, want %d with 1 failed", len(result.Statements), result.Failed, tt.statements)
+			}
+			if bad := result.Statements[2]; bad.Error == "" || bad.Line != 3 {
+				t.Errorf("statement 3 = %+v, want an error on line 3", bad)
+			}
+			if tt.continueOnError {
+				last := result.Statements[3]
+				if last.Error != "" || len(last.Rows) != 1 || last.Rows[0][0] != int64(2) {
+					t.Errorf("SELECT after the failure = %+v, want a count of 2", last)
+				}
+			}
+		})
+	}
+}
+
+func TestStatementType(t *testing.T) {
+	tests := []struct {
+		sql    string
+		dbType string
+		want   string
+	}{
+		{"SELECT 1", "sqlite", "query"},
+		{"-- note\nINSERT INTO a VALUES (1)", "sqlite", "dml"},
+		{"CREATE TABLE a (x INTEGER)", "sqlite", "ddl"},
+		{"ANALYZE", "sqlite", "other"},
+		{"ANALYZE a", "postgres", "other"},
+		{"ANALYZE TABLE a", "mysql", "other"},
+		{"OPTIMIZE TABLE a", "mysql", "other"},
+		{"EXPLAIN ANALYZE SELECT 1", "postgres", "query"},
+		{"VACUUM", "sqlite", "other"},
+	}
+	for _, tt := range tests {
+		if got := statementType(tt.sql, tt.dbType); got != tt.want {
+			t.Errorf("statementType(%q, %s) = %q, want %q", tt.sql, tt.dbType, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteSQLRowsAffected(t *testing.T) {
+	d, id := newTestSQLite(t)
+	ctx := context.Background()
+	tests := []struct {
+		sql  string
+		want *int64
+	}{
+		{"CREATE TABLE a (x INTEGER)", nil},
+		{"INSERT INTO a VALUES (1), (2)", ptr(int64(2))},
+		// SQLite would report the INSERT's two rows again
+		{"CREATE INDEX a_x ON a (x)", nil},
+		{"ANALYZE", nil},
+		{"DELETE FROM a", ptr(int64(2))},
+	}
+	for _, tt := range tests {
+		result, err := d.ExecuteSQL(ctx, id, "", tt.sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Error != "" {
+			t.Fatalf("%s: %s", tt.sql, result.Error)
+		}
+		got := result.RowsAffected
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("%s: rows affected = %v, want %v", tt.sql, fmtPtr(got), fmtPtr(tt.want))
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func fmtPtr(p *int64) string {
+	if p == nil {
+		return "nil"
+	}
+	return strconv.FormatInt(*p, 10)
+}
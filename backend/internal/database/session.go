@@ -0,0 +1,349 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"opendbm/internal/models"
+)
+
+// DefaultSessionIdleTimeout is how long a session may sit unused before it
+// is closed and its open transaction rolled back
+const DefaultSessionIdleTimeout = 10 * time.Minute
+
+var (
+	// ErrSessionNotFound is returned for unknown or expired sessions
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionBusy is returned when a session is already running a statement
+	ErrSessionBusy = errors.New("session is busy running another statement")
+	// ErrTransactionOpen is returned when beginning a transaction on a
+	// session that has one open
+	ErrTransactionOpen = errors.New("a transaction is already open")
+	// ErrNoTransaction is returned when committing or rolling back on a
+	// session without an open transaction
+	ErrNoTransaction = errors.New("no transaction is open")
+)
+
+// queryer runs statements: a *sql.Conn, or the *sql.Tx open on one
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// session is a connection pinned for one client, so that transactions and
+// session settings such as search_path or USE carry over between requests.
+// busy is held while a statement runs on it; the other fields are guarded
+// by the driver's sessionsMu.
+type session struct {
+	id           string
+	connectionID string
+	conn         *sql.Conn
+	tx           *sql.Tx
+	cancelSQL    string
+	idleTimeout  time.Duration
+	createdAt    time.Time
+	lastUsed     time.Time
+	timer        *time.Timer
+	busy         sync.Mutex
+}
+
+// OpenSession pins a connection from the pool for a client. A zero idle
+// timeout uses DefaultSessionIdleTimeout.
+func (d *SQLDriverImpl) OpenSession(ctx context.Context, id string, idleTimeout time.Duration) (*models.SessionInfo, error) {
+	pool, dbType, err := d.pool(id)
+	if err != nil {
+		return nil, err
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultSessionIdleTimeout
+	}
+
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session: %w", err)
+	}
+
+	now := time.Now()
+	s := &session{
+		id:           uuid.New().String(),
+		connectionID: id,
+		conn:         conn,
+		cancelSQL:    cancelStatementSQL(ctx, conn, dbType),
+		idleTimeout:  idleTimeout,
+		createdAt:    now,
+		lastUsed:     now,
+	}
+
+	d.sessionsMu.Lock()
+	d.sessions[s.id] = s
+	s.timer = time.AfterFunc(idleTimeout, func() { d.expireSession(s) })
+	info := s.info()
+	d.sessionsMu.Unlock()
+
+	return &info, nil
+}
+
+// ListSessions returns the open sessions of a connection, oldest first
+func (d *SQLDriverImpl) ListSessions(id string) []models.SessionInfo {
+	d.sessionsMu.Lock()
+	defer d.sessionsMu.Unlock()
+
+	sessions := []models.SessionInfo{}
+	for _, s := range d.sessions {
+		if s.connectionID == id {
+			sessions = append(sessions, s.info())
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions
+}
+
+// CloseSession rolls back a session's open transaction and returns its
+// connection to the pool
+func (d *SQLDriverImpl) CloseSession(id string, sessionID string) error {
+	s, err := d.session(id, sessionID)
+	if err != nil {
+		return err
+	}
+	if !s.busy.TryLock() {
+		return ErrSessionBusy
+	}
+	defer s.busy.Unlock()
+
+	d.sessionsMu.Lock()
+	delete(d.sessions, s.id)
+	d.sessionsMu.Unlock()
+
+	d.endSession(s)
+	return nil
+}
+
+// BeginTransaction starts a transaction on a session
+func (d *SQLDriverImpl) BeginTransaction(ctx context.Context, id string, sessionID string) (*models.SessionInfo, error) {
+	return d.transaction(id, sessionID, func(s *session) error {
+		if s.tx != nil {
+			return fmt.Errorf("session %s: %w", s.id, ErrTransactionOpen)
+		}
+		// The transaction outlives this request, so it must not be bound
+		// to the request's context
+		tx, err := s.conn.BeginTx(context.Background(), nil)
+		if err != nil {
+			return err
+		}
+		d.sessionsMu.Lock()
+		s.tx = tx
+		d.sessionsMu.Unlock()
+		return nil
+	})
+}
+
+// Commit commits a session's open transaction
+func (d *SQLDriverImpl) Commit(id string, sessionID string) (*models.SessionInfo, error) {
+	return d.transaction(id, sessionID, func(s *session) error {
+		return d.finishTransaction(s, (*sql.Tx).Commit)
+	})
+}
+
+// Rollback rolls back a session's open transaction
+func (d *SQLDriverImpl) Rollback(id string, sessionID string) (*models.SessionInfo, error) {
+	return d.transaction(id, sessionID, func(s *session) error {
+		return d.finishTransaction(s, (*sql.Tx).Rollback)
+	})
+}
+
+// transaction runs fn with the session locked and reports its new state
+func (d *SQLDriverImpl) transaction(id string, sessionID string, fn func(s *session) error) (*models.SessionInfo, error) {
+	s, err := d.session(id, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.busy.TryLock() {
+		return nil, ErrSessionBusy
+	}
+	defer s.busy.Unlock()
+
+	err = fn(s)
+
+	d.sessionsMu.Lock()
+	d.touchSession(s)
+	info := s.info()
+	d.sessionsMu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (d *SQLDriverImpl) finishTransaction(s *session, finish func(*sql.Tx) error) error {
+	if s.tx == nil {
+		return fmt.Errorf("session %s: %w", s.id, ErrNoTransaction)
+	}
+	err := finish(s.tx)
+
+	// A failed commit or rollback still ends the transaction
+	d.sessionsMu.Lock()
+	s.tx = nil
+	d.sessionsMu.Unlock()
+	return err
+}
+
+// acquire returns where a statement runs: the session's open transaction
+// or connection when sessionID is set, else a connection of its own from
+// the pool. The server is asked to stop the statement when ctx ends. Call
+// release once the statement is done.
+func (d *SQLDriverImpl) acquire(ctx context.Context, id string, sessionID string) (q queryer, release func(), err error) {
+	pool, dbType, err := d.pool(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if sessionID == "" {
+		conn, err := pool.Conn(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		stop := watchCancel(ctx, pool, cancelStatementSQL(ctx, conn, dbType))
+		detach := d.attachNotices(ctx, conn, dbType)
+		return conn, func() {
+			detach()
+			stop()
+			conn.Close()
+		}, nil
+	}
+
+	s, err := d.session(id, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !s.busy.TryLock() {
+		return nil, nil, ErrSessionBusy
+	}
+
+	d.sessionsMu.Lock()
+	q = s.conn
+	if s.tx != nil {
+		q = s.tx
+	}
+	d.sessionsMu.Unlock()
+
+	stop := watchCancel(ctx, pool, s.cancelSQL)
+	detach := d.attachNotices(ctx, s.conn, dbType)
+	return q, func() {
+		detach()
+		stop()
+		d.sessionsMu.Lock()
+		d.touchSession(s)
+		d.sessionsMu.Unlock()
+		s.busy.Unlock()
+	}, nil
+}
+
+// attachNotices routes the PostgreSQL notices of conn to the context's
+// message sink until the returned function is called
+func (d *SQLDriverImpl) attachNotices(ctx context.Context, conn *sql.Conn, dbType string) (detach func()) {
+	if dbType != "postgres" {
+		return func() {}
+	}
+	return d.notices.attach(ctx, conn)
+}
+
+// session looks up a session of a connection
+func (d *SQLDriverImpl) session(id string, sessionID string) (*session, error) {
+	d.sessionsMu.Lock()
+	defer d.sessionsMu.Unlock()
+
+	s, exists := d.sessions[sessionID]
+	if !exists || s.connectionID != id {
+		return nil, ErrSessionNotFound
+	}
+	return s, nil
+}
+
+// touchSession restarts a session's idle timer; callers must hold
+// sessionsMu
+func (d *SQLDriverImpl) touchSession(s *session) {
+	s.lastUsed = time.Now()
+	s.timer.Reset(s.idleTimeout)
+}
+
+// expireSession closes a session whose idle timer fired, unless it has been
+// used in the meantime
+func (d *SQLDriverImpl) expireSession(s *session) {
+	if !s.busy.TryLock() {
+		// A statement is running; its release restarts the timer
+		return
+	}
+	defer s.busy.Unlock()
+
+	d.sessionsMu.Lock()
+	if _, exists := d.sessions[s.id]; !exists || time.Since(s.lastUsed) < s.idleTimeout {
+		d.sessionsMu.Unlock()
+		return
+	}
+	delete(d.sessions, s.id)
+	inTx := s.tx != nil
+	d.sessionsMu.Unlock()
+
+	if inTx {
+		log.Printf("Session %s was idle for %s, rolling back its transaction", s.id, s.idleTimeout)
+	}
+	d.endSession(s)
+}
+
+// closeSessions ends every session of a connection that is going away.
+// Sessions still running a statement end once it finishes.
+func (d *SQLDriverImpl) closeSessions(id string) {
+	d.sessionsMu.Lock()
+	var closing []*session
+	for sessionID, s := range d.sessions {
+		if s.connectionID == id {
+			closing = append(closing, s)
+			delete(d.sessions, sessionID)
+		}
+	}
+	d.sessionsMu.Unlock()
+
+	for _, s := range closing {
+		go func(s *session) {
+			s.busy.Lock()
+			defer s.busy.Unlock()
+			d.endSession(s)
+		}(s)
+	}
+}
+
+// endSession rolls back a removed session's transaction and releases its
+// connection; callers must hold the session's lock
+func (d *SQLDriverImpl) endSession(s *session) {
+	s.timer.Stop()
+	if s.tx != nil {
+		if err := s.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Printf("Failed to roll back session %s: %v", s.id, err)
+		}
+		s.tx = nil
+	}
+	s.conn.Close()
+}
+
+// info describes a session; callers must hold sessionsMu
+func (s *session) info() models.SessionInfo {
+	return models.SessionInfo{
+		ID:            s.id,
+		ConnectionID:  s.connectionID,
+		InTransaction: s.tx != nil,
+		IdleTimeout:   int(s.idleTimeout / time.Second),
+		CreatedAt:     s.createdAt,
+		LastUsedAt:    s.lastUsed,
+	}
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countA counts the rows of table a on the session, or the pool when
+// sessionID is empty
+func countA(t *testing.T, d *SQLDriverImpl, id string, sessionID string) int64 {
+	t.Helper()
+	result, err := d.ExecuteQuery(context.Background(), id, sessionID, "SELECT count(*) FROM a")
+	if err != nil || result.Error != "" {
+		t.Fatalf("counting rows: %v %s", err, result.Error)
+	}
+	n, _ := firstInt(result)
+	return n
+}
+
+// execOn runs a statement on a session
+func execOn(t *testing.T, d *SQLDriverImpl, id string, sessionID string, stmt string) {
+	t.Helper()
+	result, err := d.ExecuteSQL(context.Background(), id, sessionID, stmt)
+	if err != nil || result.Error != "" {
+		t.Fatalf("%s: %v %s", stmt, err, result.Error)
+	}
+}
+
+func TestSessionTransactions(t *testing.T) {
+	d, id := newTestSQLite(t)
+	ctx := context.Background()
+	execOn(t, d, id, "", "CREATE TABLE a (x INTEGER)")
+
+	s, err := d.OpenSession(ctx, id, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.InTransaction || s.IdleTimeout != int(DefaultSessionIdleTimeout/time.Second) {
+		t.Errorf("new session = %+v", s)
+	}
+
+	if _, err := d.Commit(id, s.ID); !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("Commit() without a transaction = %v, want ErrNoTransaction", err)
+	}
+	if _, err := d.Rollback(id, s.ID); !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("Rollback() without a transaction = %v, want ErrNoTransaction", err)
+	}
+
+	info, err := d.BeginTransaction(ctx, id, s.ID)
+	if err != nil || !info.InTransaction {
+		t.Fatalf("BeginTransaction() = %+v, %v", info, err)
+	}
+	if _, err := d.BeginTransaction(ctx, id, s.ID); !errors.Is(err, ErrTransactionOpen) {
+		t.Errorf("second BeginTransaction() = %v, want ErrTransactionOpen", err)
+	}
+
+	execOn(t, d, id, s.ID, "INSERT INTO a VALUES (1)")
+	if n := countA(t, d, id, s.ID); n != 1 {
+		t.Errorf("session sees %d rows of its transaction, want 1", n)
+	}
+	if n := countA(t, d, id, ""); n != 0 {
+		t.Errorf("pool sees %d uncommitted rows, want 0", n)
+	}
+
+	info, err = d.Rollback(id, s.ID)
+	if err != nil || info.InTransaction {
+		t.Fatalf("Rollback() = %+v, %v", info, err)
+	}
+	if n := countA(t, d, id, s.ID); n != 0 {
+		t.Errorf("%d rows after rolling back, want 0", n)
+	}
+
+	if _, err := d.BeginTransaction(ctx, id, s.ID); err != nil {
+		t.Fatal(err)
+	}
+	execOn(t, d, id, s.ID, "INSERT INTO a VALUES (2)")
+	if _, err := d.Commit(id, s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := countA(t, d, id, ""); n != 1 {
+		t.Errorf("pool sees %d rows after the commit, want 1", n)
+	}
+
+	// An open transaction is rolled back when the session closes
+	if _, err := d.BeginTransaction(ctx, id, s.ID); err != nil {
+		t.Fatal(err)
+	}
+	execOn(t, d, id, s.ID, "INSERT INTO a VALUES (3)")
+	if err := d.CloseSession(id, s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := countA(t, d, id, ""); n != 1 {
+		t.Errorf("%d rows after closing the session, want 1", n)
+	}
+	if _, err := d.Commit(id, s.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Commit() on a closed session = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestSessionLookup(t *testing.T) {
+	d, id := newTestSQLite(t)
+	s, err := d.OpenSession(context.Background(), id, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.CloseSession(id, s.ID)
+
+	if sessions := d.ListSessions(id); len(sessions) != 1 || sessions[0].ID != s.ID {
+		t.Errorf("ListSessions() = %+v", sessions)
+	}
+	if sessions := d.ListSessions("other"); len(sessions) != 0 {
+		t.Errorf("ListSessions() of another connection = %+v", sessions)
+	}
+	if _, err := d.ExecuteQuery(context.Background(), "other", s.ID, "SELECT 1"); err == nil {
+		t.Error("a session ran a query for another connection")
+	}
+
+	d.sessionsMu.Lock()
+	held := d.sessions[s.ID]
+	d.sessionsMu.Unlock()
+	held.busy.Lock()
+	_, err = d.ExecuteQuery(context.Background(), id, s.ID, "SELECT 1")
+	_, beginErr := d.BeginTransaction(context.Background(), id, s.ID)
+	held.busy.Unlock()
+	if !errors.Is(err, ErrSessionBusy) || !errors.Is(beginErr, ErrSessionBusy) {
+		t.Errorf("busy session = %v, %v; want ErrSessionBusy", err, beginErr)
+	}
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	d, id := newTestSQLite(t)
+	ctx := context.Background()
+	execOn(t, d, id, "", "CREATE TABLE a (x INTEGER)")
+
+	s, err := d.OpenSession(ctx, id, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.BeginTransaction(ctx, id, s.ID); err != nil {
+		t.Fatal(err)
+	}
+	execOn(t, d, id, s.ID, "INSERT INTO a VALUES (1)")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(d.ListSessions(id)) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("idle session was not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := d.ExecuteQuery(ctx, id, s.ID, "SELECT 1"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("query on an expired session = %v, want ErrSessionNotFound", err)
+	}
+	if n := countA(t, d, id, ""); n != 0 {
+		t.Errorf("%d rows after the idle session expired, want its transaction rolled back", n)
+	}
+}
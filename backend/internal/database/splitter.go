@@ -0,0 +1,311 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxBatchRepeats caps the count of a SQL Server GO n line
+const maxBatchRepeats = 100
+
+// Statement is one statement of a script
+type Statement struct {
+	SQL  string
+	Line int // line of the script the statement starts on, from 1
+}
+
+var (
+	delimiterCommand = regexp.MustCompile(`(?i)^DELIMITER\s+(\S+)\s*$`)
+	goCommand        = regexp.MustCompile(`(?i)^GO(?:\s+(\d+))?\s*$`)
+	dollarQuoteTag   = regexp.MustCompile(`^\$(?:[A-Za-z_\x80-\xff][A-Za-z0-9_\x80-\xff]*)?\$`)
+	plsqlBlockStart  = regexp.MustCompile(`(?i)^(?:DECLARE|BEGIN|CREATE\s+(?:OR\s+REPLACE\s+)?(?:(?:NON)?EDITIONABLE\s+)?(?:PROCEDURE|FUNCTION|PACKAGE|TRIGGER|TYPE|LIBRARY|JAVA))\b`)
+	sqliteTrigger    = regexp.MustCompile(`(?i)^CREATE\s+(?:TEMP\s+|TEMPORARY\s+)?TRIGGER\b`)
+	endsWithEnd      = regexp.MustCompile(`(?i)\bEND\s*$`)
+)
+
+// SplitStatements splits a script into statements the way the dialect's
+// own client would. Quoted strings, identifiers and comments never end a
+// statement. Beyond that:
+//
+//	mysql:     DELIMITER lines change the terminator
+//	postgres:  dollar-quoted bodies ($$ ... $$, $fn$ ... $fn$) are skipped
+//	sqlserver: GO lines separate batches, which are sent whole; GO n repeats
+//	           one up to maxBatchRepeats times
+//	oracle:    PL/SQL blocks run up to a line holding only a slash
+//	sqlite:    CREATE TRIGGER bodies run up to END;
+//
+// Statements holding nothing but comments are dropped. A GO count out of
+// range is an ErrInvalidRequest.
+func SplitStatements(script string, dbType string) ([]Statement, error) {
+	s := &splitter{src: script, dbType: dbType, delimiter: ";", line: 1}
+	s.split()
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.statements, nil
+}
+
+type splitter struct {
+	src        string
+	dbType     string
+	delimiter  string
+	statements []Statement
+
+	start    int // offset of the current statement
+	codeAt   int // offset of its first character outside comments, -1 if none
+	line     int // line of offset lineAt
+	lineAt   int
+	repeated int // times a GO batch runs
+	err      error
+}
+
+func (s *splitter) split() {
+	s.codeAt = -1
+	i := 0
+	for i < len(s.src) {
+		if i == 0 || s.src[i-1] == '\n' {
+			if next, ok := s.clientCommand(i); ok {
+				if s.err != nil {
+					return
+				}
+				i = next
+				continue
+			}
+		}
+
+		if next := s.skipComment(i); next > i {
+			i = next
+			continue
+		}
+
+		c := s.src[i]
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' && s.codeAt < 0 {
+			s.codeAt = i
+		}
+
+		if next := s.skipLiteral(i); next > i {
+			i = next
+			continue
+		}
+		if strings.HasPrefix(s.src[i:], s.delimiter) && s.endsStatement(i) {
+			s.flush(i)
+			i += len(s.delimiter)
+			s.start = i
+			continue
+		}
+		i++
+	}
+	s.flush(len(s.src))
+}
+
+// skipComment returns the offset past a comment starting at i, or i when
+// there is none
+func (s *splitter) skipComment(i int) int {
+	switch {
+	case strings.HasPrefix(s.src[i:], "--"),
+		s.src[i] == '#' && s.dbType == "mysql":
+		return s.skipLine(i)
+	case strings.HasPrefix(s.src[i:], "/*"):
+		return s.skipBlockComment(i)
+	}
+	return i
+}
+
+// skipLiteral returns the offset past a quoted string or identifier
+// starting at i, or i when there is none
+func (s *splitter) skipLiteral(i int) int {
+	switch c := s.src[i]; {
+	case c == '\'':
+		return s.skipQuoted(i, '\'', s.backslashEscapes(i))
+	case c == '"':
+		return s.skipQuoted(i, '"', s.dbType == "mysql")
+	case c == '`' && (s.dbType == "mysql" || s.dbType == "sqlite"):
+		return s.skipQuoted(i, '`', false)
+	case c == '[' && (s.dbType == "sqlserver" || s.dbType == "sqlite"):
+		return s.skipQuoted(i, ']', false)
+	case c == '$' && s.dbType == "postgres" && !identChar(s.src, i-1):
+		return s.skipDollarQuoted(i)
+	}
+	return i
+}
+
+// clientCommand handles a line that is a command to the client rather than
+// SQL, returning the offset of the next line
+func (s *splitter) clientCommand(i int) (int, bool) {
+	end := strings.IndexByte(s.src[i:], '\n')
+	next := len(s.src)
+	if end >= 0 {
+		next = i + end + 1
+	} else {
+		end = len(s.src) - i
+	}
+	text := strings.TrimSpace(s.src[i : i+end])
+
+	switch s.dbType {
+	case "mysql":
+		if m := delimiterCommand.FindStringSubmatch(text); m != nil {
+			s.flush(i)
+			s.delimiter = m[1]
+			s.start = next
+			return next, true
+		}
+	case "sqlserver":
+		if m := goCommand.FindStringSubmatch(text); m != nil {
+			s.repeated = 1
+			if m[1] != "" {
+				n, err := strconv.Atoi(m[1])
+				if err != nil || n > maxBatchRepeats {
+					s.err = invalidRequest{fmt.Errorf("line %d: GO repeats a batch at most %d times", s.lineOf(i), maxBatchRepeats)}
+					return next, true
+				}
+				s.repeated = n
+			}
+			s.flush(i)
+			s.start = next
+			return next, true
+		}
+	case "oracle":
+		if text == "/" {
+			s.flush(i)
+			s.start = next
+			return next, true
+		}
+	}
+	return 0, false
+}
+
+// endsStatement reports whether the delimiter at i ends the statement.
+// SQL Server batches only end at GO, and procedural bodies at their own
+// terminator.
+func (s *splitter) endsStatement(i int) bool {
+	if s.codeAt < 0 {
+		return true
+	}
+	text := s.src[s.codeAt:i]
+	switch s.dbType {
+	case "sqlserver":
+		return false
+	case "oracle":
+		return !plsqlBlockStart.MatchString(text)
+	case "sqlite":
+		return !sqliteTrigger.MatchString(text) || endsWithEnd.MatchString(text)
+	}
+	return true
+}
+
+// flush ends the current statement at end
+func (s *splitter) flush(end int) {
+	repeated := s.repeated
+	s.repeated = 0
+	if s.codeAt < 0 || s.codeAt >= end {
+		s.codeAt = -1
+		return
+	}
+
+	text := strings.TrimSpace(s.src[s.start:end])
+	line := s.lineOf(s.codeAt)
+	s.codeAt = -1
+
+	if repeated < 1 {
+		repeated = 1
+	}
+	for n := 0; n < repeated; n++ {
+		s.statements = append(s.statements, Statement{SQL: text, Line: line})
+	}
+}
+
+// lineOf returns the line of offset, which must not be before the offset
+// of the previous call
+func (s *splitter) lineOf(offset int) int {
+	s.line += strings.Count(s.src[s.lineAt:offset], "\n")
+	s.lineAt = offset
+	return s.line
+}
+
+func (s *splitter) skipLine(i int) int {
+	if end := strings.IndexByte(s.src[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(s.src)
+}
+
+// skipBlockComment skips a /* */ comment, which PostgreSQL allows to nest
+func (s *splitter) skipBlockComment(i int) int {
+	depth := 0
+	for i < len(s.src) {
+		switch {
+		case strings.HasPrefix(s.src[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(s.src[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 || s.dbType != "postgres" {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return i
+}
+
+// skipQuoted skips a quoted string or identifier starting at i, where a
+// doubled closing quote stands for itself
+func (s *splitter) skipQuoted(i int, closing byte, backslash bool) int {
+	i++
+	for i < len(s.src) {
+		switch s.src[i] {
+		case '\\':
+			if backslash {
+				i += 2
+				continue
+			}
+		case closing:
+			if i+1 < len(s.src) && s.src[i+1] == closing {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
+
+// skipDollarQuoted skips a PostgreSQL $tag$ ... $tag$ string. A $ that
+// starts no tag is left alone, as in positional parameters like $1.
+func (s *splitter) skipDollarQuoted(i int) int {
+	tag := dollarQuoteTag.FindString(s.src[i:])
+	if tag == "" {
+		return i
+	}
+	end := strings.Index(s.src[i+len(tag):], tag)
+	if end < 0 {
+		return len(s.src)
+	}
+	return i + len(tag) + end + len(tag)
+}
+
+// backslashEscapes reports whether backslashes escape in the string
+// starting at i: always in MySQL, and in PostgreSQL E'...' strings
+func (s *splitter) backslashEscapes(i int) bool {
+	switch s.dbType {
+	case "mysql":
+		return true
+	case "postgres":
+		return i > 0 && (s.src[i-1] == 'E' || s.src[i-1] == 'e') && !identChar(s.src, i-2)
+	}
+	return false
+}
+
+// identChar reports whether src[i] can be part of an identifier
+func identChar(src string, i int) bool {
+	if i < 0 || i >= len(src) {
+		return false
+	}
+	c := src[i]
+	return c == '_' || c >= 0x80 || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		dbType string
+		script string
+		want   []Statement
+	}{
+		{
+			"plain", "sqlite",
+			"SELECT 1; SELECT 2;\nSELECT 3",
+			[]Statement{{"SELECT 1", 1}, {"SELECT 2", 1}, {"SELECT 3", 2}},
+		},
+		{
+			"empty and comment-only statements", "postgres",
+			";;\n-- note\n;\n/* block */ ;\n",
+			nil,
+		},
+		{
+			"delimiters in strings, identifiers and comments", "postgres",
+			"SELECT 'a;b', \"c;d\" -- e;f\nFROM t; /* g; */ SELECT 1",
+			[]Statement{{"SELECT 'a;b', \"c;d\" -- e;f\nFROM t", 1}, {"/* g; */ SELECT 1", 2}},
+		},
+		{
+			"doubled quotes", "sqlite",
+			"SELECT 'it''s;'; SELECT \"a\"\";\"",
+			[]Statement{{"SELECT 'it''s;'", 1}, {"SELECT \"a\"\";\"", 1}},
+		},
+		{
+			"line of the first code after a comment", "sqlite",
+			"SELECT 1;\n-- about the next one\n\nSELECT 2;",
+			[]Statement{{"SELECT 1", 1}, {"-- about the next one\n\nSELECT 2", 4}},
+		},
+		{
+			"unterminated string", "sqlite",
+			"SELECT 'open; SELECT 2",
+			[]Statement{{"SELECT 'open; SELECT 2", 1}},
+		},
+		{
+			"mysql backslash escapes and hash comments", "mysql",
+			"SELECT 'a\\';b'; # c;\nSELECT `x;y`",
+			[]Statement{{"SELECT 'a\\';b'", 1}, {"# c;\nSELECT `x;y`", 2}},
+		},
+		{
+			"mysql delimiter", "mysql",
+			"DELIMITER //\nCREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END//\nDELIMITER ;\nCALL p();",
+			[]Statement{{"CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END", 2}, {"CALL p()", 4}},
+		},
+		{
+			"postgres backslashes only escape in E strings", "postgres",
+			"SELECT 'a\\'; SELECT E'b\\';c';",
+			[]Statement{{"SELECT 'a\\'", 1}, {"SELECT E'b\\';c'", 1}},
+		},
+		{
+			"postgres dollar quoting", "postgres",
+			"CREATE FUNCTION f() RETURNS int AS $fn$ SELECT 1; $$ $fn$ LANGUAGE sql; SELECT $1;",
+			[]Statement{{"CREATE FUNCTION f() RETURNS int AS $fn$ SELECT 1; $$ $fn$ LANGUAGE sql", 1}, {"SELECT $1", 1}},
+		},
+		{
+			"postgres nested comments", "postgres",
+			"SELECT /* a /* b; */ c; */ 1; SELECT 2",
+			[]Statement{{"SELECT /* a /* b; */ c; */ 1", 1}, {"SELECT 2", 1}},
+		},
+		{
+			"sqlserver batches", "sqlserver",
+			"SELECT 1; SELECT 2\nGO\nPRINT 'go'\ngo 3\nSELECT [a;b]",
+			[]Statement{{"SELECT 1; SELECT 2", 1}, {"PRINT 'go'", 3}, {"PRINT 'go'", 3}, {"PRINT 'go'", 3}, {"SELECT [a;b]", 5}},
+		},
+		{
+			"oracle blocks end at a slash", "oracle",
+			"SELECT 1 FROM dual;\nBEGIN\n  NULL;\nEND;\n/\nCREATE OR REPLACE PROCEDURE p IS BEGIN NULL; END;\n/\nSELECT 2 FROM dual",
+			[]Statement{
+				{"SELECT 1 FROM dual", 1},
+				{"BEGIN\n  NULL;\nEND;", 2},
+				{"CREATE OR REPLACE PROCEDURE p IS BEGIN NULL; END;", 6},
+				{"SELECT 2 FROM dual", 8},
+			},
+		},
+		{
+			"sqlite triggers end at END;", "sqlite",
+			"CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET n = n + 1; END; SELECT 1",
+			[]Statement{{"CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET n = n + 1; END", 1}, {"SELECT 1", 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitStatements(tt.script, tt.dbType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitStatements() =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitStatementsBatchRepeats(t *testing.T) {
+	script := "PRINT 'a'\nGO 100\nPRINT 'b'\nGO 100000000\nPRINT 'c'"
+	if _, err := SplitStatements(script, "sqlserver"); !errors.Is(err, ErrInvalidRequest) || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("GO 100000000 = %v, want an ErrInvalidRequest on line 4", err)
+	}
+	if _, err := SplitStatements("PRINT 'a'\nGO 99999999999999999999", "sqlserver"); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("GO with an overflowing count = %v, want an ErrInvalidRequest", err)
+	}
+
+	statements, err := SplitStatements("PRINT 'a'\nGO 100", "sqlserver")
+	if err != nil || len(statements) != maxBatchRepeats {
+		t.Errorf("GO 100 = %d statements, %v; want %d", len(statements), err, maxBatchRepeats)
+	}
+	// Only SQL Server reads GO
+	if statements, err := SplitStatements("SELECT 1\nGO 100000000", "postgres"); err != nil || len(statements) != 1 {
+		t.Errorf("postgres GO line = %v, %v; want one statement", statements, err)
+	}
+}
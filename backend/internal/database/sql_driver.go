@@ -1,12 +1,25 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/golang-sql/sqlexp"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
@@ -17,18 +30,70 @@ import (
 	"opendbm/internal/models"
 )
 
+var errConnectionNotFound = errors.New("connection not found")
+
+// metadataTimeout bounds catalog queries such as listing tables
+const metadataTimeout = 30 * time.Second
+
+const (
+	// MaxQueryRows caps the rows a buffered query returns; larger results
+	// should be streamed
+	MaxQueryRows = 50_000
+	// MaxStreamRows caps the rows a streamed query sends
+	MaxStreamRows = 1_000_000
+	// rowBatchSize is the number of rows handed to a RowWriter at a time
+	rowBatchSize = 500
+)
+
 // SQLDriverImpl implements SQLDriver interface using GORM
 type SQLDriverImpl struct {
 	connections     map[string]*gorm.DB
 	connectionTypes map[string]string
+	forwards        map[string]*Forward
+	tlsNames        map[string]string
+	secrets         SecretResolver
+	tunnels         *TunnelManager
 	mu              sync.RWMutex
+
+	sessions   map[string]*session
+	sessionsMu sync.Mutex
+	notices    *noticeRouter
+}
+
+var (
+	_ SQLDriver        = (*SQLDriverImpl)(nil)
+	_ ParamBinder      = (*SQLDriverImpl)(nil)
+	_ QueryStreamer    = (*SQLDriverImpl)(nil)
+	_ ScriptRunner     = (*SQLDriverImpl)(nil)
+	_ Explainer        = (*SQLDriverImpl)(nil)
+	_ SessionDriver    = (*SQLDriverImpl)(nil)
+	_ SchemaLister     = (*SQLDriverImpl)(nil)
+	_ TableStatsLister = (*SQLDriverImpl)(nil)
+	_ TableDescriber   = (*SQLDriverImpl)(nil)
+	_ DefinitionReader = (*SQLDriverImpl)(nil)
+	_ DDLGenerator     = (*SQLDriverImpl)(nil)
+	_ TableDataReader  = (*SQLDriverImpl)(nil)
+)
+
+func init() {
+	for _, dbType := range []string{"mysql", "postgres", "sqlite", "sqlserver", "oracle"} {
+		Register(dbType, func(env DriverEnv) Driver {
+			return NewSQLDriver(env.Secrets, env.Tunnels)
+		})
+	}
 }
 
 // NewSQLDriver creates a new SQL driver instance
-func NewSQLDriver() *SQLDriverImpl {
+func NewSQLDriver(secrets SecretResolver, tunnels *TunnelManager) *SQLDriverImpl {
 	return &SQLDriverImpl{
 		connections:     make(map[string]*gorm.DB),
 		connectionTypes: make(map[string]string),
+		forwards:        make(map[string]*Forward),
+		tlsNames:        make(map[string]string),
+		secrets:         secrets,
+		tunnels:         tunnels,
+		sessions:        make(map[string]*session),
+		notices:         newNoticeRouter(),
 	}
 }
 
@@ -36,26 +101,84 @@ func NewSQLDriver() *SQLDriverImpl {
 func (d *SQLDriverImpl) Connect(config models.ConnectionConfig) (string, error) {
 	var dialector gorm.Dialector
 
+	id := config.ID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	password, err := resolvePassword(d.secrets, config)
+	if err != nil {
+		return "", err
+	}
+
+	// TLS verifies the host the user entered, not the local tunnel address
+	tlsConfig, err := buildTLSConfig(d.secrets, config, config.Host)
+	if err != nil {
+		return "", err
+	}
+
+	var forward *Forward
+	if config.UseSSH && config.Type != "sqlite" {
+		forward, err = openForward(d.tunnels, config)
+		if err != nil {
+			return "", err
+		}
+		config.Host, config.Port = forward.LocalAddr()
+	}
+
+	var tlsName string
+	cleanup := func() {
+		if forward != nil {
+			forward.Close()
+		}
+		if tlsName != "" {
+			mysqldriver.DeregisterTLSConfig(tlsName)
+		}
+	}
+
 	switch config.Type {
 	case "mysql":
-		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&charset=utf8mb4",
-			config.Username, config.Password, config.Host, config.Port, config.Database)
-		dialector = mysql.Open(dsn)
+		if tlsConfig != nil {
+			tlsName = "opendbm-" + uuid.New().String()
+			if err := mysqldriver.RegisterTLSConfig(tlsName, tlsConfig); err != nil {
+				cleanup()
+				return "", fmt.Errorf("failed to register tls config: %w", err)
+			}
+		}
+		dialector = mysql.Open(mysqlDSN(config, password, tlsName))
 	case "postgres":
-		sslMode := "disable"
-		if config.SSL {
-			sslMode = "require"
+		pgConfig, err := pgx.ParseConfig(postgresDSN(config, password))
+		if err != nil {
+			cleanup()
+			return "", fmt.Errorf("invalid connection settings: %w", err)
 		}
-		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-			config.Host, config.Port, config.Username, config.Password, config.Database, sslMode)
-		dialector = postgres.Open(dsn)
+		pgConfig.TLSConfig = tlsConfig
+		pgConfig.OnNotice = d.notices.notice
+		dialector = postgres.New(postgres.Config{Conn: stdlib.OpenDB(*pgConfig)})
 	case "sqlite":
 		dialector = sqlite.Open(config.Database)
 	case "sqlserver":
-		dsn := fmt.Sprintf("sqlserver://%s:%s@%s:%d?database=%s",
-			config.Username, config.Password, config.Host, config.Port, config.Database)
-		dialector = sqlserver.Open(dsn)
+		dsn := sqlServerDSN(config, password)
+		if tlsConfig == nil {
+			dialector = sqlserver.Open(dsn)
+			break
+		}
+		msConfig, err := msdsn.Parse(dsn)
+		if err != nil {
+			cleanup()
+			return "", fmt.Errorf("invalid connection settings: %w", err)
+		}
+		msConfig.Encryption = msdsn.EncryptionRequired
+		msConfig.TLSConfig = tlsConfig
+		dialector = sqlserver.New(sqlserver.Config{Conn: sql.OpenDB(mssql.NewConnectorConfig(msConfig))})
+	case "oracle":
+		dialector, err = openOracle(config, password)
+		if err != nil {
+			cleanup()
+			return "", err
+		}
 	default:
+		cleanup()
 		return "", fmt.Errorf("unsupported database type: %s", config.Type)
 	}
 
@@ -63,15 +186,19 @@ func (d *SQLDriverImpl) Connect(config models.ConnectionConfig) (string, error)
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
+		cleanup()
 		return "", fmt.Errorf("failed to connect: %w", err)
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
+		cleanup()
 		return "", fmt.Errorf("failed to get sql.DB: %w", err)
 	}
 
 	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		cleanup()
 		return "", fmt.Errorf("failed to ping database: %w", err)
 	}
 
@@ -79,33 +206,97 @@ func (d *SQLDriverImpl) Connect(config models.ConnectionConfig) (string, error)
 	sqlDB.SetMaxIdleConns(5)
 	sqlDB.SetConnMaxLifetime(5 * time.Minute)
 
-	id := uuid.New().String()
 	d.mu.Lock()
+	d.close(id)
 	d.connections[id] = db
 	d.connectionTypes[id] = config.Type
+	if forward != nil {
+		d.forwards[id] = forward
+	}
+	if tlsName != "" {
+		d.tlsNames[id] = tlsName
+	}
 	d.mu.Unlock()
 
 	return id, nil
 }
 
+// mysqlDSN builds a MySQL connection string, escaping the user, password
+// and database. tlsName names a config registered with the driver, if any.
+func mysqlDSN(config models.ConnectionConfig, password string, tlsName string) string {
+	cfg := mysqldriver.NewConfig()
+	cfg.User = config.Username
+	cfg.Passwd = password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	cfg.DBName = config.Database
+	cfg.ParseTime = true
+	cfg.Params = map[string]string{"charset": "utf8mb4"}
+	cfg.TLSConfig = tlsName
+	return cfg.FormatDSN()
+}
+
+// sqlServerDSN builds a sqlserver:// URL, escaping the user, password and
+// database
+func sqlServerDSN(config models.ConnectionConfig, password string) string {
+	u := &url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(config.Username, password),
+		Host:     net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+		RawQuery: url.Values{"database": {config.Database}}.Encode(),
+	}
+	return u.String()
+}
+
+// postgresDSN builds a keyword/value connection string, quoting every value
+// so that spaces, quotes and backslashes in a password survive. TLS is set
+// on the parsed config instead.
+func postgresDSN(config models.ConnectionConfig, password string) string {
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	settings := []struct{ key, value string }{
+		{"host", config.Host},
+		{"port", strconv.Itoa(config.Port)},
+		{"user", config.Username},
+		{"password", password},
+		{"dbname", config.Database},
+		{"sslmode", "disable"},
+	}
+	parts := make([]string, len(settings))
+	for i, setting := range settings {
+		parts[i] = setting.key + "='" + quote.Replace(setting.value) + "'"
+	}
+	return strings.Join(parts, " ")
+}
+
 // Disconnect closes a database connection
 func (d *SQLDriverImpl) Disconnect(id string) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	db, exists := d.connections[id]
-	if !exists {
-		return nil
-	}
+	d.close(id)
+	return nil
+}
 
-	sqlDB, err := db.DB()
-	if err == nil {
-		sqlDB.Close()
+// close releases a connection, its sessions and its SSH forward; callers
+// must hold d.mu
+func (d *SQLDriverImpl) close(id string) {
+	d.closeSessions(id)
+	if db, exists := d.connections[id]; exists {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	if forward, exists := d.forwards[id]; exists {
+		forward.Close()
+	}
+	if tlsName, exists := d.tlsNames[id]; exists {
+		mysqldriver.DeregisterTLSConfig(tlsName)
 	}
 
 	delete(d.connections, id)
 	delete(d.connectionTypes, id)
-	return nil
+	delete(d.forwards, id)
+	delete(d.tlsNames, id)
 }
 
 // Ping checks if connection is alive
@@ -125,8 +316,90 @@ func (d *SQLDriverImpl) Ping(id string) error {
 	return sqlDB.Ping()
 }
 
-// ExecuteQuery executes a SELECT query and returns results
-func (d *SQLDriverImpl) ExecuteQuery(id string, query string) (*models.QueryResult, error) {
+// ExecuteQuery executes a query with args bound to its placeholders and
+// returns results, keeping at most MaxQueryRows rows. Statements that return
+// no rows are handed to ExecuteSQL. The query runs on the session when
+// sessionID is set, else on a dedicated connection, until ctx ends, at
+// which point the server is asked to stop it.
+func (d *SQLDriverImpl) ExecuteQuery(ctx context.Context, id string, sessionID string, query string, args ...interface{}) (*models.ResultSet, error) {
+	_, dbType, err := d.pool(id)
+	if err != nil {
+		return nil, err
+	}
+	if !returnsRows(query, dbType) {
+		return d.ExecuteSQL(ctx, id, sessionID, query, args...)
+	}
+
+	result := &models.ResultSet{
+		Columns:       []models.ResultColumn{},
+		Rows:          [][]interface{}{},
+		StatementType: statementType(query, dbType),
+	}
+
+	trailer, err := d.StreamQuery(ctx, id, sessionID, query, MaxQueryRows, resultBuffer{result}, args...)
+	if err != nil {
+		return nil, err
+	}
+	if trailer.Error != "" {
+		result.Columns = []models.ResultColumn{}
+		result.Rows = [][]interface{}{}
+	}
+
+	result.RowCount = len(result.Rows)
+	result.ExecutionTime = trailer.ExecutionTime
+	result.Truncated = trailer.Truncated
+	result.Messages = trailer.Messages
+	result.Error = trailer.Error
+	return result, nil
+}
+
+// StreamQuery executes a SELECT query and hands its rows to w in batches as
+// they are read, stopping after maxRows rows when maxRows is positive. The
+// returned trailer reports statement errors; an error is returned only when
+// the connection or session is unknown or the session is busy.
+func (d *SQLDriverImpl) StreamQuery(ctx context.Context, id string, sessionID string, query string, maxRows int, w RowWriter, args ...interface{}) (*models.QueryStreamTrailer, error) {
+	start := time.Now()
+	_, dbType, err := d.pool(id)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx, _ = withMessages(ctx)
+
+	q, release, err := d.acquire(ctx, id, sessionID)
+	if err != nil {
+		if isLookupError(err) {
+			return nil, err
+		}
+		return streamTrailer(ctx, start, 0, false, err), nil
+	}
+	defer release()
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		trailer := streamTrailer(ctx, start, 0, false, err)
+		trailer.Messages = statementMessages(ctx, q, dbType, err)
+		return trailer, nil
+	}
+	defer rows.Close()
+
+	count, truncated, err := scanRows(rows, maxRows, w)
+	if truncated && sessionID == "" {
+		// Stop the server producing rows that will never be read, rather
+		// than draining them when the rows are closed. A session's rows are
+		// drained, as canceling could end its connection.
+		cancel()
+	}
+	rows.Close()
+
+	trailer := streamTrailer(ctx, start, count, truncated, err)
+	trailer.Messages = statementMessages(ctx, q, dbType, err)
+	return trailer, nil
+}
+
+// query runs a metadata statement with bound arguments and collects its rows
+func (d *SQLDriverImpl) query(id string, query string, args ...interface{}) (*models.QueryResult, error) {
 	d.mu.RLock()
 	db, exists := d.connections[id]
 	d.mu.RUnlock()
@@ -135,17 +408,14 @@ func (d *SQLDriverImpl) ExecuteQuery(id string, query string) (*models.QueryResu
 		return nil, fmt.Errorf("connection not found")
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+
 	start := time.Now()
 
-	rows, err := db.Raw(query).Rows()
+	rows, err := db.WithContext(ctx).Raw(query, args...).Rows()
 	if err != nil {
-		return &models.QueryResult{
-			Columns:       []string{},
-			Rows:          []map[string]interface{}{},
-			RowCount:      0,
-			ExecutionTime: time.Since(start).Milliseconds(),
-			Error:         err.Error(),
-		}, nil
+		return failedResult(ctx, start, err), nil
 	}
 	defer rows.Close()
 
@@ -154,7 +424,7 @@ func (d *SQLDriverImpl) ExecuteQuery(id string, query string) (*models.QueryResu
 		return nil, fmt.Errorf("failed to get columns: %w", err)
 	}
 
-	var results []map[string]interface{}
+	results := []map[string]interface{}{}
 	for rows.Next() {
 		values := make([]interface{}, len(columns))
 		valuePtrs := make([]interface{}, len(columns))
@@ -176,9 +446,8 @@ func (d *SQLDriverImpl) ExecuteQuery(id string, query string) (*models.QueryResu
 		}
 		results = append(results, row)
 	}
-
-	if results == nil {
-		results = []map[string]interface{}{}
+	if err := rows.Err(); err != nil {
+		return failedResult(ctx, start, err), nil
 	}
 
 	return &models.QueryResult{
@@ -189,17 +458,279 @@ func (d *SQLDriverImpl) ExecuteQuery(id string, query string) (*models.QueryResu
 	}, nil
 }
 
-// ExecuteSQL executes a non-SELECT SQL statement
-func (d *SQLDriverImpl) ExecuteSQL(id string, sqlStatement string) error {
+// scanRows reads a result set into w, rowBatchSize rows at a time. It stops
+// after maxRows rows when maxRows is positive and reports whether more rows
+// were left unread.
+func scanRows(rows *sql.Rows, maxRows int, w RowWriter) (count int, truncated bool, err error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get columns: %w", err)
+	}
+	columns := resultColumns(types)
+	if err := w.Header(columns); err != nil {
+		return 0, false, err
+	}
+
+	batch := make([][]interface{}, 0, rowBatchSize)
+	for rows.Next() {
+		if maxRows > 0 && count == maxRows {
+			truncated = true
+			break
+		}
+
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return count, false, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		for i := range values {
+			values[i] = cellValue(values[i], &columns[i])
+		}
+		batch = append(batch, values)
+		count++
+
+		if len(batch) == rowBatchSize {
+			if err := w.Rows(batch); err != nil {
+				return count, false, err
+			}
+			batch = make([][]interface{}, 0, rowBatchSize)
+		}
+	}
+	if len(batch) > 0 {
+		if err := w.Rows(batch); err != nil {
+			return count, false, err
+		}
+	}
+	if truncated {
+		return count, true, nil
+	}
+	return count, false, rows.Err()
+}
+
+// resultBuffer is a RowWriter that collects rows into a ResultSet
+type resultBuffer struct {
+	result *models.ResultSet
+}
+
+func (b resultBuffer) Header(columns []models.ResultColumn) error {
+	b.result.Columns = columns
+	return nil
+}
+
+func (b resultBuffer) Rows(rows [][]interface{}) error {
+	b.result.Rows = append(b.result.Rows, rows...)
+	return nil
+}
+
+// streamTrailer summarizes a finished query
+func streamTrailer(ctx context.Context, start time.Time, count int, truncated bool, err error) *models.QueryStreamTrailer {
+	trailer := &models.QueryStreamTrailer{
+		RowCount:      count,
+		ExecutionTime: time.Since(start).Milliseconds(),
+		Truncated:     truncated,
+	}
+	if err != nil {
+		trailer.Error = queryError(ctx, err)
+	}
+	return trailer
+}
+
+// failedResult reports a statement error inside the result, as the query
+// editor shows it next to the statement
+func failedResult(ctx context.Context, start time.Time, err error) *models.QueryResult {
+	return &models.QueryResult{
+		Columns:       []string{},
+		Rows:          []map[string]interface{}{},
+		RowCount:      0,
+		ExecutionTime: time.Since(start).Milliseconds(),
+		Error:         queryError(ctx, err),
+	}
+}
+
+// ExecuteSQL executes a statement that returns no rows, such as DML or DDL,
+// with args bound to its placeholders, on the session when sessionID is set,
+// until ctx ends. The result reports the rows affected and server messages;
+// statement errors are reported in the result too, while an error is
+// returned when the connection or session is unknown or the session is busy.
+func (d *SQLDriverImpl) ExecuteSQL(ctx context.Context, id string, sessionID string, sqlStatement string, args ...interface{}) (*models.ResultSet, error) {
+	start := time.Now()
+	_, dbType, err := d.pool(id)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.ResultSet{
+		Columns:       []models.ResultColumn{},
+		Rows:          [][]interface{}{},
+		StatementType: statementType(sqlStatement, dbType),
+	}
+	ctx, _ = withMessages(ctx)
+
+	q, release, err := d.acquire(ctx, id, sessionID)
+	if err != nil {
+		if isLookupError(err) {
+			return nil, err
+		}
+		result.Error = queryError(ctx, err)
+		result.ExecutionTime = time.Since(start).Milliseconds()
+		return result, nil
+	}
+	defer release()
+
+	err = execStatement(ctx, q, dbType, sqlStatement, args, result)
+	result.Messages = append(result.Messages, statementMessages(ctx, q, dbType, err)...)
+	if err != nil {
+		result.Error = queryError(ctx, err)
+	}
+	result.ExecutionTime = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// execStatement runs a statement that returns no rows, recording the rows
+// a DML statement affected and, for inserts, the ID of the last row
+// inserted where the driver reports one (MySQL and SQLite). Other
+// statements have no row count: SQLite would report the last DML's.
+func execStatement(ctx context.Context, q queryer, dbType string, query string, args []interface{}, result *models.ResultSet) error {
+	if dbType == "sqlserver" {
+		return execSQLServer(ctx, q, query, args, result)
+	}
+
+	res, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if result.StatementType != "dml" {
+		return nil
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		result.RowsAffected = &n
+	}
+	if insertStatement.MatchString(stripLeadingComments(query)) {
+		if id, err := res.LastInsertId(); err == nil && id > 0 {
+			result.LastInsertID = &id
+		}
+	}
+	return nil
+}
+
+// execSQLServer runs a SQL Server batch through the driver's message queue,
+// which is the only way to receive PRINT output and informational messages.
+// Row counts of the batch's statements are added up; any rows it returns
+// are discarded.
+func execSQLServer(ctx context.Context, q queryer, query string, args []interface{}, result *models.ResultSet) error {
+	messages := &sqlexp.ReturnMessage{}
+	rows, err := q.QueryContext(ctx, query, append(args, messages)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var affected int64
+	counted := false
+	var batchErr error
+	for active := true; active; {
+		switch m := messages.Message(ctx).(type) {
+		case sqlexp.MsgNotice:
+			result.Messages = append(result.Messages, sqlServerMessage(m.Message))
+		case sqlexp.MsgRowsAffected:
+			affected += m.Count
+			counted = true
+		case sqlexp.MsgNext:
+			for rows.Next() {
+			}
+		case sqlexp.MsgNextResultSet:
+			active = rows.NextResultSet()
+		case sqlexp.MsgError:
+			if batchErr == nil {
+				batchErr = m.Error
+			}
+		}
+	}
+	if counted {
+		result.RowsAffected = &affected
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return rows.Err()
+}
+
+// pool returns the connection pool and dialect of a connection
+func (d *SQLDriverImpl) pool(id string) (*sql.DB, string, error) {
 	d.mu.RLock()
 	db, exists := d.connections[id]
+	dbType := d.connectionTypes[id]
 	d.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("connection not found")
+		return nil, "", errConnectionNotFound
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, "", err
 	}
+	return sqlDB, dbType, nil
+}
 
-	return db.Exec(sqlStatement).Error
+// isLookupError reports whether err is about the connection or session a
+// statement was meant for rather than the statement itself
+func isLookupError(err error) bool {
+	return errors.Is(err, errConnectionNotFound) || errors.Is(err, ErrSessionNotFound) || errors.Is(err, ErrSessionBusy)
+}
+
+// cancelStatementSQL returns the statement that stops whatever conn is
+// running, issued from another connection. The SQL Server, SQLite and
+// Oracle drivers interrupt statements themselves when their context ends;
+// the MySQL driver only drops its side of the connection, which leaves the
+// query running, so it and PostgreSQL get an explicit cancel.
+func cancelStatementSQL(ctx context.Context, conn *sql.Conn, dbType string) string {
+	switch dbType {
+	case "postgres":
+		var pid int64
+		if err := conn.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid); err == nil {
+			return fmt.Sprintf("SELECT pg_cancel_backend(%d)", pid)
+		}
+	case "mysql":
+		var connID int64
+		if err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&connID); err == nil {
+			return fmt.Sprintf("KILL QUERY %d", connID)
+		}
+	}
+	return ""
+}
+
+// watchCancel runs cancelSQL on the pool once ctx ends. Call stop when the
+// statement has finished.
+func watchCancel(ctx context.Context, pool *sql.DB, cancelSQL string) (stop func()) {
+	if cancelSQL == "" {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			cancelCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := pool.ExecContext(cancelCtx, cancelSQL); err != nil {
+				log.Printf("Failed to cancel query: %v", err)
+			}
+		case <-done:
+		}
+	}()
+	// Wait for a cancel in flight so it cannot reach the connection after
+	// it has gone back to the pool and started another statement
+	return func() {
+		close(done)
+		<-exited
+	}
 }
 
 // ListDatabases lists all databases
@@ -216,13 +747,16 @@ func (d *SQLDriverImpl) ListDatabases(id string) ([]string, error) {
 		query = "SELECT datname FROM pg_database WHERE datistemplate = false ORDER BY datname"
 	case "sqlserver":
 		query = "SELECT name FROM sys.databases ORDER BY name"
+	case "oracle":
+		// Oracle has one database per service; schemas play the role of databases
+		query = "SELECT username FROM all_users WHERE oracle_maintained = 'N' ORDER BY username"
 	case "sqlite":
 		return []string{"main"}, nil
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", dbType)
 	}
 
-	result, err := d.ExecuteQuery(id, query)
+	result, err := d.query(id, query)
 	if err != nil {
 		return nil, err
 	}
@@ -239,117 +773,55 @@ func (d *SQLDriverImpl) ListDatabases(id string) ([]string, error) {
 	return databases, nil
 }
 
-// ListTables lists all tables in a database
-func (d *SQLDriverImpl) ListTables(id string, database string) ([]models.TableInfo, error) {
+// ListSchemas lists the schemas of a database that hold user objects.
+// MySQL and Oracle have no schemas inside a database, which is its own
+// only schema; SQLite's schemas are its attached databases.
+func (d *SQLDriverImpl) ListSchemas(id string, database string) ([]string, error) {
 	d.mu.RLock()
 	dbType := d.connectionTypes[id]
-	db := d.connections[id]
 	d.mu.RUnlock()
 
 	var query string
 	switch dbType {
-	case "mysql":
-		query = fmt.Sprintf("SHOW TABLES FROM `%s`", database)
+	case "mysql", "oracle":
+		return []string{database}, nil
 	case "postgres":
-		query = "SELECT tablename as name FROM pg_tables WHERE schemaname = 'public' ORDER BY tablename"
+		query = "SELECT nspname AS name FROM pg_namespace WHERE nspname <> 'information_schema' AND nspname !~ '^pg_' ORDER BY nspname"
 	case "sqlserver":
-		query = "SELECT table_name as name FROM information_schema.tables WHERE table_type = 'BASE TABLE' ORDER BY table_name"
+		// Schemas of the fixed database roles belong to principals from 16384
+		query = "SELECT name FROM sys.schemas WHERE name NOT IN ('sys', 'INFORMATION_SCHEMA', 'guest') AND principal_id < 16384 ORDER BY name"
 	case "sqlite":
-		query = "SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%' ORDER BY name"
+		query = "SELECT name FROM pragma_database_list ORDER BY seq"
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", dbType)
 	}
 
-	if dbType == "mysql" {
-		db.Exec(fmt.Sprintf("USE `%s`", database))
-	}
-
-	result, err := d.ExecuteQuery(id, query)
+	result, err := d.query(id, query)
 	if err != nil {
 		return nil, err
 	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
 
-	var tables []models.TableInfo
+	schemas := []string{}
 	for _, row := range result.Rows {
-		for _, v := range row {
-			if tableName, ok := v.(string); ok {
-				tables = append(tables, models.TableInfo{Name: tableName, Type: "table"})
-				break
-			}
+		if name, ok := row["name"].(string); ok {
+			schemas = append(schemas, name)
 		}
 	}
-	return tables, nil
+	return schemas, nil
 }
 
-// GetTableSchema returns column information for a table
+// GetTableSchema returns column information for a table, given as a name
+// or schema.name. Without a schema the current one is used; for Oracle the
+// schema is the owner.
 func (d *SQLDriverImpl) GetTableSchema(id string, table string) ([]models.ColumnInfo, error) {
-	d.mu.RLock()
-	dbType := d.connectionTypes[id]
-	d.mu.RUnlock()
-
-	var query string
-	switch dbType {
-	case "mysql":
-		query = fmt.Sprintf("DESCRIBE `%s`", table)
-	case "postgres":
-		query = fmt.Sprintf(`SELECT column_name, data_type, is_nullable, column_default FROM information_schema.columns WHERE table_name = '%s' ORDER BY ordinal_position`, table)
-	case "sqlite":
-		query = fmt.Sprintf("PRAGMA table_info(%s)", table)
-	case "sqlserver":
-		query = fmt.Sprintf(`SELECT column_name, data_type, is_nullable, column_default FROM information_schema.columns WHERE table_name = '%s' ORDER BY ordinal_position`, table)
-	default:
-		return nil, fmt.Errorf("unsupported database type: %s", dbType)
-	}
-
-	result, err := d.ExecuteQuery(id, query)
+	detail, err := d.GetTableDetail(id, "", table)
 	if err != nil {
 		return nil, err
 	}
-
-	var columns []models.ColumnInfo
-	for _, row := range result.Rows {
-		col := models.ColumnInfo{}
-		switch dbType {
-		case "mysql":
-			if v, ok := row["Field"].(string); ok {
-				col.Name = v
-			}
-			if v, ok := row["Type"].(string); ok {
-				col.Type = v
-			}
-			if v, ok := row["Null"].(string); ok {
-				col.Nullable = v == "YES"
-			}
-			if v, ok := row["Key"].(string); ok {
-				col.IsPrimaryKey = v == "PRI"
-			}
-		case "postgres", "sqlserver":
-			if v, ok := row["column_name"].(string); ok {
-				col.Name = v
-			}
-			if v, ok := row["data_type"].(string); ok {
-				col.Type = v
-			}
-			if v, ok := row["is_nullable"].(string); ok {
-				col.Nullable = v == "YES"
-			}
-		case "sqlite":
-			if v, ok := row["name"].(string); ok {
-				col.Name = v
-			}
-			if v, ok := row["type"].(string); ok {
-				col.Type = v
-			}
-			if v, ok := row["notnull"]; ok {
-				col.Nullable = v == int64(0) || v == 0
-			}
-			if v, ok := row["pk"]; ok {
-				col.IsPrimaryKey = v == int64(1) || v == 1
-			}
-		}
-		columns = append(columns, col)
-	}
-	return columns, nil
+	return detail.Columns, nil
 }
 
 // GetDB returns the underlying *sql.DB for a connection
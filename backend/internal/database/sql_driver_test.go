@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"crypto/tls"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/microsoft/go-mssqldb/msdsn"
+
+	"opendbm/internal/models"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		database string
+	}{
+		{"plain", "app", "secret", "app"},
+		{"space", "app", "two words", "my db"},
+		{"quotes", "o'brien", `it's "quoted"`, "app"},
+		{"backslashes", "app", `back\slash\`, "app"},
+		{"escaped quote", "app", `\'`, "app"},
+		{"key look-alike", "app", "x sslmode=require host=evil", "app"},
+		{"empty", "app", "", "app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := models.ConnectionConfig{Host: "db.internal", Port: 5433, Username: tt.user, Database: tt.database}
+			parsed, err := pgx.ParseConfig(postgresDSN(config, tt.password))
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+			if parsed.Host != "db.internal" || parsed.Port != 5433 {
+				t.Errorf("host = %s:%d, want db.internal:5433", parsed.Host, parsed.Port)
+			}
+			if parsed.User != tt.user || parsed.Password != tt.password || parsed.Database != tt.database {
+				t.Errorf("user, password, database = %q, %q, %q; want %q, %q, %q",
+					parsed.User, parsed.Password, parsed.Database, tt.user, tt.password, tt.database)
+			}
+			if parsed.TLSConfig != nil {
+				t.Error("TLS was enabled by a value in the connection string")
+			}
+		})
+	}
+}
+
+// dsnCredentials are user names, passwords and databases with characters
+// that mean something in a connection string
+var dsnCredentials = []struct {
+	name     string
+	user     string
+	password string
+	database string
+}{
+	{"plain", "app", "secret", "app"},
+	{"at and slash", "app@corp", "p@ss/word", "app"},
+	{"query and colon", "app", "a?b:c=d&e", "my db"},
+	{"percent", "app", "100%25 %zz", "app"},
+	{"hash and space", "app", "# two words", "app"},
+	{"empty", "app", "", "app"},
+}
+
+func TestMySQLDSN(t *testing.T) {
+	if err := mysqldriver.RegisterTLSConfig("opendbm-test", &tls.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	defer mysqldriver.DeregisterTLSConfig("opendbm-test")
+
+	for _, tt := range dsnCredentials {
+		t.Run(tt.name, func(t *testing.T) {
+			config := models.ConnectionConfig{Host: "db.internal", Port: 3307, Username: tt.user, Database: tt.database}
+			parsed, err := mysqldriver.ParseDSN(mysqlDSN(config, tt.password, "opendbm-test"))
+			if err != nil {
+				t.Fatalf("ParseDSN() error = %v", err)
+			}
+			if parsed.Net != "tcp" || parsed.Addr != "db.internal:3307" {
+				t.Errorf("address = %s(%s), want tcp(db.internal:3307)", parsed.Net, parsed.Addr)
+			}
+			if parsed.User != tt.user || parsed.Passwd != tt.password || parsed.DBName != tt.database {
+				t.Errorf("user, password, database = %q, %q, %q; want %q, %q, %q",
+					parsed.User, parsed.Passwd, parsed.DBName, tt.user, tt.password, tt.database)
+			}
+			if !parsed.ParseTime || parsed.TLSConfig != "opendbm-test" || parsed.Params["charset"] != "utf8mb4" {
+				t.Errorf("parseTime = %v, tls = %q, charset = %q", parsed.ParseTime, parsed.TLSConfig, parsed.Params["charset"])
+			}
+		})
+	}
+}
+
+func TestSQLServerDSN(t *testing.T) {
+	for _, tt := range dsnCredentials {
+		t.Run(tt.name, func(t *testing.T) {
+			config := models.ConnectionConfig{Host: "db.internal", Port: 1434, Username: tt.user, Database: tt.database}
+			parsed, err := msdsn.Parse(sqlServerDSN(config, tt.password))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if parsed.Host != "db.internal" || parsed.Port != 1434 {
+				t.Errorf("host = %s:%d, want db.internal:1434", parsed.Host, parsed.Port)
+			}
+			if parsed.User != tt.user || parsed.Password != tt.password || parsed.Database != tt.database {
+				t.Errorf("user, password, database = %q, %q, %q; want %q, %q, %q",
+					parsed.User, parsed.Password, parsed.Database, tt.user, tt.password, tt.database)
+			}
+		})
+	}
+}
+
+// attachSQLite attaches a new database file under the given schema name,
+// keeping the pool to one connection so that every query sees it
+func attachSQLite(t *testing.T, d *SQLDriverImpl, id string, schema string) {
+	t.Helper()
+	db, _, err := d.pool(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	stmt := "ATTACH DATABASE '" + filepath.Join(t.TempDir(), "attached.db") + "' AS " + dialect("sqlite").quote(schema)
+	if result, err := d.ExecuteSQL(context.Background(), id, "", stmt); err != nil || result.Error != "" {
+		t.Fatal(err, result.Error)
+	}
+}
+
+func TestListSchemasSQLite(t *testing.T) {
+	d, id := newTestSQLite(t)
+	schemas, err := d.ListSchemas(id, "")
+	if err != nil || !reflect.DeepEqual(schemas, []string{"main"}) {
+		t.Errorf("ListSchemas() = %v, %v, want main", schemas, err)
+	}
+
+	attachSQLite(t, d, id, `we"ird`)
+	schemas, err = d.ListSchemas(id, "")
+	if err != nil || !reflect.DeepEqual(schemas, []string{"main", `we"ird`}) {
+		t.Errorf("ListSchemas() after attaching = %v, %v, want main and the attached database", schemas, err)
+	}
+
+	if _, err := d.ListSchemas("unknown", ""); err == nil {
+		t.Error("listing the schemas of an unknown connection succeeded")
+	}
+}
+
+func TestListTablesSQLite(t *testing.T) {
+	d, id := newTestSQLite(t)
+	attachSQLite(t, d, id, `we"ird`)
+	for _, stmt := range []string{
+		"CREATE TABLE b (x INTEGER)",
+		"CREATE TABLE a (x INTEGER)",
+		`CREATE TABLE "we""ird".c (x INTEGER)`,
+	} {
+		if result, err := d.ExecuteSQL(context.Background(), id, "", stmt); err != nil || result.Error != "" {
+			t.Fatal(err, result.Error)
+		}
+	}
+
+	tables := func(infos []models.TableInfo) string {
+		names := make([]string, len(infos))
+		for i, info := range infos {
+			names[i] = info.Schema + "." + info.Name + ":" + info.Type
+		}
+		return strings.Join(names, " ")
+	}
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{"", "main.a:table main.b:table"},
+		{"main", "main.a:table main.b:table"},
+		{`we"ird`, `we"ird.c:table`},
+	}
+	for _, tt := range tests {
+		infos, err := d.ListTables(id, "", tt.schema)
+		if err != nil || tables(infos) != tt.want {
+			t.Errorf("ListTables(%q) = %s, %v, want %s", tt.schema, tables(infos), err, tt.want)
+		}
+	}
+
+	if _, err := d.ListTables(id, "", "missing"); err == nil {
+		t.Error("listing the tables of an unknown schema succeeded")
+	}
+
+	// Tables of an attached database are addressed by schema
+	columns, err := d.GetTableSchema(id, TableRef(`we"ird`, "c"))
+	if err != nil || len(columns) != 1 || columns[0].Name != "x" {
+		t.Errorf("GetTableSchema() = %+v, %v, want column x", columns, err)
+	}
+}
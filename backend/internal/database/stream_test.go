@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"opendbm/internal/models"
+)
+
+// recordingWriter is a RowWriter that keeps what it is handed, failing
+// once it holds failAfter rows when failAfter is set
+type recordingWriter struct {
+	columns   []models.ResultColumn
+	headers   int
+	batches   []int
+	rows      [][]interface{}
+	failAfter int
+}
+
+func (w *recordingWriter) Header(columns []models.ResultColumn) error {
+	w.columns = columns
+	w.headers++
+	return nil
+}
+
+func (w *recordingWriter) Rows(rows [][]interface{}) error {
+	w.batches = append(w.batches, len(rows))
+	w.rows = append(w.rows, rows...)
+	if w.failAfter > 0 && len(w.rows) >= w.failAfter {
+		return errors.New("client went away")
+	}
+	return nil
+}
+
+// countQuery selects the numbers 1 to n
+func countQuery(n int) string {
+	return fmt.Sprintf("WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < %d) SELECT i, 'row ' || i AS label FROM n", n)
+}
+
+func TestStreamQuery(t *testing.T) {
+	d, id := newTestSQLite(t)
+	ctx := context.Background()
+	total := 2*rowBatchSize + 5
+
+	tests := []struct {
+		name      string
+		maxRows   int
+		failAfter int
+		rows      int
+		batches   []int
+		truncated bool
+		failed    bool
+	}{
+		{"every row", 0, 0, total, []int{rowBatchSize, rowBatchSize, 5}, false, false},
+		{"under the cap", total + 1, 0, total, []int{rowBatchSize, rowBatchSize, 5}, false, false},
+		{"capped", rowBatchSize + 1, 0, rowBatchSize + 1, []int{rowBatchSize, 1}, true, false},
+		{"writer fails", 0, 1, rowBatchSize, []int{rowBatchSize}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &recordingWriter{failAfter: tt.failAfter}
+			trailer, err := d.StreamQuery(ctx, id, "", countQuery(total), tt.maxRows, w)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.headers != 1 || len(w.columns) != 2 || w.columns[0].Name != "i" || w.columns[1].Name != "label" {
+				t.Errorf("headers = %d with columns %+v, want one with i and label", w.headers, w.columns)
+			}
+			if fmt.Sprint(w.batches) != fmt.Sprint(tt.batches) {
+				t.Errorf("batches = %v, want %v", w.batches, tt.batches)
+			}
+			if trailer.RowCount != tt.rows || len(w.rows) != tt.rows || trailer.Truncated != tt.truncated {
+				t.Errorf("trailer = %+v with %d rows written, want %d rows, truncated %v", trailer, len(w.rows), tt.rows, tt.truncated)
+			}
+			if (trailer.Error != "") != tt.failed {
+				t.Errorf("trailer error = %q, want failed %v", trailer.Error, tt.failed)
+			}
+			if len(w.rows) > 0 && (w.rows[0][0] != int64(1) || w.rows[0][1] != "row 1") {
+				t.Errorf("first row = %v, want [1 row 1]", w.rows[0])
+			}
+		})
+	}
+}
+
+func TestStreamQueryErrors(t *testing.T) {
+	d, id := newTestSQLite(t)
+	ctx := context.Background()
+
+	w := &recordingWriter{}
+	trailer, err := d.StreamQuery(ctx, id, "", "SELECT * FROM missing", 0, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trailer.Error == "" || w.headers != 0 || len(w.rows) != 0 {
+		t.Errorf("unknown table = %+v after %d headers, want an error and no rows", trailer, w.headers)
+	}
+
+	if _, err := d.StreamQuery(ctx, "unknown", "", "SELECT 1", 0, w); err == nil {
+		t.Error("streaming from an unknown connection succeeded")
+	}
+}
+
+func TestExecuteQueryTruncated(t *testing.T) {
+	d, id := newTestSQLite(t)
+	result, err := d.ExecuteQuery(context.Background(), id, "", countQuery(MaxQueryRows+10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" || !result.Truncated || result.RowCount != MaxQueryRows || len(result.Rows) != MaxQueryRows {
+		t.Errorf("result has %d rows, truncated %v, error %q; want %d truncated", result.RowCount, result.Truncated, result.Error, MaxQueryRows)
+	}
+}
@@ -0,0 +1,579 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"opendbm/internal/models"
+)
+
+// DefaultPageSize is the number of table rows in a page unless asked
+// otherwise
+const DefaultPageSize = 100
+
+// ErrInvalidRequest is matched by errors in what a request asks for, such
+// as the table name, paging, sort, filter or cursor of a table data request
+// or the batches of a script
+var ErrInvalidRequest = errors.New("invalid request")
+
+// invalidRequest keeps the message of an error while matching
+// ErrInvalidRequest
+type invalidRequest struct {
+	error
+}
+
+func (invalidRequest) Is(target error) bool {
+	return target == ErrInvalidRequest
+}
+
+// GetTableData returns one page of a table's rows, filtered and sorted as
+// asked. Tables with a primary key are always read in a stable order, with
+// the key breaking ties. When the sort uses key columns only, pages are
+// read by keyset pagination: each page carries a cursor that the next one
+// starts after, so deep pages cost no more than the first.
+func (d *SQLDriverImpl) GetTableData(ctx context.Context, id string, database string, table string, req models.TableDataRequest) (*models.TablePage, error) {
+	_, dbType, err := d.pool(id)
+	if err != nil {
+		return nil, err
+	}
+	t, err := resolveTable(dbType, database, table)
+	if err != nil {
+		return nil, invalidRequest{err}
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = DefaultPageSize
+	}
+	if req.PageSize >= MaxQueryRows {
+		req.PageSize = MaxQueryRows - 1
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	switch req.Count {
+	case "", "exact", "estimate":
+	default:
+		return nil, invalidRequest{fmt.Errorf("count must be exact or estimate")}
+	}
+
+	// Without a key the order is whatever the sort gives; a missing key is
+	// no error, as the data query reports unknown tables itself
+	key, _ := d.primaryKey(id, dbType, t)
+	order, keyset, err := tableOrder(req.Sort, key)
+	if err != nil {
+		return nil, invalidRequest{err}
+	}
+	if req.After != "" && !keyset {
+		return nil, invalidRequest{fmt.Errorf("cursors need a table with a primary key, sorted by key columns only")}
+	}
+
+	b := &sqlBuilder{dialect: dialect(dbType)}
+	b.write("SELECT * FROM ", b.dialect.qualify(t))
+	where, err := tableWhere(b, req.Filter)
+	if err != nil {
+		return nil, invalidRequest{err}
+	}
+	if req.After != "" {
+		values, err := decodeCursor(req.After, len(order))
+		if err != nil {
+			return nil, invalidRequest{err}
+		}
+		if where {
+			b.write(" AND ")
+		} else {
+			b.write(" WHERE ")
+		}
+		b.keysetCondition(order, values)
+	}
+
+	b.orderBy(order)
+	limit := req.PageSize
+	offset := (req.Page - 1) * req.PageSize
+	if keyset {
+		// One extra row tells whether another page follows
+		limit++
+	}
+	if req.After != "" {
+		// The cursor already skips the rows of earlier pages
+		offset = 0
+	}
+	b.paginate(limit, offset)
+
+	result, err := d.ExecuteQuery(ctx, id, "", b.String(), b.args...)
+	if err != nil {
+		return nil, err
+	}
+	page := &models.TablePage{ResultSet: *result}
+	if page.Error != "" {
+		return page, nil
+	}
+
+	if keyset && len(page.Rows) > req.PageSize {
+		page.Rows = page.Rows[:req.PageSize]
+		page.RowCount = req.PageSize
+		page.NextCursor = encodeCursor(page.Columns, page.Rows[len(page.Rows)-1], order)
+	}
+
+	if req.Count != "" {
+		count, estimated, err := d.countRows(ctx, id, dbType, t, req.Filter, req.Count == "estimate")
+		if err != nil {
+			page.Error = "failed to count rows: " + err.Error()
+		} else {
+			page.TotalCount = &count
+			page.CountEstimated = estimated
+		}
+	}
+	return page, nil
+}
+
+// orderColumn is a column rows are ordered by
+type orderColumn struct {
+	name string
+	desc bool
+}
+
+// tableOrder returns the order of a page: the sort, followed by the key
+// columns it leaves out. Keyset pagination applies when the sort only uses
+// key columns, which are never NULL.
+func tableOrder(sort []models.TableSort, key []string) ([]orderColumn, bool, error) {
+	isKey := make(map[string]bool, len(key))
+	for _, column := range key {
+		isKey[column] = true
+	}
+
+	var order []orderColumn
+	seen := make(map[string]bool)
+	keyset := len(key) > 0
+	for _, s := range sort {
+		if s.Column == "" {
+			return nil, false, fmt.Errorf("sort needs a column")
+		}
+		var desc bool
+		switch strings.ToLower(s.Direction) {
+		case "", "asc":
+		case "desc":
+			desc = true
+		default:
+			return nil, false, fmt.Errorf("sort direction must be asc or desc")
+		}
+		if seen[s.Column] {
+			continue
+		}
+		seen[s.Column] = true
+		order = append(order, orderColumn{name: s.Column, desc: desc})
+		keyset = keyset && isKey[s.Column]
+	}
+	for _, column := range key {
+		if !seen[column] {
+			order = append(order, orderColumn{name: column})
+		}
+	}
+	return order, keyset, nil
+}
+
+// tableWhere writes the WHERE clause of a filter, reporting whether there
+// was one
+func tableWhere(b *sqlBuilder, filter *models.TableFilter) (bool, error) {
+	if filter == nil || filter.Column == "" && len(filter.And) == 0 && len(filter.Or) == 0 {
+		return false, nil
+	}
+	b.write(" WHERE ")
+	return true, b.filter(*filter)
+}
+
+// countRows counts the rows matching a filter. An estimate comes from the
+// catalog's statistics for a whole table, and from the planner for a
+// filtered PostgreSQL table; otherwise the rows are counted.
+func (d *SQLDriverImpl) countRows(ctx context.Context, id string, dbType string, t tableName, filter *models.TableFilter, estimate bool) (int64, bool, error) {
+	b := &sqlBuilder{dialect: dialect(dbType)}
+	from := b.dialect.qualify(t)
+	filtered, err := tableWhere(b, filter)
+	if err != nil {
+		return 0, false, err
+	}
+	where := b.String()
+
+	if estimate && !filtered {
+		if n, ok := d.tableRowEstimate(ctx, id, dbType, t); ok {
+			return n, true, nil
+		}
+	}
+	if estimate && filtered && dbType == "postgres" {
+		plan, err := d.Explain(ctx, id, "", "SELECT 1 FROM "+from+where, false, b.args...)
+		if err == nil && plan.Error == "" && len(plan.Plans) > 0 && plan.Plans[0].EstimatedRows != nil {
+			return int64(*plan.Plans[0].EstimatedRows), true, nil
+		}
+	}
+
+	result, err := d.ExecuteQuery(ctx, id, "", "SELECT COUNT(*) FROM "+from+where, b.args...)
+	if err != nil {
+		return 0, false, err
+	}
+	if result.Error != "" {
+		return 0, false, fmt.Errorf("%s", result.Error)
+	}
+	n, ok := firstInt(result)
+	if !ok {
+		return 0, false, fmt.Errorf("no count returned")
+	}
+	return n, false, nil
+}
+
+// tableRowEstimate reads a table's row count from the statistics the
+// database keeps for its planner. SQLite keeps none.
+func (d *SQLDriverImpl) tableRowEstimate(ctx context.Context, id string, dbType string, t tableName) (int64, bool) {
+	var query string
+	var args []interface{}
+	switch dbType {
+	case "postgres":
+		query = "SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass($1)"
+		args = append(args, dialect(dbType).qualify(t))
+	case "mysql":
+		query = "SELECT table_rows FROM information_schema.tables WHERE table_schema = COALESCE(?, DATABASE()) AND table_name = ?"
+		args = append(args, t.schemaArg(), t.name)
+	case "sqlserver":
+		query = "SELECT SUM(rows) FROM sys.partitions WHERE object_id = OBJECT_ID(@p1) AND index_id IN (0, 1)"
+		args = append(args, dialect(dbType).qualify(t))
+	case "oracle":
+		query = "SELECT num_rows FROM all_tables WHERE owner = NVL(:1, SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA')) AND table_name = :2"
+		args = append(args, t.schemaArg(), t.name)
+	default:
+		return 0, false
+	}
+
+	result, err := d.ExecuteQuery(ctx, id, "", query, args...)
+	if err != nil || result.Error != "" {
+		return 0, false
+	}
+	n, ok := firstInt(result)
+	// PostgreSQL reports -1 for tables never analyzed
+	return n, ok && n >= 0
+}
+
+// primaryKey returns the primary key columns of a table in key order
+func (d *SQLDriverImpl) primaryKey(id string, dbType string, t tableName) ([]string, error) {
+	var query string
+	var args []interface{}
+	switch dbType {
+	case "postgres":
+		query = `SELECT a.attname AS name FROM pg_index i
+			JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+			WHERE i.indrelid = to_regclass(?) AND i.indisprimary
+			ORDER BY array_position(i.indkey::int2[], a.attnum)`
+		args = append(args, dialect(dbType).qualify(t))
+	case "mysql":
+		query = `SELECT column_name AS name FROM information_schema.key_column_usage
+			WHERE table_schema = COALESCE(?, DATABASE()) AND table_name = ? AND constraint_name = 'PRIMARY'
+			ORDER BY ordinal_position`
+		args = append(args, t.schemaArg(), t.name)
+	case "sqlite":
+		query = "SELECT name FROM pragma_table_info(?, COALESCE(?, 'main')) WHERE pk > 0 ORDER BY pk"
+		args = append(args, t.name, t.schemaArg())
+	case "sqlserver":
+		query = `SELECT c.name AS name FROM sys.indexes i
+			JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+			JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+			WHERE i.is_primary_key = 1 AND i.object_id = OBJECT_ID(?)
+			ORDER BY ic.key_ordinal`
+		args = append(args, dialect(dbType).qualify(t))
+	case "oracle":
+		query = `SELECT cc.column_name AS name FROM all_constraints k
+			JOIN all_cons_columns cc ON cc.owner = k.owner AND cc.constraint_name = k.constraint_name
+			WHERE k.constraint_type = 'P' AND k.owner = NVL(?, SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA')) AND k.table_name = ?
+			ORDER BY cc.position`
+		args = append(args, t.schemaArg(), t.name)
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", dbType)
+	}
+
+	result, err := d.query(id, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
+
+	var columns []string
+	for _, row := range result.Rows {
+		for _, v := range row {
+			if name, ok := v.(string); ok {
+				columns = append(columns, name)
+			}
+		}
+	}
+	return columns, nil
+}
+
+// sqlBuilder writes a statement, binding values to the dialect's
+// placeholders
+type sqlBuilder struct {
+	dialect dialect
+	sql     strings.Builder
+	args    []interface{}
+}
+
+func (b *sqlBuilder) String() string {
+	return b.sql.String()
+}
+
+func (b *sqlBuilder) write(parts ...string) {
+	for _, part := range parts {
+		b.sql.WriteString(part)
+	}
+}
+
+func (b *sqlBuilder) ident(name string) {
+	b.write(b.dialect.quote(name))
+}
+
+// bind writes a placeholder for v
+func (b *sqlBuilder) bind(v interface{}) {
+	b.args = append(b.args, v)
+	b.write(b.dialect.placeholder(len(b.args)))
+}
+
+// filterOperators maps the comparison operators of a TableFilter to SQL
+var filterOperators = map[string]string{
+	"eq":       " = ",
+	"ne":       " <> ",
+	"lt":       " < ",
+	"le":       " <= ",
+	"gt":       " > ",
+	"ge":       " >= ",
+	"like":     " LIKE ",
+	"not_like": " NOT LIKE ",
+}
+
+// filter writes the condition of a filter node
+func (b *sqlBuilder) filter(f models.TableFilter) error {
+	switch {
+	case len(f.And) > 0:
+		return b.filterGroup(f.And, " AND ")
+	case len(f.Or) > 0:
+		return b.filterGroup(f.Or, " OR ")
+	case f.Column == "":
+		return fmt.Errorf("a filter needs a column, or filters to combine with and or or")
+	}
+
+	switch f.Op {
+	case "is_null":
+		b.ident(f.Column)
+		b.write(" IS NULL")
+		return nil
+	case "is_not_null":
+		b.ident(f.Column)
+		b.write(" IS NOT NULL")
+		return nil
+	case "in", "not_in":
+		values, err := filterValues(f)
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			// Nothing is in an empty list
+			if f.Op == "in" {
+				b.write("1 = 0")
+			} else {
+				b.write("1 = 1")
+			}
+			return nil
+		}
+		b.ident(f.Column)
+		if f.Op == "in" {
+			b.write(" IN (")
+		} else {
+			b.write(" NOT IN (")
+		}
+		for i, v := range values {
+			if i > 0 {
+				b.write(", ")
+			}
+			b.bind(v)
+		}
+		b.write(")")
+		return nil
+	case "between":
+		values, err := filterValues(f)
+		if err != nil {
+			return err
+		}
+		if len(values) != 2 {
+			return fmt.Errorf("between on %s needs [low, high]", f.Column)
+		}
+		b.ident(f.Column)
+		b.write(" BETWEEN ")
+		b.bind(values[0])
+		b.write(" AND ")
+		b.bind(values[1])
+		return nil
+	}
+
+	op, ok := filterOperators[f.Op]
+	if !ok {
+		return fmt.Errorf("unknown filter operator %q", f.Op)
+	}
+	v, err := paramValue(models.QueryParam{Type: f.Type, Value: f.Value})
+	if err != nil {
+		return fmt.Errorf("filter on %s: %w", f.Column, err)
+	}
+	if v == nil {
+		return fmt.Errorf("filter on %s compares with null; use is_null or is_not_null", f.Column)
+	}
+	b.ident(f.Column)
+	b.write(op)
+	b.bind(v)
+	return nil
+}
+
+func (b *sqlBuilder) filterGroup(filters []models.TableFilter, join string) error {
+	b.write("(")
+	for i, f := range filters {
+		if i > 0 {
+			b.write(join)
+		}
+		if err := b.filter(f); err != nil {
+			return err
+		}
+	}
+	b.write(")")
+	return nil
+}
+
+// filterValues converts the array value of an in or between filter
+func filterValues(f models.TableFilter) ([]interface{}, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(f.Value, &raw); err != nil {
+		return nil, fmt.Errorf("%s on %s needs an array of values", f.Op, f.Column)
+	}
+	values := make([]interface{}, len(raw))
+	for i, r := range raw {
+		v, err := paramValue(models.QueryParam{Type: f.Type, Value: r})
+		if err != nil {
+			return nil, fmt.Errorf("filter on %s: %w", f.Column, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// keysetCondition writes the condition for rows after the given values of
+// the order columns: (c1 > v1) OR (c1 = v1 AND c2 > v2) OR ...
+func (b *sqlBuilder) keysetCondition(order []orderColumn, values []interface{}) {
+	b.write("(")
+	for i := range order {
+		if i > 0 {
+			b.write(" OR ")
+		}
+		b.write("(")
+		for j := 0; j < i; j++ {
+			b.ident(order[j].name)
+			b.write(" = ")
+			b.bind(values[j])
+			b.write(" AND ")
+		}
+		b.ident(order[i].name)
+		if order[i].desc {
+			b.write(" < ")
+		} else {
+			b.write(" > ")
+		}
+		b.bind(values[i])
+		b.write(")")
+	}
+	b.write(")")
+}
+
+func (b *sqlBuilder) orderBy(order []orderColumn) {
+	if len(order) == 0 {
+		if b.dialect == "sqlserver" {
+			// OFFSET ... FETCH requires an ORDER BY in T-SQL
+			b.write(" ORDER BY (SELECT NULL)")
+		}
+		return
+	}
+	b.write(" ORDER BY ")
+	for i, column := range order {
+		if i > 0 {
+			b.write(", ")
+		}
+		b.ident(column.name)
+		if column.desc {
+			b.write(" DESC")
+		}
+	}
+}
+
+func (b *sqlBuilder) paginate(limit int, offset int) {
+	switch b.dialect {
+	case "sqlserver", "oracle":
+		b.write(fmt.Sprintf(" OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", offset, limit))
+	default:
+		b.write(fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset))
+	}
+}
+
+// encodeCursor records the order column values of the last row of a page
+func encodeCursor(columns []models.ResultColumn, row []interface{}, order []orderColumn) string {
+	values := make([]interface{}, len(order))
+	for i, column := range order {
+		index := -1
+		for j, c := range columns {
+			if c.Name == column.name || index < 0 && strings.EqualFold(c.Name, column.name) {
+				index = j
+			}
+		}
+		if index < 0 {
+			return ""
+		}
+		values[i] = row[index]
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads the values of a cursor back into bindable values
+func decodeCursor(cursor string, columns int) ([]interface{}, error) {
+	invalid := fmt.Errorf("invalid cursor")
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil || len(raw) != columns {
+		return nil, invalid
+	}
+	values := make([]interface{}, len(raw))
+	for i, r := range raw {
+		v, err := paramValue(models.QueryParam{Value: r})
+		if err != nil || v == nil {
+			return nil, invalid
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// firstInt reads an integer from the first cell of a result
+func firstInt(result *models.ResultSet) (int64, bool) {
+	if len(result.Rows) == 0 || len(result.Rows[0]) == 0 {
+		return 0, false
+	}
+	switch v := result.Rows[0][0].(type) {
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return int64(n), err == nil
+	}
+	return 0, false
+}
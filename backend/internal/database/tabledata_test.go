@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"opendbm/internal/models"
+)
+
+func TestTableFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		dbType   string
+		filter   string
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  bool
+	}{
+		{
+			"comparison", "postgres", `{"column": "a", "op": "ge", "value": 2}`,
+			`"a" >= $1`, []interface{}{int64(2)}, false,
+		},
+		{
+			"quoted column", "mysql", "{\"column\": \"we`ird\", \"op\": \"like\", \"value\": \"x%\"}",
+			"`we``ird` LIKE ?", []interface{}{"x%"}, false,
+		},
+		{
+			"groups", "sqlserver",
+			`{"or": [{"column": "a", "op": "is_null"}, {"and": [{"column": "a", "op": "in", "value": [1, 2]}, {"column": "b", "op": "ne", "value": "x", "type": "string"}]}]}`,
+			`([a] IS NULL OR ([a] IN (@p1, @p2) AND [b] <> @p3))`, []interface{}{int64(1), int64(2), "x"}, false,
+		},
+		{
+			"between", "oracle", `{"column": "a", "op": "between", "value": ["2024-01-01", "2025-01-01"]}`,
+			`"a" BETWEEN :1 AND :2`, []interface{}{"2024-01-01", "2025-01-01"}, false,
+		},
+		{
+			"empty in", "sqlite", `{"column": "a", "op": "in", "value": []}`,
+			"1 = 0", nil, false,
+		},
+		{
+			"empty not in", "sqlite", `{"column": "a", "op": "not_in", "value": []}`,
+			"1 = 1", nil, false,
+		},
+		{"no column", "sqlite", `{"op": "eq", "value": 1}`, "", nil, true},
+		{"unknown operator", "sqlite", `{"column": "a", "op": "~", "value": 1}`, "", nil, true},
+		{"null comparison", "sqlite", `{"column": "a", "op": "eq", "value": null}`, "", nil, true},
+		{"in without array", "sqlite", `{"column": "a", "op": "in", "value": 1}`, "", nil, true},
+		{"between one value", "sqlite", `{"column": "a", "op": "between", "value": [1]}`, "", nil, true},
+		{"bad type", "sqlite", `{"column": "a", "op": "eq", "value": "x", "type": "integer"}`, "", nil, true},
+		{"bad nested filter", "sqlite", `{"and": [{"column": "a", "op": "is_null"}, {"column": "b"}]}`, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f models.TableFilter
+			if err := json.Unmarshal([]byte(tt.filter), &f); err != nil {
+				t.Fatal(err)
+			}
+			b := &sqlBuilder{dialect: dialect(tt.dbType)}
+			err := b.filter(f)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %q, want an error", b.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.wantSQL || !reflect.DeepEqual(b.args, tt.wantArgs) {
+				t.Errorf("got %q %#v, want %q %#v", b.String(), b.args, tt.wantSQL, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	b := &sqlBuilder{dialect: "postgres"}
+	b.keysetCondition([]orderColumn{{name: "a", desc: true}, {name: "b"}}, []interface{}{int64(1), "x"})
+	want := `(("a" < $1) OR ("a" = $2 AND "b" > $3))`
+	if b.String() != want || !reflect.DeepEqual(b.args, []interface{}{int64(1), int64(1), "x"}) {
+		t.Errorf("got %q %#v, want %q", b.String(), b.args, want)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	columns := []models.ResultColumn{{Name: "ID"}, {Name: "name"}, {Name: "score"}}
+	order := []orderColumn{{name: "score", desc: true}, {name: "id"}}
+	cursor := encodeCursor(columns, []interface{}{int64(7), "x", 1.5}, order)
+	if cursor == "" {
+		t.Fatal("no cursor")
+	}
+	values, err := decodeCursor(cursor, len(order))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{1.5, int64(7)}; !reflect.DeepEqual(values, want) {
+		t.Errorf("decoded %#v, want %#v", values, want)
+	}
+
+	if got := encodeCursor(columns, []interface{}{int64(7), "x", 1.5}, []orderColumn{{name: "missing"}}); got != "" {
+		t.Errorf("cursor for a column not in the page = %q, want none", got)
+	}
+
+	for _, bad := range []string{"not base64!", "bm90IGpzb24", "WzFd", "WzEsbnVsbF0"} {
+		if _, err := decodeCursor(bad, 2); err == nil {
+			t.Errorf("decodeCursor(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestGetTableDataKeyset(t *testing.T) {
+	d, id := newTestSQLite(t)
+	ctx := context.Background()
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)",
+		"INSERT INTO t VALUES (1, 'a'), (2, 'b'), (3, 'c'), (4, 'd'), (5, 'e')",
+	} {
+		if result, err := d.ExecuteSQL(ctx, id, "", stmt); err != nil || result.Error != "" {
+			t.Fatal(err, result.Error)
+		}
+	}
+
+	req := models.TableDataRequest{
+		PageSize: 2,
+		Sort:     []models.TableSort{{Column: "id", Direction: "desc"}},
+		Filter:   &models.TableFilter{Column: "id", Op: "ne", Value: json.RawMessage("3")},
+		Count:    "exact",
+	}
+	var ids []interface{}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("keyset pagination did not end")
+		}
+		page, err := d.GetTableData(ctx, id, "", "t", req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Error != "" {
+			t.Fatal(page.Error)
+		}
+		if page.TotalCount == nil || *page.TotalCount != 4 {
+			t.Errorf("total count = %v, want 4", page.TotalCount)
+		}
+		for _, row := range page.Rows {
+			ids = append(ids, row[0])
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		req.After = page.NextCursor
+	}
+	if want := []interface{}{int64(5), int64(4), int64(2), int64(1)}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("read ids %v, want %v", ids, want)
+	}
+
+	// A page number without a cursor still skips the earlier pages
+	page, err := d.GetTableData(ctx, id, "", "t", models.TableDataRequest{Page: 2, PageSize: 2})
+	if err != nil || page.Error != "" {
+		t.Fatal(err, page.Error)
+	}
+	if len(page.Rows) != 2 || page.Rows[0][0] != int64(3) || page.Rows[1][0] != int64(4) || page.NextCursor == "" {
+		t.Errorf("page 2 = %v with cursor %q, want ids 3 and 4 and a cursor", page.Rows, page.NextCursor)
+	}
+	req = models.TableDataRequest{PageSize: 2, After: page.NextCursor}
+	if page, err = d.GetTableData(ctx, id, "", "t", req); err != nil || len(page.Rows) != 1 || page.Rows[0][0] != int64(5) {
+		t.Errorf("page after page 2 = %v, %v, want id 5", page, err)
+	}
+
+	invalid := []models.TableDataRequest{
+		{Count: "some"},
+		{Sort: []models.TableSort{{Column: "id", Direction: "up"}}},
+		{Sort: []models.TableSort{{Column: "name"}}, After: req.After},
+		{After: "garbage"},
+		{Filter: &models.TableFilter{Column: "id", Op: "near"}},
+	}
+	for _, r := range invalid {
+		if _, err := d.GetTableData(ctx, id, "", "t", r); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("GetTableData(%+v) = %v, want ErrInvalidRequest", r, err)
+		}
+	}
+	if _, err := d.GetTableData(ctx, id, "", `"t`, models.TableDataRequest{}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("unterminated table name = %v, want ErrInvalidRequest", err)
+	}
+
+	// A table that does not exist is an execution error, not a bad request
+	page, err = d.GetTableData(ctx, id, "", "missing", models.TableDataRequest{})
+	if err != nil || page.Error == "" {
+		t.Errorf("missing table = %v, %v, want an error in the page", page, err)
+	}
+}
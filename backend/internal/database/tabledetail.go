@@ -0,0 +1,730 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"opendbm/internal/models"
+)
+
+// GetTableDetail returns a table's columns, indexes, constraints and
+// foreign keys. The table is a name or schema.name; without a schema the
+// current one is used, or for MySQL and Oracle the database.
+func (d *SQLDriverImpl) GetTableDetail(id string, database string, table string) (*models.TableDetail, error) {
+	_, dbType, err := d.pool(id)
+	if err != nil {
+		return nil, err
+	}
+	t, err := resolveTable(dbType, database, table)
+	if err != nil {
+		return nil, err
+	}
+
+	var detail *models.TableDetail
+	switch dbType {
+	case "sqlite":
+		detail, err = d.sqliteTableDetail(id, t)
+	case "postgres", "mysql", "sqlserver", "oracle":
+		detail, err = d.catalogTableDetail(id, dbType, t)
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", dbType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Key membership of columns follows from the constraints
+	primary := map[string]bool{}
+	for _, c := range detail.Constraints {
+		if c.Type == "primary_key" {
+			for _, column := range c.Columns {
+				primary[column] = true
+			}
+		}
+	}
+	foreign := map[string]bool{}
+	for _, fk := range detail.ForeignKeys {
+		for _, column := range fk.Columns {
+			foreign[column] = true
+		}
+	}
+	for i := range detail.Columns {
+		detail.Columns[i].IsPrimaryKey = primary[detail.Columns[i].Name]
+		detail.Columns[i].IsForeignKey = foreign[detail.Columns[i].Name]
+	}
+	return detail, nil
+}
+
+// tableDetailQueries are the catalog queries describing a table, each
+// taking the same arguments. Rows are one per column of the object they
+// describe, in order, with the names below.
+type tableDetailQueries struct {
+	// name, type, nullable, default_value, comment, identity,
+	// table_schema and table_comment
+	columns string
+	// name, is_unique, is_primary, method, predicate and column_name
+	indexes string
+	// name, type (primary_key, unique or check), definition and
+	// column_name
+	constraints string
+	// name, definition: check conditions, where constraints lacks them
+	checks string
+	// name, column_name, ref_schema, ref_table, ref_column, on_delete and
+	// on_update
+	foreignKeys string
+	args        []interface{}
+	// columnType formats the type of a column row, when its type column
+	// does not hold it whole
+	columnType func(row map[string]interface{}) string
+}
+
+func (d *SQLDriverImpl) catalogTableDetail(id string, dbType string, t tableName) (*models.TableDetail, error) {
+	q := detailQueries(dbType, t)
+
+	columns, err := d.catalogRows(id, q.columns, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, ErrObjectNotFound
+	}
+	detail := &models.TableDetail{
+		Name:        t.name,
+		Schema:      catalogString(columns[0]["table_schema"]),
+		Comment:     catalogString(columns[0]["table_comment"]),
+		Columns:     []models.ColumnInfo{},
+		Indexes:     []models.IndexInfo{},
+		Constraints: []models.ConstraintInfo{},
+		ForeignKeys: []models.ForeignKeyInfo{},
+	}
+	for _, row := range columns {
+		col := models.ColumnInfo{
+			Name:         catalogString(row["name"]),
+			Type:         catalogString(row["type"]),
+			Nullable:     catalogBool(row["nullable"]),
+			DefaultValue: strings.TrimSpace(catalogString(row["default_value"])),
+			IsIdentity:   catalogBool(row["identity"]),
+			Comment:      catalogString(row["comment"]),
+		}
+		if q.columnType != nil {
+			col.Type = q.columnType(row)
+		}
+		detail.Columns = append(detail.Columns, col)
+	}
+
+	rows, err := d.catalogRows(id, q.indexes, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		name := catalogString(row["name"])
+		n := len(detail.Indexes)
+		if n == 0 || detail.Indexes[n-1].Name != name {
+			detail.Indexes = append(detail.Indexes, models.IndexInfo{
+				Name:      name,
+				Columns:   []string{},
+				Unique:    catalogBool(row["is_unique"]),
+				Primary:   catalogBool(row["is_primary"]),
+				Method:    strings.ToLower(catalogString(row["method"])),
+				Predicate: catalogString(row["predicate"]),
+			})
+			n++
+		}
+		if column := catalogString(row["column_name"]); column != "" {
+			detail.Indexes[n-1].Columns = append(detail.Indexes[n-1].Columns, column)
+		}
+	}
+
+	rows, err = d.catalogRows(id, q.constraints, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	checks := map[string]string{}
+	if q.checks != "" {
+		// Servers too old for check constraints have nowhere to list them
+		if checkRows, err := d.catalogRows(id, q.checks, q.args...); err == nil {
+			for _, row := range checkRows {
+				checks[catalogString(row["name"])] = catalogString(row["definition"])
+			}
+		}
+	}
+	for _, row := range rows {
+		name := catalogString(row["name"])
+		kind := catalogString(row["type"])
+		definition := catalogString(row["definition"])
+		if kind == "check" && definition == "" {
+			definition = checks[name]
+		}
+		if kind == "check" && notNullCheck.MatchString(definition) {
+			// Oracle keeps NOT NULL columns as check constraints
+			continue
+		}
+		n := len(detail.Constraints)
+		if n == 0 || detail.Constraints[n-1].Name != name {
+			constraint := models.ConstraintInfo{Name: name, Type: kind}
+			if kind == "check" {
+				constraint.Expression = definition
+			}
+			detail.Constraints = append(detail.Constraints, constraint)
+			n++
+		}
+		if column := catalogString(row["column_name"]); column != "" {
+			detail.Constraints[n-1].Columns = append(detail.Constraints[n-1].Columns, column)
+		}
+	}
+
+	rows, err = d.catalogRows(id, q.foreignKeys, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		name := catalogString(row["name"])
+		n := len(detail.ForeignKeys)
+		if n == 0 || detail.ForeignKeys[n-1].Name != name {
+			detail.ForeignKeys = append(detail.ForeignKeys, models.ForeignKeyInfo{
+				Name:              name,
+				Columns:           []string{},
+				ReferencedSchema:  catalogString(row["ref_schema"]),
+				ReferencedTable:   catalogString(row["ref_table"]),
+				ReferencedColumns: []string{},
+				OnDelete:          referentialAction(row["on_delete"]),
+				OnUpdate:          referentialAction(row["on_update"]),
+			})
+			n++
+		}
+		fk := &detail.ForeignKeys[n-1]
+		fk.Columns = append(fk.Columns, catalogString(row["column_name"]))
+		fk.ReferencedColumns = append(fk.ReferencedColumns, catalogString(row["ref_column"]))
+	}
+	return detail, nil
+}
+
+// notNullCheck matches the condition of a NOT NULL column kept as a check
+// constraint
+var notNullCheck = regexp.MustCompile(`^"[^"]+" IS NOT NULL$`)
+
+// detailQueries returns the catalog queries describing a table. PostgreSQL
+// and SQL Server find it by its qualified name, as the current schema is
+// searched when it has none.
+func detailQueries(dbType string, t tableName) tableDetailQueries {
+	switch dbType {
+	case "postgres":
+		return tableDetailQueries{
+			columns: `SELECT a.attname AS name, format_type(a.atttypid, a.atttypmod) AS type, NOT a.attnotnull AS nullable,
+					pg_get_expr(ad.adbin, ad.adrelid) AS default_value, col_description(a.attrelid, a.attnum) AS comment,
+					a.attidentity <> '' OR COALESCE(pg_get_expr(ad.adbin, ad.adrelid), '') LIKE 'nextval(%' AS identity,
+					n.nspname AS table_schema, obj_description(c.oid, 'pg_class') AS table_comment
+				FROM pg_attribute a
+				JOIN pg_class c ON c.oid = a.attrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+				LEFT JOIN pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+				WHERE a.attrelid = to_regclass(?) AND a.attnum > 0 AND NOT a.attisdropped
+				ORDER BY a.attnum`,
+			indexes: `SELECT i.relname AS name, x.indisunique AS is_unique, x.indisprimary AS is_primary, am.amname AS method,
+					pg_get_expr(x.indpred, x.indrelid) AS predicate,
+					COALESCE(a.attname, pg_get_indexdef(x.indexrelid, k.n::int, true)) AS column_name
+				FROM pg_index x
+				JOIN pg_class i ON i.oid = x.indexrelid JOIN pg_am am ON am.oid = i.relam
+				CROSS JOIN LATERAL unnest(x.indkey::int2[]) WITH ORDINALITY AS k(attnum, n)
+				LEFT JOIN pg_attribute a ON a.attrelid = x.indrelid AND a.attnum = k.attnum AND k.attnum > 0
+				WHERE x.indrelid = to_regclass(?) AND k.n <= x.indnkeyatts
+				ORDER BY i.relname, k.n`,
+			constraints: `SELECT con.conname AS name,
+					CASE con.contype WHEN 'p' THEN 'primary_key' WHEN 'u' THEN 'unique' ELSE 'check' END AS type,
+					CASE con.contype WHEN 'c' THEN pg_get_expr(con.conbin, con.conrelid, true) END AS definition,
+					a.attname AS column_name
+				FROM pg_constraint con
+				LEFT JOIN LATERAL unnest(con.conkey) WITH ORDINALITY AS k(attnum, n) ON true
+				LEFT JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				WHERE con.conrelid = to_regclass(?) AND con.contype IN ('p', 'u', 'c')
+				ORDER BY con.conname, k.n`,
+			foreignKeys: `SELECT con.conname AS name, a.attname AS column_name, rn.nspname AS ref_schema, rc.relname AS ref_table,
+					ra.attname AS ref_column, con.confdeltype::text AS on_delete, con.confupdtype::text AS on_update
+				FROM pg_constraint con
+				CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, refnum, n)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				JOIN pg_class rc ON rc.oid = con.confrelid JOIN pg_namespace rn ON rn.oid = rc.relnamespace
+				JOIN pg_attribute ra ON ra.attrelid = con.confrelid AND ra.attnum = k.refnum
+				WHERE con.conrelid = to_regclass(?) AND con.contype = 'f'
+				ORDER BY con.conname, k.n`,
+			args: []interface{}{dialect(dbType).qualify(t)},
+		}
+
+	case "mysql":
+		const table = "table_schema = COALESCE(?, DATABASE()) AND table_name = ?"
+		return tableDetailQueries{
+			columns: `SELECT c.column_name AS name, c.column_type AS type, c.is_nullable = 'YES' AS nullable,
+					c.column_default AS default_value, c.column_comment AS comment, c.extra LIKE '%auto_increment%' AS identity,
+					c.table_schema AS table_schema, t.table_comment AS table_comment
+				FROM information_schema.columns c
+				JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+				WHERE c.table_schema = COALESCE(?, DATABASE()) AND c.table_name = ? ORDER BY c.ordinal_position`,
+			indexes: `SELECT index_name AS name, non_unique = 0 AS is_unique, index_name = 'PRIMARY' AS is_primary,
+					index_type AS method, column_name AS column_name
+				FROM information_schema.statistics WHERE ` + table + ` ORDER BY index_name, seq_in_index`,
+			constraints: `SELECT tc.constraint_name AS name,
+					CASE tc.constraint_type WHEN 'PRIMARY KEY' THEN 'primary_key' WHEN 'UNIQUE' THEN 'unique' ELSE 'check' END AS type,
+					kcu.column_name AS column_name
+				FROM information_schema.table_constraints tc
+				LEFT JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = tc.constraint_schema
+					AND kcu.constraint_name = tc.constraint_name AND kcu.table_name = tc.table_name
+				WHERE tc.table_schema = COALESCE(?, DATABASE()) AND tc.table_name = ?
+					AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE', 'CHECK')
+				ORDER BY tc.constraint_name, kcu.ordinal_position`,
+			checks: `SELECT cc.constraint_name AS name, cc.check_clause AS definition
+				FROM information_schema.check_constraints cc
+				JOIN information_schema.table_constraints tc ON tc.constraint_schema = cc.constraint_schema
+					AND tc.constraint_name = cc.constraint_name
+				WHERE tc.table_schema = COALESCE(?, DATABASE()) AND tc.table_name = ? AND tc.constraint_type = 'CHECK'`,
+			foreignKeys: `SELECT kcu.constraint_name AS name, kcu.column_name AS column_name, kcu.referenced_table_schema AS ref_schema,
+					kcu.referenced_table_name AS ref_table, kcu.referenced_column_name AS ref_column,
+					rc.delete_rule AS on_delete, rc.update_rule AS on_update
+				FROM information_schema.key_column_usage kcu
+				JOIN information_schema.referential_constraints rc ON rc.constraint_schema = kcu.constraint_schema
+					AND rc.constraint_name = kcu.constraint_name
+				WHERE kcu.table_schema = COALESCE(?, DATABASE()) AND kcu.table_name = ? AND kcu.referenced_table_name IS NOT NULL
+				ORDER BY kcu.constraint_name, kcu.ordinal_position`,
+			args: []interface{}{t.schemaArg(), t.name},
+		}
+
+	case "sqlserver":
+		return tableDetailQueries{
+			columns: `SELECT c.name AS name, TYPE_NAME(c.user_type_id) AS type, c.max_length, c.precision, c.scale,
+					c.is_nullable AS nullable, OBJECT_DEFINITION(c.default_object_id) AS default_value,
+					CAST(ep.value AS nvarchar(max)) AS comment, c.is_identity AS [identity], SCHEMA_NAME(o.schema_id) AS table_schema,
+					(SELECT CAST(tp.value AS nvarchar(max)) FROM sys.extended_properties tp WHERE tp.class = 1
+						AND tp.major_id = c.object_id AND tp.minor_id = 0 AND tp.name = 'MS_Description') AS table_comment
+				FROM sys.columns c
+				JOIN sys.objects o ON o.object_id = c.object_id
+				LEFT JOIN sys.extended_properties ep ON ep.class = 1 AND ep.major_id = c.object_id
+					AND ep.minor_id = c.column_id AND ep.name = 'MS_Description'
+				WHERE c.object_id = OBJECT_ID(?)
+				ORDER BY c.column_id`,
+			indexes: `SELECT i.name AS name, i.is_unique AS is_unique, i.is_primary_key AS is_primary, i.type_desc AS method,
+					i.filter_definition AS predicate, c.name AS column_name
+				FROM sys.indexes i
+				JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id AND ic.is_included_column = 0
+				JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+				WHERE i.object_id = OBJECT_ID(?) AND i.type > 0
+				ORDER BY i.name, ic.key_ordinal`,
+			constraints: `SELECT k.name, k.type, k.definition, k.column_name
+				FROM (SELECT OBJECT_ID(?) AS id) t
+				CROSS APPLY (
+					SELECT kc.name AS name, CASE kc.type WHEN 'PK' THEN 'primary_key' ELSE 'unique' END AS type,
+						NULL AS definition, c.name AS column_name, ic.key_ordinal AS position
+					FROM sys.key_constraints kc
+					JOIN sys.index_columns ic ON ic.object_id = kc.parent_object_id AND ic.index_id = kc.unique_index_id
+					JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+					WHERE kc.parent_object_id = t.id
+					UNION ALL
+					SELECT cc.name, 'check', cc.definition, COL_NAME(cc.parent_object_id, NULLIF(cc.parent_column_id, 0)), 0
+					FROM sys.check_constraints cc
+					WHERE cc.parent_object_id = t.id
+				) k
+				ORDER BY k.name, k.position`,
+			foreignKeys: `SELECT fk.name AS name, pc.name AS column_name, SCHEMA_NAME(rt.schema_id) AS ref_schema, rt.name AS ref_table,
+					rc.name AS ref_column, fk.delete_referential_action_desc AS on_delete, fk.update_referential_action_desc AS on_update
+				FROM sys.foreign_keys fk
+				JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+				JOIN sys.columns pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+				JOIN sys.objects rt ON rt.object_id = fkc.referenced_object_id
+				JOIN sys.columns rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+				WHERE fk.parent_object_id = OBJECT_ID(?)
+				ORDER BY fk.name, fkc.constraint_column_id`,
+			args:       []interface{}{dialect(dbType).qualify(t)},
+			columnType: sqlServerColumnType,
+		}
+	}
+
+	// Oracle
+	const owner = "NVL(?, SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA'))"
+	return tableDetailQueries{
+		columns: `SELECT c.column_name AS "name", c.data_type, c.data_length, c.char_length, c.data_precision, c.data_scale,
+				CASE c.nullable WHEN 'Y' THEN 1 ELSE 0 END AS "nullable", c.data_default AS "default_value",
+				cm.comments AS "comment", CASE c.identity_column WHEN 'YES' THEN 1 ELSE 0 END AS "identity",
+				c.owner AS "table_schema", (SELECT tc.comments FROM all_tab_comments tc
+					WHERE tc.owner = c.owner AND tc.table_name = c.table_name) AS "table_comment"
+			FROM all_tab_columns c
+			LEFT JOIN all_col_comments cm ON cm.owner = c.owner AND cm.table_name = c.table_name AND cm.column_name = c.column_name
+			WHERE c.owner = ` + owner + ` AND c.table_name = ?
+			ORDER BY c.column_id`,
+		indexes: `SELECT i.index_name AS "name", CASE i.uniqueness WHEN 'UNIQUE' THEN 1 ELSE 0 END AS "is_unique",
+				CASE WHEN EXISTS (SELECT 1 FROM all_constraints k WHERE k.owner = i.table_owner AND k.table_name = i.table_name
+					AND k.constraint_type = 'P' AND k.index_name = i.index_name) THEN 1 ELSE 0 END AS "is_primary",
+				i.index_type AS "method", ic.column_name AS "column_name"
+			FROM all_indexes i
+			JOIN all_ind_columns ic ON ic.index_owner = i.owner AND ic.index_name = i.index_name
+			WHERE i.table_owner = ` + owner + ` AND i.table_name = ?
+			ORDER BY i.index_name, ic.column_position`,
+		constraints: `SELECT k.constraint_name AS "name",
+				CASE k.constraint_type WHEN 'P' THEN 'primary_key' WHEN 'U' THEN 'unique' ELSE 'check' END AS "type",
+				k.search_condition AS "definition", cc.column_name AS "column_name"
+			FROM all_constraints k
+			LEFT JOIN all_cons_columns cc ON cc.owner = k.owner AND cc.constraint_name = k.constraint_name
+			WHERE k.owner = ` + owner + ` AND k.table_name = ? AND k.constraint_type IN ('P', 'U', 'C')
+			ORDER BY k.constraint_name, cc.position`,
+		// Oracle has no ON UPDATE actions
+		foreignKeys: `SELECT k.constraint_name AS "name", cc.column_name AS "column_name", r.owner AS "ref_schema",
+				r.table_name AS "ref_table", rc.column_name AS "ref_column", k.delete_rule AS "on_delete", 'NO ACTION' AS "on_update"
+			FROM all_constraints k
+			JOIN all_cons_columns cc ON cc.owner = k.owner AND cc.constraint_name = k.constraint_name
+			JOIN all_constraints r ON r.owner = k.r_owner AND r.constraint_name = k.r_constraint_name
+			JOIN all_cons_columns rc ON rc.owner = r.owner AND rc.constraint_name = r.constraint_name AND rc.position = cc.position
+			WHERE k.owner = ` + owner + ` AND k.table_name = ? AND k.constraint_type = 'R'
+			ORDER BY k.constraint_name, cc.position`,
+		args:       []interface{}{t.schemaArg(), t.name},
+		columnType: oracleColumnType,
+	}
+}
+
+// sqliteTableDetail reads a table's structure from SQLite's pragmas. Check
+// constraints are only kept in the table's CREATE statement, so they are
+// read from there.
+func (d *SQLDriverImpl) sqliteTableDetail(id string, t tableName) (*models.TableDetail, error) {
+	schema := t.schema
+	if schema == "" {
+		schema = "main"
+	}
+	master := dialect("sqlite").quote(schema) + ".sqlite_master"
+
+	columns, err := d.catalogRows(id, `SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?, ?) ORDER BY cid`, t.name, schema)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, ErrObjectNotFound
+	}
+	detail := &models.TableDetail{
+		Name:        t.name,
+		Schema:      schema,
+		Columns:     []models.ColumnInfo{},
+		Indexes:     []models.IndexInfo{},
+		Constraints: []models.ConstraintInfo{},
+		ForeignKeys: []models.ForeignKeyInfo{},
+	}
+
+	var createSQL string
+	if rows, err := d.catalogRows(id, "SELECT sql FROM "+master+" WHERE type = 'table' AND name = ?", t.name); err == nil && len(rows) > 0 {
+		createSQL = catalogString(rows[0]["sql"])
+	}
+
+	// Primary key columns in key order, by their pk position
+	key := map[int64]string{}
+	for _, row := range columns {
+		col := models.ColumnInfo{
+			Name:         catalogString(row["name"]),
+			Type:         catalogString(row["type"]),
+			Nullable:     !catalogBool(row["notnull"]),
+			DefaultValue: catalogString(row["dflt_value"]),
+		}
+		if pk, _ := catalogInt(row["pk"]); pk > 0 {
+			key[pk] = col.Name
+		}
+		detail.Columns = append(detail.Columns, col)
+	}
+	if len(key) > 0 {
+		pk := models.ConstraintInfo{Type: "primary_key"}
+		for i := int64(1); i <= int64(len(key)); i++ {
+			pk.Columns = append(pk.Columns, key[i])
+		}
+		detail.Constraints = append(detail.Constraints, pk)
+	}
+	// A single INTEGER PRIMARY KEY column is the rowid, which numbers rows
+	// itself
+	if len(key) == 1 {
+		for i := range detail.Columns {
+			if detail.Columns[i].Name == key[1] && strings.EqualFold(detail.Columns[i].Type, "INTEGER") {
+				detail.Columns[i].IsIdentity = true
+			}
+		}
+	}
+
+	indexes, err := d.catalogRows(id, `SELECT il.name AS name, il."unique" AS is_unique, il.origin AS origin, il.partial AS partial,
+			ii.name AS column_name, m.sql AS sql
+		FROM pragma_index_list(?, ?) il
+		JOIN pragma_index_info(il.name, ?) ii
+		LEFT JOIN `+master+` m ON m.type = 'index' AND m.name = il.name
+		ORDER BY il.seq DESC, ii.seqno`, t.name, schema, schema)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range indexes {
+		name := catalogString(row["name"])
+		origin := catalogString(row["origin"])
+		n := len(detail.Indexes)
+		if n == 0 || detail.Indexes[n-1].Name != name {
+			index := models.IndexInfo{
+				Name:    name,
+				Columns: []string{},
+				Unique:  catalogBool(row["is_unique"]),
+				Primary: origin == "pk",
+				Method:  "btree",
+			}
+			if catalogBool(row["partial"]) {
+				index.Predicate = sqlitePredicate(catalogString(row["sql"]))
+			}
+			detail.Indexes = append(detail.Indexes, index)
+			if origin == "u" {
+				detail.Constraints = append(detail.Constraints, models.ConstraintInfo{Type: "unique"})
+			}
+			n++
+		}
+		column := catalogString(row["column_name"])
+		if column == "" {
+			column = "(expression)"
+		}
+		detail.Indexes[n-1].Columns = append(detail.Indexes[n-1].Columns, column)
+		if origin == "u" {
+			c := &detail.Constraints[len(detail.Constraints)-1]
+			c.Columns = append(c.Columns, column)
+		}
+	}
+	detail.Constraints = append(detail.Constraints, sqliteChecks(createSQL)...)
+
+	fks, err := d.catalogRows(id, `SELECT id, "table" AS ref_table, "from" AS column_name, "to" AS ref_column, on_update, on_delete
+		FROM pragma_foreign_key_list(?, ?) ORDER BY id, seq`, t.name, schema)
+	if err != nil {
+		return nil, err
+	}
+	lastID := int64(-1)
+	for _, row := range fks {
+		fkID, _ := catalogInt(row["id"])
+		if fkID != lastID {
+			detail.ForeignKeys = append(detail.ForeignKeys, models.ForeignKeyInfo{
+				Columns:           []string{},
+				ReferencedTable:   catalogString(row["ref_table"]),
+				ReferencedColumns: []string{},
+				OnDelete:          referentialAction(row["on_delete"]),
+				OnUpdate:          referentialAction(row["on_update"]),
+			})
+			lastID = fkID
+		}
+		fk := &detail.ForeignKeys[len(detail.ForeignKeys)-1]
+		fk.Columns = append(fk.Columns, catalogString(row["column_name"]))
+		// A reference without columns is to the referenced table's key
+		if ref := catalogString(row["ref_column"]); ref != "" {
+			fk.ReferencedColumns = append(fk.ReferencedColumns, ref)
+		}
+	}
+	return detail, nil
+}
+
+// sqlitePredicate returns the WHERE clause of a partial index's CREATE
+// statement
+func sqlitePredicate(createSQL string) string {
+	loc := sqliteWhere.FindAllStringIndex(createSQL, -1)
+	if len(loc) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(createSQL[loc[len(loc)-1][1]:])
+}
+
+var sqliteWhere = regexp.MustCompile(`(?i)\sWHERE\s`)
+
+// sqliteChecks finds the CHECK constraints in a CREATE TABLE statement,
+// with their names when they are declared as CONSTRAINT name CHECK
+func sqliteChecks(createSQL string) []models.ConstraintInfo {
+	var checks []models.ConstraintInfo
+	var words []string
+	for i := 0; i < len(createSQL); {
+		c := createSQL[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			end := sqliteQuotedEnd(createSQL, i)
+			words = append(words, createSQL[i:end])
+			i = end
+		case c == '(' && len(words) > 0 && strings.EqualFold(words[len(words)-1], "CHECK"):
+			end := sqliteParenEnd(createSQL, i)
+			// An unterminated condition runs to the end of the statement
+			expression := strings.TrimSuffix(createSQL[i+1:end], ")")
+			check := models.ConstraintInfo{Type: "check", Expression: strings.TrimSpace(expression)}
+			if n := len(words); n >= 3 && strings.EqualFold(words[n-3], "CONSTRAINT") {
+				check.Name = sqliteUnquote(words[n-2])
+			}
+			checks = append(checks, check)
+			words = nil
+			i = end
+		case isSQLWordByte(c):
+			start := i
+			for i < len(createSQL) && isSQLWordByte(createSQL[i]) {
+				i++
+			}
+			words = append(words, createSQL[start:i])
+		default:
+			if c == ',' || c == '(' || c == ')' {
+				words = nil
+			}
+			i++
+		}
+	}
+	return checks
+}
+
+func isSQLWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// sqliteQuotedEnd returns the index after the quoted string or identifier
+// starting at i
+func sqliteQuotedEnd(s string, i int) int {
+	closing := s[i]
+	if closing == '[' {
+		closing = ']'
+	}
+	for j := i + 1; j < len(s); j++ {
+		if s[j] == closing {
+			if closing != ']' && j+1 < len(s) && s[j+1] == closing {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// sqliteParenEnd returns the index after the parenthesis matching the one
+// at i
+func sqliteParenEnd(s string, i int) int {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\'', '"', '`', '[':
+			j = sqliteQuotedEnd(s, j) - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+func sqliteUnquote(name string) string {
+	if len(name) < 2 {
+		return name
+	}
+	switch name[0] {
+	case '"', '`':
+		q := string(name[0])
+		return strings.ReplaceAll(name[1:len(name)-1], q+q, q)
+	case '[':
+		return name[1 : len(name)-1]
+	}
+	return name
+}
+
+// referentialAction spells a foreign key rule as SQL does. PostgreSQL
+// reports rules as single letters and SQL Server with underscores.
+func referentialAction(v interface{}) string {
+	action := catalogString(v)
+	switch action {
+	case "a":
+		return "NO ACTION"
+	case "r":
+		return "RESTRICT"
+	case "c":
+		return "CASCADE"
+	case "n":
+		return "SET NULL"
+	case "d":
+		return "SET DEFAULT"
+	}
+	return strings.ToUpper(strings.ReplaceAll(action, "_", " "))
+}
+
+// sqlServerColumnType formats a column type the way T-SQL spells it, e.g.
+// nvarchar(50), varbinary(max) or decimal(10,2)
+func sqlServerColumnType(row map[string]interface{}) string {
+	typeName := catalogString(row["type"])
+	length, _ := catalogInt(row["max_length"])
+	precision, _ := catalogInt(row["precision"])
+	scale, _ := catalogInt(row["scale"])
+
+	size := func(n int64) string {
+		if n < 0 {
+			return "max"
+		}
+		return strconv.FormatInt(n, 10)
+	}
+	switch typeName {
+	case "varchar", "char", "varbinary", "binary":
+		return fmt.Sprintf("%s(%s)", typeName, size(length))
+	case "nvarchar", "nchar":
+		if length > 0 {
+			length /= 2
+		}
+		return fmt.Sprintf("%s(%s)", typeName, size(length))
+	case "decimal", "numeric":
+		return fmt.Sprintf("%s(%d,%d)", typeName, precision, scale)
+	case "datetime2", "datetimeoffset", "time":
+		return fmt.Sprintf("%s(%d)", typeName, scale)
+	}
+	return typeName
+}
+
+// catalogRows runs a catalog query, failing on statement errors too
+func (d *SQLDriverImpl) catalogRows(id string, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	result, err := d.query(id, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
+	return result.Rows, nil
+}
+
+// catalogString reads a text value from a catalog row; NULL reads as empty
+func catalogString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// catalogBool reads a flag from a catalog row, which drivers return as
+// booleans, numbers or text
+func catalogBool(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		switch strings.ToUpper(b) {
+		case "1", "Y", "YES", "TRUE", "T":
+			return true
+		}
+		return false
+	}
+	n, ok := catalogInt(v)
+	return ok && n != 0
+}
+
+// catalogInt reads an integer from a catalog row, which drivers return as
+// various types
+func catalogInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int32:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		return i, err == nil
+	case []byte:
+		i, err := strconv.ParseInt(string(n), 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
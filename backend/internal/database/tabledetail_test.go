@@ -0,0 +1,286 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"opendbm/internal/models"
+)
+
+func TestGetTableDetailSQLite(t *testing.T) {
+	d, id := newTestSQLite(t)
+	for _, stmt := range []string{
+		"CREATE TABLE parent (id INTEGER PRIMARY KEY, code TEXT UNIQUE)",
+		`CREATE TABLE child (
+			a INTEGER,
+			b INTEGER,
+			parent_id INTEGER REFERENCES parent ON DELETE CASCADE,
+			note TEXT NOT NULL DEFAULT 'x',
+			CONSTRAINT "pos""itive" CHECK (a > 0 AND b IN (1, 2)),
+			CHECK (note <> ')'),
+			PRIMARY KEY (b, a),
+			UNIQUE (note, parent_id),
+			FOREIGN KEY (a, b) REFERENCES other (x, y) ON UPDATE SET NULL
+		)`,
+		"CREATE INDEX child_note ON child (note) WHERE note IS NOT NULL",
+		"CREATE INDEX child_lower ON child (lower(note), a)",
+	} {
+		if result, err := d.ExecuteSQL(context.Background(), id, "", stmt); err != nil || result.Error != "" {
+			t.Fatal(err, result.Error)
+		}
+	}
+
+	detail, err := d.GetTableDetail(id, "", "child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Name != "child" || detail.Schema != "main" {
+		t.Errorf("table = %s.%s, want main.child", detail.Schema, detail.Name)
+	}
+
+	wantColumns := []models.ColumnInfo{
+		{Name: "a", Type: "INTEGER", Nullable: true, IsPrimaryKey: true, IsForeignKey: true},
+		{Name: "b", Type: "INTEGER", Nullable: true, IsPrimaryKey: true, IsForeignKey: true},
+		{Name: "parent_id", Type: "INTEGER", Nullable: true, IsForeignKey: true},
+		{Name: "note", Type: "TEXT", DefaultValue: "'x'"},
+	}
+	if !reflect.DeepEqual(detail.Columns, wantColumns) {
+		t.Errorf("columns = %+v\nwant %+v", detail.Columns, wantColumns)
+	}
+
+	wantIndexes := map[string]models.IndexInfo{
+		"child_note":               {Name: "child_note", Columns: []string{"note"}, Method: "btree", Predicate: "note IS NOT NULL"},
+		"child_lower":              {Name: "child_lower", Columns: []string{"(expression)", "a"}, Method: "btree"},
+		"sqlite_autoindex_child_1": {Name: "sqlite_autoindex_child_1", Columns: []string{"b", "a"}, Unique: true, Primary: true, Method: "btree"},
+		"sqlite_autoindex_child_2": {Name: "sqlite_autoindex_child_2", Columns: []string{"note", "parent_id"}, Unique: true, Method: "btree"},
+	}
+	if len(detail.Indexes) != len(wantIndexes) {
+		t.Errorf("indexes = %+v", detail.Indexes)
+	}
+	for _, index := range detail.Indexes {
+		if want := wantIndexes[index.Name]; !reflect.DeepEqual(index, want) {
+			t.Errorf("index %s = %+v, want %+v", index.Name, index, want)
+		}
+	}
+
+	wantConstraints := []models.ConstraintInfo{
+		{Type: "primary_key", Columns: []string{"b", "a"}},
+		{Type: "unique", Columns: []string{"note", "parent_id"}},
+		{Name: `pos"itive`, Type: "check", Expression: "a > 0 AND b IN (1, 2)"},
+		{Type: "check", Expression: "note <> ')'"},
+	}
+	if !reflect.DeepEqual(detail.Constraints, wantConstraints) {
+		t.Errorf("constraints = %+v\nwant %+v", detail.Constraints, wantConstraints)
+	}
+
+	wantFKs := map[string]models.ForeignKeyInfo{
+		"parent": {Columns: []string{"parent_id"}, ReferencedTable: "parent", ReferencedColumns: []string{}, OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+		"other":  {Columns: []string{"a", "b"}, ReferencedTable: "other", ReferencedColumns: []string{"x", "y"}, OnDelete: "NO ACTION", OnUpdate: "SET NULL"},
+	}
+	if len(detail.ForeignKeys) != len(wantFKs) {
+		t.Errorf("foreign keys = %+v", detail.ForeignKeys)
+	}
+	for _, fk := range detail.ForeignKeys {
+		if want := wantFKs[fk.ReferencedTable]; !reflect.DeepEqual(fk, want) {
+			t.Errorf("foreign key to %s = %+v, want %+v", fk.ReferencedTable, fk, want)
+		}
+	}
+
+	parent, err := d.GetTableDetail(id, "", "main.parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parent.Columns[0].IsIdentity || !parent.Columns[0].IsPrimaryKey || parent.Columns[1].IsIdentity {
+		t.Errorf("parent columns = %+v, want id as the rowid", parent.Columns)
+	}
+	if len(parent.Indexes) != 1 || !parent.Indexes[0].Unique || parent.Indexes[0].Primary {
+		t.Errorf("parent indexes = %+v, want the unique index on code only", parent.Indexes)
+	}
+
+	if _, err := d.GetTableDetail(id, "", "missing"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("missing table = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestSQLiteChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		createSQL string
+		want      []models.ConstraintInfo
+	}{
+		{"none", "CREATE TABLE t (a INTEGER)", nil},
+		{"column", "CREATE TABLE t (a INTEGER CHECK (a > 0), b TEXT)", []models.ConstraintInfo{{Type: "check", Expression: "a > 0"}}},
+		{
+			"named", `CREATE TABLE t (a INTEGER, CONSTRAINT a_range CHECK(a BETWEEN 1 AND 9), CONSTRAINT [b c] check (length(b) < 3))`,
+			[]models.ConstraintInfo{
+				{Name: "a_range", Type: "check", Expression: "a BETWEEN 1 AND 9"},
+				{Name: "b c", Type: "check", Expression: "length(b) < 3"},
+			},
+		},
+		{
+			"quoted parentheses", "CREATE TABLE t (a TEXT CHECK (a <> ')' AND a <> \"(\"), `check` TEXT)",
+			[]models.ConstraintInfo{{Type: "check", Expression: "a <> ')' AND a <> \"(\""}},
+		},
+		{
+			"names that are not checks", `CREATE TABLE "check" ("check" INTEGER, checked INTEGER DEFAULT 'CHECK (x)', CONSTRAINT c UNIQUE (checked))`,
+			nil,
+		},
+		{
+			"backquoted name", "CREATE TABLE t (a INTEGER CONSTRAINT `a``b` CHECK ((a)))",
+			[]models.ConstraintInfo{{Name: "a`b", Type: "check", Expression: "(a)"}},
+		},
+		{"unterminated", "CREATE TABLE t (a INTEGER CHECK (a > 0", []models.ConstraintInfo{{Type: "check", Expression: "a > 0"}}},
+		{"cut after the parenthesis", "CREATE TABLE t (a INTEGER CHECK (", []models.ConstraintInfo{{Type: "check"}}},
+	}
+	for _, tt := range tests {
+		if got := sqliteChecks(tt.createSQL); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: sqliteChecks(%q) = %+v, want %+v", tt.name, tt.createSQL, got, tt.want)
+		}
+	}
+
+	// Cut short, a statement must not make the scan run past its end
+	createSQL := "CREATE TABLE t (a TEXT CONSTRAINT \"c\"\"d\" CHECK (a <> ')' AND [x] IN (f('('))), b `e`)"
+	for i := range createSQL {
+		sqliteChecks(createSQL[:i])
+	}
+}
+
+func TestSQLitePredicate(t *testing.T) {
+	tests := []struct {
+		createSQL string
+		want      string
+	}{
+		{"CREATE INDEX i ON t (a) WHERE a > 0", "a > 0"},
+		{"CREATE INDEX i ON t (a)\nwhere\ta IS NOT NULL ", "a IS NOT NULL"},
+		{"CREATE INDEX somewhere ON t (a) WHERE b = 1", "b = 1"},
+		{"CREATE INDEX i ON t (a)", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := sqlitePredicate(tt.createSQL); got != tt.want {
+			t.Errorf("sqlitePredicate(%q) = %q, want %q", tt.createSQL, got, tt.want)
+		}
+	}
+}
+
+func TestReferentialAction(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"a", "NO ACTION"},
+		{"r", "RESTRICT"},
+		{"c", "CASCADE"},
+		{"n", "SET NULL"},
+		{"d", "SET DEFAULT"},
+		{"SET_NULL", "SET NULL"},
+		{"NO_ACTION", "NO ACTION"},
+		{"cascade", "CASCADE"},
+		{"SET DEFAULT", "SET DEFAULT"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := referentialAction(tt.value); got != tt.want {
+			t.Errorf("referentialAction(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSQLServerColumnType(t *testing.T) {
+	tests := []struct {
+		row  map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"type": "int", "max_length": int64(4), "precision": int64(10), "scale": int64(0)}, "int"},
+		{map[string]interface{}{"type": "varchar", "max_length": int64(20)}, "varchar(20)"},
+		{map[string]interface{}{"type": "varbinary", "max_length": int64(-1)}, "varbinary(max)"},
+		{map[string]interface{}{"type": "nvarchar", "max_length": int64(100)}, "nvarchar(50)"},
+		{map[string]interface{}{"type": "nvarchar", "max_length": int64(-1)}, "nvarchar(max)"},
+		{map[string]interface{}{"type": "nchar", "max_length": "2"}, "nchar(1)"},
+		{map[string]interface{}{"type": "decimal", "precision": int64(10), "scale": int64(2)}, "decimal(10,2)"},
+		{map[string]interface{}{"type": "numeric", "precision": float64(38), "scale": float64(0)}, "numeric(38,0)"},
+		{map[string]interface{}{"type": "datetime2", "scale": int64(7)}, "datetime2(7)"},
+		{map[string]interface{}{"type": "time", "scale": int64(3)}, "time(3)"},
+		{map[string]interface{}{"type": "datetime", "scale": int64(3)}, "datetime"},
+		{map[string]interface{}{"type": "my_type"}, "my_type"},
+	}
+	for _, tt := range tests {
+		if got := sqlServerColumnType(tt.row); got != tt.want {
+			t.Errorf("sqlServerColumnType(%v) = %q, want %q", tt.row, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogValues(t *testing.T) {
+	flags := []struct {
+		value interface{}
+		want  bool
+	}{
+		{true, true}, {false, false}, {"YES", true}, {"y", true}, {"t", true}, {"NO", false},
+		{"N", false}, {int64(1), true}, {int32(0), false}, {float64(2), true}, {[]byte("1"), true}, {nil, false},
+	}
+	for _, tt := range flags {
+		if got := catalogBool(tt.value); got != tt.want {
+			t.Errorf("catalogBool(%#v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	ints := []struct {
+		value interface{}
+		want  int64
+		ok    bool
+	}{
+		{int64(-5), -5, true}, {int32(7), 7, true}, {8, 8, true}, {float64(9), 9, true},
+		{"10", 10, true}, {[]byte("11"), 11, true}, {"x", 0, false}, {nil, 0, false},
+	}
+	for _, tt := range ints {
+		if got, ok := catalogInt(tt.value); got != tt.want || ok != tt.ok {
+			t.Errorf("catalogInt(%#v) = %d, %v, want %d, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if catalogString(nil) != "" || catalogString("s") != "s" || catalogString(int64(3)) != "3" {
+		t.Error("catalogString does not read NULL, text and numbers")
+	}
+}
+
+func TestDetailQueries(t *testing.T) {
+	tests := []struct {
+		dbType     string
+		table      tableName
+		args       []interface{}
+		columnType bool
+		checks     bool
+	}{
+		{"postgres", tableName{name: "t"}, []interface{}{`"t"`}, false, false},
+		{"postgres", tableName{schema: "my.s", name: `a"b`}, []interface{}{`"my.s"."a""b"`}, false, false},
+		{"mysql", tableName{schema: "db", name: "t"}, []interface{}{"db", "t"}, false, true},
+		{"sqlserver", tableName{schema: "dbo", name: "t"}, []interface{}{"[dbo].[t]"}, true, false},
+		{"oracle", tableName{name: "T"}, []interface{}{nil, "T"}, true, false},
+	}
+	for _, tt := range tests {
+		q := detailQueries(tt.dbType, tt.table)
+		if q.columns == "" || q.indexes == "" || q.constraints == "" || q.foreignKeys == "" {
+			t.Errorf("detailQueries(%s) leaves queries out: %+v", tt.dbType, q)
+		}
+		if !reflect.DeepEqual(q.args, tt.args) {
+			t.Errorf("detailQueries(%s, %+v) args = %#v, want %#v", tt.dbType, tt.table, q.args, tt.args)
+		}
+		if (q.columnType != nil) != tt.columnType || (q.checks != "") != tt.checks {
+			t.Errorf("detailQueries(%s) column type %v and checks %v, want %v and %v", tt.dbType, q.columnType != nil, q.checks != "", tt.columnType, tt.checks)
+		}
+	}
+
+	for _, condition := range []string{`"NAME" IS NOT NULL`, `"a b" IS NOT NULL`} {
+		if !notNullCheck.MatchString(condition) {
+			t.Errorf("%s is not read as a NOT NULL column", condition)
+		}
+	}
+	for _, condition := range []string{`"A" IS NOT NULL AND "B" > 0`, `NAME IS NOT NULL`} {
+		if notNullCheck.MatchString(condition) {
+			t.Errorf("%s is read as a NOT NULL column", condition)
+		}
+	}
+}
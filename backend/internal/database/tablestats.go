@@ -0,0 +1,111 @@
+package database
+
+import (
+	"opendbm/internal/models"
+)
+
+// addTableStats sets the estimated row counts and data and index sizes of
+// the tables and materialized views in a listing. They come from what the
+// catalog already keeps, never from counting rows, so they are as fresh
+// as the database's last statistics update. Stats are best effort: when
+// the catalog cannot be read, tables are left without them.
+func (d *SQLDriverImpl) addTableStats(id string, dbType string, schema interface{}, tables []models.TableInfo) {
+	var queries []catalogQuery
+	switch dbType {
+	case "postgres":
+		queries = []catalogQuery{{postgresStatsQuery, 1}}
+	case "mysql":
+		queries = []catalogQuery{{"SELECT table_schema AS `schema`, table_name AS name, table_rows AS row_count, data_length AS data_size, index_length AS index_size FROM information_schema.tables WHERE table_schema = ? AND table_type = 'BASE TABLE'", 1}}
+	case "sqlserver":
+		queries = []catalogQuery{{sqlServerStatsQuery, 1}}
+	case "oracle":
+		// Sizes would need the DBA views, so Oracle only has row counts
+		queries = []catalogQuery{{`SELECT owner AS "schema", table_name AS "name", num_rows AS "row_count" FROM all_tables WHERE owner = ?`, 1}}
+	case "sqlite":
+		// dbstat is only there when SQLite is built with it, and
+		// sqlite_stat1 only once ANALYZE has run; the first that answers wins
+		prefix := dialect(dbType).quote(schema.(string)) + "."
+		queries = []catalogQuery{
+			{`SELECT ? AS schema, m.tbl_name AS name,
+				SUM(CASE WHEN m.type = 'table' AND s.pagetype = 'leaf' THEN s.ncell END) AS row_count,
+				SUM(CASE WHEN m.type = 'table' THEN s.pgsize ELSE 0 END) AS data_size,
+				SUM(CASE WHEN m.type = 'index' THEN s.pgsize ELSE 0 END) AS index_size
+			FROM dbstat(?) s JOIN ` + prefix + `sqlite_master m ON m.name = s.name
+			GROUP BY m.tbl_name`, 2},
+			{`SELECT ? AS schema, tbl AS name, MAX(CAST(stat AS INTEGER)) AS row_count
+			FROM ` + prefix + `sqlite_stat1 GROUP BY tbl`, 1},
+		}
+	default:
+		return
+	}
+
+	type key struct{ schema, name string }
+	stats := map[key]map[string]interface{}{}
+	for _, query := range queries {
+		args := make([]interface{}, query.placeholders)
+		for i := range args {
+			args[i] = schema
+		}
+		rows, err := d.catalogRows(id, query.sql, args...)
+		if err != nil {
+			continue
+		}
+		for _, row := range rows {
+			stats[key{catalogString(row["schema"]), catalogString(row["name"])}] = row
+		}
+		break
+	}
+
+	for i := range tables {
+		t := &tables[i]
+		if t.Type != "table" && t.Type != "materialized_view" {
+			continue
+		}
+		row, ok := stats[key{t.Schema, t.Name}]
+		if !ok {
+			continue
+		}
+		t.RowCount = catalogSize(row["row_count"])
+		t.DataSize = catalogSize(row["data_size"])
+		t.IndexSize = catalogSize(row["index_size"])
+	}
+}
+
+// catalogSize reads a count or size from a catalog row. Negative values
+// are how PostgreSQL marks tables it has never analyzed.
+func catalogSize(v interface{}) *int64 {
+	n, ok := catalogInt(v)
+	if !ok || n < 0 {
+		return nil
+	}
+	return &n
+}
+
+// postgresStatsQuery reads the planner's row estimate and the on-disk size
+// of each table and materialized view. Partitioned tables add up their
+// partitions; pg_partition_tree has nothing for materialized views.
+const postgresStatsQuery = `SELECT n.nspname AS schema, c.relname AS name,
+	CASE WHEN bool_and(p.reltuples >= 0 OR p.relkind = 'p') THEN SUM(GREATEST(p.reltuples, 0))::bigint END AS row_count,
+	SUM(pg_table_size(p.oid))::bigint AS data_size,
+	SUM(pg_indexes_size(p.oid))::bigint AS index_size
+FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN LATERAL pg_partition_tree(c.oid) t ON true
+JOIN pg_class p ON p.oid = COALESCE(t.relid, c.oid)
+WHERE c.relkind IN ('r', 'p', 'm') AND NOT c.relispartition
+	AND n.nspname <> 'information_schema' AND n.nspname !~ '^pg_' AND n.nspname = COALESCE(?, n.nspname)
+GROUP BY n.nspname, c.relname`
+
+// sqlServerStatsQuery reads row counts from sys.partitions and sizes from
+// the pages allocated to each table's heap or clustered index and to its
+// other indexes
+const sqlServerStatsQuery = `SELECT s.name AS [schema], o.name AS name,
+	(SELECT SUM(r.rows) FROM sys.partitions r WHERE r.object_id = o.object_id AND r.index_id IN (0, 1)) AS row_count,
+	SUM(CASE WHEN p.index_id IN (0, 1) THEN a.used_pages ELSE 0 END) * 8192 AS data_size,
+	SUM(CASE WHEN p.index_id > 1 THEN a.used_pages ELSE 0 END) * 8192 AS index_size
+FROM sys.objects o
+JOIN sys.schemas s ON s.schema_id = o.schema_id
+JOIN sys.partitions p ON p.object_id = o.object_id
+JOIN sys.allocation_units a ON a.container_id = p.partition_id
+WHERE o.is_ms_shipped = 0 AND o.type IN ('U', 'V') AND s.name = COALESCE(?, s.name)
+GROUP BY s.name, o.name, o.object_id`
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"opendbm/internal/models"
+)
+
+func TestListTablesWithStatsSQLite(t *testing.T) {
+	d, id := newTestSQLite(t)
+	ctx := context.Background()
+	for _, stmt := range []string{
+		"CREATE TABLE a (id INTEGER PRIMARY KEY, x INTEGER)",
+		"CREATE INDEX a_x ON a (x)",
+		"CREATE TABLE b (y TEXT)",
+		"CREATE VIEW v AS SELECT x FROM a",
+		countInsert("a", "x", 100),
+		countInsert("b", "y", 7),
+	} {
+		if result, err := d.ExecuteSQL(ctx, id, "", stmt); err != nil || result.Error != "" {
+			t.Fatal(err, result.Error)
+		}
+	}
+
+	stats := func(tables []models.TableInfo) map[string]*int64 {
+		counts := map[string]*int64{}
+		for _, table := range tables {
+			counts[table.Name] = table.RowCount
+			if table.DataSize != nil || table.IndexSize != nil {
+				t.Errorf("%s has sizes without dbstat: %+v", table.Name, table)
+			}
+		}
+		return counts
+	}
+
+	// Before ANALYZE there are no statistics to read
+	tables, err := d.ListTablesWithStats(id, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, count := range stats(tables) {
+		if count != nil {
+			t.Errorf("%s has %d rows before ANALYZE, want none", name, *count)
+		}
+	}
+
+	if result, err := d.ExecuteSQL(ctx, id, "", "ANALYZE"); err != nil || result.Error != "" {
+		t.Fatal(err, result.Error)
+	}
+	tables, err = d.ListTablesWithStats(id, "", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := stats(tables)
+	if fmtPtr(counts["a"]) != "100" || fmtPtr(counts["b"]) != "7" || counts["v"] != nil || len(counts) != 3 {
+		t.Errorf("row counts a=%s b=%s v=%s, want 100, 7 and none for the view", fmtPtr(counts["a"]), fmtPtr(counts["b"]), fmtPtr(counts["v"]))
+	}
+
+	// Plain listings never carry them
+	tables, err = d.ListTables(id, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, count := range stats(tables) {
+		if count != nil {
+			t.Errorf("ListTables() gives %s a row count", name)
+		}
+	}
+}
+
+// countInsert inserts the numbers 1 to n into a column of a table
+func countInsert(table string, column string, n int) string {
+	return "INSERT INTO " + table + " (" + column + ") SELECT i FROM (" + countQuery(n) + ")"
+}
+
+func TestCatalogSize(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{int64(42), "42"},
+		{float64(1e6), "1000000"},
+		{"8192", "8192"},
+		{int64(0), "0"},
+		{int64(-1), "nil"},
+		{float64(-1), "nil"},
+		{nil, "nil"},
+		{"unknown", "nil"},
+	}
+	for _, tt := range tests {
+		if got := fmtPtr(catalogSize(tt.value)); got != tt.want {
+			t.Errorf("catalogSize(%#v) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
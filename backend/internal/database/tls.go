@@ -0,0 +1,162 @@
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"opendbm/internal/models"
+)
+
+// SSL modes accepted in ConnectionConfig.SSLMode, named after libpq
+const (
+	SSLModeDisable    = "disable"
+	SSLModeRequire    = "require"
+	SSLModeVerifyCA   = "verify-ca"
+	SSLModeVerifyFull = "verify-full"
+)
+
+// sslMode returns the effective SSL mode of a config. An explicit SSLMode
+// wins; otherwise SSL alone means an encrypted but unverified connection and
+// SSLRejectUnauthorized upgrades it to full verification.
+func sslMode(config models.ConnectionConfig) (string, error) {
+	switch config.SSLMode {
+	case "":
+	case SSLModeDisable, SSLModeRequire, SSLModeVerifyCA, SSLModeVerifyFull:
+		return config.SSLMode, nil
+	default:
+		return "", fmt.Errorf("unsupported ssl mode: %s", config.SSLMode)
+	}
+
+	if !config.SSL {
+		return SSLModeDisable, nil
+	}
+	if config.SSLRejectUnauthorized != nil && *config.SSLRejectUnauthorized {
+		return SSLModeVerifyFull, nil
+	}
+	return SSLModeRequire, nil
+}
+
+// buildTLSConfig turns the SSL settings of a config into a *tls.Config, or
+// returns nil when TLS is disabled. serverName is the database host as the
+// user entered it, which may differ from the dialed address when the
+// connection goes through an SSH tunnel.
+func buildTLSConfig(secrets SecretResolver, config models.ConnectionConfig, serverName string) (*tls.Config, error) {
+	mode, err := sslMode(config)
+	if err != nil || mode == SSLModeDisable {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	caPEM, err := pemSource(config.SSLCA, config.SSLCAPath, "CA certificate")
+	if err != nil {
+		return nil, err
+	}
+	if caPEM != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no valid certificates found in CA certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certPEM, err := pemSource(config.SSLCert, config.SSLCertPath, "client certificate")
+	if err != nil {
+		return nil, err
+	}
+	if certPEM != nil {
+		keyPEM, err := clientKey(secrets, config)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate or key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	switch mode {
+	case SSLModeRequire:
+		tlsConfig.InsecureSkipVerify = true
+	case SSLModeVerifyCA:
+		// Verify the chain ourselves but skip the hostname check
+		roots := tlsConfig.RootCAs
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyChain(rawCerts, roots)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// clientKey returns the client key PEM from its path or from the vault
+func clientKey(secrets SecretResolver, config models.ConnectionConfig) ([]byte, error) {
+	if config.SSLKey != "" {
+		return []byte(config.SSLKey), nil
+	}
+	if config.SSLKeyPath != "" {
+		data, err := os.ReadFile(expandHome(config.SSLKeyPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+		return data, nil
+	}
+	if config.SSLKeyRef != "" && secrets != nil {
+		key, err := secrets.Get(config.SSLKeyRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve client key: %w", err)
+		}
+		return []byte(key), nil
+	}
+	return nil, fmt.Errorf("client certificate requires a client key")
+}
+
+// pemSource returns inline PEM content, or reads it from path
+func pemSource(content string, path string, what string) ([]byte, error) {
+	if content != "" {
+		return []byte(content), nil
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", what, err)
+	}
+	return data, nil
+}
+
+func verifyChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("server presented no certificate")
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse server certificate: %w", err)
+		}
+		certs[i] = cert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
@@ -0,0 +1,685 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+
+	"opendbm/internal/models"
+	"opendbm/internal/store"
+	"opendbm/internal/vault"
+)
+
+// TunnelManager handles saved SSH tunnels and the SSH sessions opened for them.
+// A chain of tunnels reaches the database through each hop in order, e.g. a
+// bastion followed by an internal jump box. Connections that use the same
+// chain share a single set of SSH clients.
+type TunnelManager struct {
+	store      *store.Store
+	vault      *vault.Vault
+	knownHosts *KnownHosts
+	tunnels    map[string]*models.SSHTunnelConfig
+	sessions   map[string]*sshSession
+	mu         sync.Mutex
+}
+
+// sshSession holds the SSH clients of one chain, shared by all its forwards.
+// refs is guarded by the manager's lock; clients by the session's own, which
+// is held while the chain is dialed so that a slow hop only holds up the
+// connections that go through it.
+type sshSession struct {
+	chain []string
+	refs  int
+
+	mu      sync.Mutex
+	clients []*ssh.Client // one per hop, the last one reaches the database
+	closed  bool          // released for good, never to be dialed again
+}
+
+// errTunnelClosed is returned when dialing through a released chain
+var errTunnelClosed = errors.New("ssh tunnel is closed")
+
+// Forward listens on a local port and forwards each accepted connection over
+// a chain of SSH tunnels to a remote address
+type Forward struct {
+	chain    []string
+	remote   string
+	listener net.Listener
+	tunnels  *TunnelManager
+	once     sync.Once
+}
+
+// NewTunnelManager creates a tunnel manager and loads saved tunnels from the store
+func NewTunnelManager(st *store.Store, v *vault.Vault) (*TunnelManager, error) {
+	t := &TunnelManager{
+		store:      st,
+		vault:      v,
+		knownHosts: NewKnownHosts(st),
+		tunnels:    make(map[string]*models.SSHTunnelConfig),
+		sessions:   make(map[string]*sshSession),
+	}
+
+	saved, err := st.ListTunnels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ssh tunnels: %w", err)
+	}
+	for i := range saved {
+		t.tunnels[saved[i].ID] = &saved[i]
+	}
+
+	return t, nil
+}
+
+// KnownHosts returns the host key verifier used for every SSH hop
+func (t *TunnelManager) KnownHosts() *KnownHosts {
+	return t.knownHosts
+}
+
+// List returns all saved tunnels
+func (t *TunnelManager) List() []models.SSHTunnelConfig {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]models.SSHTunnelConfig, 0, len(t.tunnels))
+	for _, config := range t.tunnels {
+		result = append(result, redactTunnel(*config))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Get returns a single saved tunnel
+func (t *TunnelManager) Get(id string) (*models.SSHTunnelConfig, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	config, exists := t.tunnels[id]
+	if !exists {
+		return nil, fmt.Errorf("ssh tunnel not found: %s", id)
+	}
+
+	redacted := redactTunnel(*config)
+	return &redacted, nil
+}
+
+// Create saves a new tunnel
+func (t *TunnelManager) Create(config models.SSHTunnelConfig) (*models.SSHTunnelConfig, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	config.ID = uuid.New().String()
+	if err := t.storeSecrets(&config); err != nil {
+		return nil, err
+	}
+	if err := t.store.SaveTunnel(config); err != nil {
+		t.deleteSecrets(config.ID)
+		return nil, fmt.Errorf("failed to save ssh tunnel: %w", err)
+	}
+
+	t.tunnels[config.ID] = &config
+	redacted := redactTunnel(config)
+	return &redacted, nil
+}
+
+// Update replaces a saved tunnel. Secrets that are left empty keep their
+// stored value. Shared SSH clients that go through the tunnel are dropped so
+// the next dial uses the new settings.
+func (t *TunnelManager) Update(id string, config models.SSHTunnelConfig) (*models.SSHTunnelConfig, error) {
+	var dropped []*sshSession
+	t.mu.Lock()
+	defer func() {
+		t.mu.Unlock()
+		resetSessions(dropped)
+	}()
+
+	existing, exists := t.tunnels[id]
+	if !exists {
+		return nil, fmt.Errorf("ssh tunnel not found: %s", id)
+	}
+
+	config.ID = id
+	stale := keepSecrets(&config, *existing)
+	if err := t.storeSecrets(&config); err != nil {
+		return nil, err
+	}
+	if err := t.store.SaveTunnel(config); err != nil {
+		return nil, fmt.Errorf("failed to save ssh tunnel: %w", err)
+	}
+	for _, name := range stale {
+		t.vault.Delete(tunnelSecretRef(id, name))
+	}
+
+	t.tunnels[id] = &config
+	dropped = t.sessionsThrough(id)
+
+	redacted := redactTunnel(config)
+	return &redacted, nil
+}
+
+// Delete removes a saved tunnel and closes the SSH clients that use it
+func (t *TunnelManager) Delete(id string) error {
+	var dropped []*sshSession
+	t.mu.Lock()
+	defer func() {
+		t.mu.Unlock()
+		resetSessions(dropped)
+	}()
+
+	if _, exists := t.tunnels[id]; !exists {
+		return fmt.Errorf("ssh tunnel not found: %s", id)
+	}
+
+	if err := t.store.DeleteTunnel(id); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("failed to delete ssh tunnel: %w", err)
+	}
+	t.deleteSecrets(id)
+
+	dropped = t.sessionsThrough(id)
+	delete(t.tunnels, id)
+	return nil
+}
+
+// Test opens and closes an SSH client for a tunnel config. When the config
+// refers to a saved tunnel, missing secrets are taken from the vault.
+func (t *TunnelManager) Test(config models.SSHTunnelConfig) error {
+	if config.ID != "" {
+		t.mu.Lock()
+		if existing, exists := t.tunnels[config.ID]; exists {
+			keepSecrets(&config, *existing)
+		}
+		t.mu.Unlock()
+	}
+
+	client, err := t.dialHop(nil, config)
+	if err != nil {
+		return err
+	}
+	return client.Close()
+}
+
+// Forward starts a local listener that forwards to remoteHost:remotePort
+// through the given chain of tunnels
+func (t *TunnelManager) Forward(chain []string, remoteHost string, remotePort int) (*Forward, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no ssh tunnel selected")
+	}
+	if err := t.acquire(chain); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.release(chain)
+		return nil, fmt.Errorf("failed to open local tunnel port: %w", err)
+	}
+
+	f := &Forward{
+		chain:    chain,
+		remote:   net.JoinHostPort(remoteHost, strconv.Itoa(remotePort)),
+		listener: listener,
+		tunnels:  t,
+	}
+	go f.serve()
+	return f, nil
+}
+
+// LocalAddr returns the local host and port that forward to the remote address
+func (f *Forward) LocalAddr() (string, int) {
+	addr := f.listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+// Close stops the listener and releases the shared SSH clients
+func (f *Forward) Close() error {
+	var err error
+	f.once.Do(func() {
+		err = f.listener.Close()
+		f.tunnels.release(f.chain)
+	})
+	return err
+}
+
+func (f *Forward) serve() {
+	for {
+		local, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.pipe(local)
+	}
+}
+
+func (f *Forward) pipe(local net.Conn) {
+	defer local.Close()
+
+	remote, err := f.tunnels.dialRemote(f.chain, f.remote)
+	if err != nil {
+		return
+	}
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// acquire takes a reference on the shared SSH clients of a chain, dialing them if needed
+func (t *TunnelManager) acquire(chain []string) error {
+	t.mu.Lock()
+	key := chainKey(chain)
+	session, ok := t.sessions[key]
+	if !ok {
+		session = &sshSession{chain: chain}
+		t.sessions[key] = session
+	}
+	session.refs++
+	t.mu.Unlock()
+
+	if _, err := t.connect(session, nil); err != nil {
+		t.release(chain)
+		return err
+	}
+	return nil
+}
+
+// release drops a reference and closes the SSH clients once nothing uses them
+func (t *TunnelManager) release(chain []string) {
+	t.mu.Lock()
+	key := chainKey(chain)
+	session, ok := t.sessions[key]
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	session.refs--
+	if session.refs > 0 {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.sessions, key)
+	t.mu.Unlock()
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.closed = true
+	session.close()
+}
+
+// sessionsThrough returns the sessions of every chain that goes through a
+// tunnel; callers must hold t.mu and reset them once they have released it
+func (t *TunnelManager) sessionsThrough(tunnelID string) []*sshSession {
+	var sessions []*sshSession
+	for _, session := range t.sessions {
+		for _, id := range session.chain {
+			if id == tunnelID {
+				sessions = append(sessions, session)
+				break
+			}
+		}
+	}
+	return sessions
+}
+
+// resetSessions closes the SSH clients of sessions; forwards that are still
+// open redial on their next connection
+func resetSessions(sessions []*sshSession) {
+	for _, session := range sessions {
+		session.mu.Lock()
+		session.close()
+		session.mu.Unlock()
+	}
+}
+
+// dialRemote opens a channel to addr over the chain. The SSH clients are
+// only redialed when they no longer answer: a remote host refusing addr
+// says nothing about them, and other connections share them.
+func (t *TunnelManager) dialRemote(chain []string, addr string) (net.Conn, error) {
+	t.mu.Lock()
+	session, ok := t.sessions[chainKey(chain)]
+	t.mu.Unlock()
+	if !ok {
+		return nil, errTunnelClosed
+	}
+
+	client := session.current()
+	if client != nil {
+		conn, err := client.Dial("tcp", addr)
+		if err == nil {
+			return conn, nil
+		}
+		if alive(client) {
+			return nil, err
+		}
+	}
+
+	client, err := t.connect(session, client)
+	if err != nil {
+		return nil, err
+	}
+	return client.Dial("tcp", addr)
+}
+
+// connect returns the last client of a session's chain, dialing the chain
+// when the session has no clients, when they no longer answer, or when
+// stale is still the current client. Callers that find the clients dead
+// at the same time wait for one another's redial instead of repeating it.
+func (t *TunnelManager) connect(session *sshSession, stale *ssh.Client) (*ssh.Client, error) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.closed {
+		return nil, errTunnelClosed
+	}
+	if len(session.clients) > 0 {
+		if client := session.client(); client != stale && alive(client) {
+			return client, nil
+		}
+		session.close()
+	}
+
+	t.mu.Lock()
+	configs, err := t.chainConfigs(session.chain)
+	t.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := t.dialChain(configs)
+	if err != nil {
+		return nil, err
+	}
+	session.clients = clients
+	return session.client(), nil
+}
+
+// chainConfigs returns the configs of each hop of a chain; callers must
+// hold t.mu
+func (t *TunnelManager) chainConfigs(chain []string) ([]models.SSHTunnelConfig, error) {
+	configs := make([]models.SSHTunnelConfig, len(chain))
+	for i, id := range chain {
+		config, exists := t.tunnels[id]
+		if !exists {
+			return nil, fmt.Errorf("ssh tunnel not found: %s", id)
+		}
+		configs[i] = *config
+	}
+	return configs, nil
+}
+
+// dialChain connects to each hop of a chain through the previous one
+func (t *TunnelManager) dialChain(configs []models.SSHTunnelConfig) ([]*ssh.Client, error) {
+	clients := make([]*ssh.Client, 0, len(configs))
+	closeAll := func() {
+		for i := len(clients) - 1; i >= 0; i-- {
+			clients[i].Close()
+		}
+	}
+
+	var prev *ssh.Client
+	for i, config := range configs {
+		client, err := t.dialHop(prev, config)
+		if err != nil {
+			closeAll()
+			if len(configs) > 1 {
+				return nil, fmt.Errorf("ssh hop %d (%s): %w", i+1, config.Name, err)
+			}
+			return nil, err
+		}
+		clients = append(clients, client)
+		prev = client
+	}
+	return clients, nil
+}
+
+// dialHop opens an SSH client to a tunnel host, directly or through the
+// previous hop of a chain
+func (t *TunnelManager) dialHop(prev *ssh.Client, config models.SSHTunnelConfig) (*ssh.Client, error) {
+	port := config.Port
+	if port == 0 {
+		port = 22
+	}
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(port))
+
+	clientConfig, err := t.clientConfig(config, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if prev == nil {
+		client, err := ssh.Dial("tcp", addr, clientConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open ssh tunnel: %w", err)
+		}
+		return client, nil
+	}
+
+	conn, err := prev.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach %s: %w", addr, err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open ssh tunnel: %w", err)
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+func (t *TunnelManager) clientConfig(config models.SSHTunnelConfig, addr string) (*ssh.ClientConfig, error) {
+	var auth ssh.AuthMethod
+
+	switch config.AuthMethod {
+	case "password", "":
+		password, err := t.secret(config.Password, config.HasPassword, tunnelSecretRef(config.ID, "password"))
+		if err != nil {
+			return nil, err
+		}
+		auth = ssh.Password(password)
+	case "key":
+		signer, err := t.signer(config)
+		if err != nil {
+			return nil, err
+		}
+		auth = ssh.PublicKeys(signer)
+	default:
+		return nil, fmt.Errorf("unsupported ssh auth method: %s", config.AuthMethod)
+	}
+
+	return &ssh.ClientConfig{
+		User:              config.Username,
+		Auth:              []ssh.AuthMethod{auth},
+		HostKeyCallback:   t.knownHosts.Callback(),
+		HostKeyAlgorithms: t.knownHosts.Algorithms(addr),
+		Timeout:           10 * time.Second,
+	}, nil
+}
+
+func (t *TunnelManager) signer(config models.SSHTunnelConfig) (ssh.Signer, error) {
+	key, err := t.secret(config.PrivateKey, config.HasPrivateKey, tunnelSecretRef(config.ID, "privateKey"))
+	if err != nil {
+		return nil, err
+	}
+
+	if key == "" {
+		if config.KeyPath == "" {
+			return nil, fmt.Errorf("ssh private key is required")
+		}
+		data, err := os.ReadFile(expandHome(config.KeyPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ssh private key: %w", err)
+		}
+		key = string(data)
+	}
+
+	passphrase, err := t.secret(config.Passphrase, config.HasPassphrase, tunnelSecretRef(config.ID, "passphrase"))
+	if err != nil {
+		return nil, err
+	}
+
+	if passphrase != "" {
+		signer, err := ssh.ParsePrivateKeyWithPassphrase([]byte(key), []byte(passphrase))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ssh private key: %w", err)
+		}
+		return signer, nil
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(key))
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, fmt.Errorf("ssh private key is encrypted; a passphrase is required")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ssh private key: %w", err)
+	}
+	return signer, nil
+}
+
+// secret returns an explicitly supplied secret or falls back to the vault
+func (t *TunnelManager) secret(value string, stored bool, ref string) (string, error) {
+	if value != "" || !stored {
+		return value, nil
+	}
+
+	value, err := t.vault.Get(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve ssh secret: %w", err)
+	}
+	return value, nil
+}
+
+// keepSecrets carries the stored secrets of an existing tunnel over to an
+// edited config where they still apply: the password to password auth, and
+// the key and its passphrase to key auth, where a key path replaces an
+// uploaded key. Secrets given for another auth method are dropped. It
+// returns the stored secrets that no longer apply.
+func keepSecrets(config *models.SSHTunnelConfig, existing models.SSHTunnelConfig) []string {
+	passwordAuth := config.AuthMethod == "password" || config.AuthMethod == ""
+	keyAuth := config.AuthMethod == "key"
+	if !passwordAuth {
+		config.Password = ""
+	}
+	if !keyAuth {
+		config.PrivateKey = ""
+		config.Passphrase = ""
+	}
+
+	var stale []string
+	keep := func(name string, stored bool, applies bool) bool {
+		if stored && !applies {
+			stale = append(stale, name)
+		}
+		return stored && applies
+	}
+	config.HasPassword = keep("password", existing.HasPassword, passwordAuth)
+	config.HasPrivateKey = keep("privateKey", existing.HasPrivateKey, keyAuth && config.KeyPath == "")
+	config.HasPassphrase = keep("passphrase", existing.HasPassphrase, keyAuth)
+	return stale
+}
+
+// storeSecrets moves plaintext secrets from the config into the vault
+func (t *TunnelManager) storeSecrets(config *models.SSHTunnelConfig) error {
+	secrets := []struct {
+		name  string
+		value *string
+		has   *bool
+	}{
+		{"password", &config.Password, &config.HasPassword},
+		{"privateKey", &config.PrivateKey, &config.HasPrivateKey},
+		{"passphrase", &config.Passphrase, &config.HasPassphrase},
+	}
+
+	for _, s := range secrets {
+		if *s.value == "" {
+			continue
+		}
+		if err := t.vault.Put(tunnelSecretRef(config.ID, s.name), *s.value); err != nil {
+			return fmt.Errorf("failed to store ssh %s: %w", s.name, err)
+		}
+		*s.value = ""
+		*s.has = true
+	}
+	return nil
+}
+
+func (t *TunnelManager) deleteSecrets(id string) {
+	for _, name := range []string{"password", "privateKey", "passphrase"} {
+		t.vault.Delete(tunnelSecretRef(id, name))
+	}
+}
+
+// client returns the last client of the chain; callers must hold s.mu
+func (s *sshSession) client() *ssh.Client {
+	return s.clients[len(s.clients)-1]
+}
+
+// current returns the last client of the chain, or nil when the chain is
+// not connected
+func (s *sshSession) current() *ssh.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || len(s.clients) == 0 {
+		return nil
+	}
+	return s.client()
+}
+
+// close closes the clients of the chain; callers must hold s.mu
+func (s *sshSession) close() {
+	for i := len(s.clients) - 1; i >= 0; i-- {
+		s.clients[i].Close()
+	}
+	s.clients = nil
+}
+
+func chainKey(chain []string) string {
+	return strings.Join(chain, ">")
+}
+
+func tunnelSecretRef(id string, name string) string {
+	return "ssh/" + id + "/" + name
+}
+
+func redactTunnel(config models.SSHTunnelConfig) models.SSHTunnelConfig {
+	config.Password = ""
+	config.PrivateKey = ""
+	config.Passphrase = ""
+	return config
+}
+
+// alive reports whether the SSH server still answers on the client
+func alive(client *ssh.Client) bool {
+	_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+	return err == nil
+}
+
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
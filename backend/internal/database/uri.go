@@ -0,0 +1,73 @@
+package database
+
+import (
+	"net/url"
+	"strings"
+
+	"opendbm/internal/models"
+)
+
+// extractURICredentials moves a password embedded in a connection string into
+// config.Password so that it ends up in the vault instead of the saved URI
+func extractURICredentials(config *models.ConnectionConfig) {
+	scheme, authority, tail, ok := splitURI(config.URI)
+	if !ok {
+		return
+	}
+
+	at := strings.LastIndex(authority, "@")
+	if at < 0 {
+		return
+	}
+	user, pass, hasPass := strings.Cut(authority[:at], ":")
+	if !hasPass {
+		return
+	}
+
+	if config.Password == "" {
+		if unescaped, err := url.PathUnescape(pass); err == nil {
+			pass = unescaped
+		}
+		config.Password = pass
+	}
+
+	userinfo := ""
+	if user != "" {
+		userinfo = user + "@"
+	}
+	config.URI = scheme + "://" + userinfo + authority[at+1:] + tail
+}
+
+// uriWithPassword puts a password back into a connection string that carries
+// a username but no password
+func uriWithPassword(uri string, password string) string {
+	if password == "" {
+		return uri
+	}
+
+	scheme, authority, tail, ok := splitURI(uri)
+	if !ok {
+		return uri
+	}
+
+	at := strings.LastIndex(authority, "@")
+	if at < 0 || strings.Contains(authority[:at], ":") {
+		return uri
+	}
+	return scheme + "://" + authority[:at] + ":" + strings.ReplaceAll(url.QueryEscape(password), "+", "%20") + authority[at:] + tail
+}
+
+// splitURI splits scheme://authority/rest without validating the host list,
+// which may hold several comma separated hosts
+func splitURI(uri string) (scheme, authority, tail string, ok bool) {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok {
+		return "", "", "", false
+	}
+
+	authority = rest
+	if end := strings.IndexAny(rest, "/?"); end >= 0 {
+		authority, tail = rest[:end], rest[end:]
+	}
+	return scheme, authority, tail, true
+}
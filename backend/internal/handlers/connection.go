@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"opendbm/internal/database"
 	"opendbm/internal/models"
@@ -29,6 +32,45 @@ func CreateConnection(manager *database.Manager) gin.HandlerFunc {
 		}
 
 		conn, err := manager.Connect(config)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, conn)
+	}
+}
+
+// GetConnection returns a single saved connection
+func GetConnection(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		conn, err := manager.GetConnection(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, conn)
+	}
+}
+
+// UpdateConnection replaces the saved config of a connection
+func UpdateConnection(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		var config models.ConnectionConfig
+		if err := c.ShouldBindJSON(&config); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if _, err := manager.GetConnection(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		conn, err := manager.Update(id, config)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -54,7 +96,9 @@ func TestConnection(manager *database.Manager) gin.HandlerFunc {
 
 		if err := manager.TestConnection(config); err != nil {
 			log.Printf("[TestConnection] Connection test failed: %v", err)
-			c.JSON(http.StatusOK, gin.H{"success": false, "error": err.Error()})
+			body := errorResponse(err)
+			body["success"] = false
+			c.JSON(http.StatusOK, body)
 			return
 		}
 
@@ -96,12 +140,80 @@ func ExecuteQuery(manager *database.Manager) gin.HandlerFunc {
 			return
 		}
 
-		result, err := manager.GetSQLDriver().ExecuteQuery(req.ConnectionID, req.SQL)
+		driver, err := sqlDriver(manager, req.ConnectionID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		query, args, err := bindParams(manager, driver, req.ConnectionID, req.SQL, req.Params)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx, queryID, done, err := manager.StartQuery(c.Request.Context(), req.ConnectionID, req.QueryID, req.SQL, time.Duration(req.Timeout)*time.Second)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		defer done()
+
+		result, err := driver.ExecuteQuery(ctx, req.ConnectionID, req.SessionID, query, args...)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
+		result.QueryID = queryID
+		if req.RowFormat == "map" {
+			c.JSON(http.StatusOK, result.MapResult())
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// ExecuteScript executes a script of several statements, returning a result
+// per statement
+func ExecuteScript(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.QueryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var continueOnError bool
+		switch req.OnError {
+		case "", "stop":
+		case "continue":
+			continueOnError = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "on_error must be stop or continue"})
+			return
+		}
+
+		runner, err := sqlFeature[database.ScriptRunner](manager, req.ConnectionID, database.CapabilityScripts)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		ctx, queryID, done, err := manager.StartQuery(c.Request.Context(), req.ConnectionID, req.QueryID, req.SQL, time.Duration(req.Timeout)*time.Second)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		defer done()
+
+		result, err := runner.ExecuteScript(ctx, req.ConnectionID, req.SessionID, req.SQL, continueOnError, req.Params)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		result.QueryID = queryID
 		c.JSON(http.StatusOK, result)
 	}
 }
@@ -110,7 +222,19 @@ func ExecuteQuery(manager *database.Manager) gin.HandlerFunc {
 func ListDatabases(manager *database.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
-		databases, err := manager.GetSQLDriver().ListDatabases(id)
+		driver, err := manager.Driver(id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		lister, ok := driver.(databaseLister)
+		if !ok {
+			respondError(c, http.StatusNotImplemented, capabilityError(manager, id, driver, database.CapabilitySQL))
+			return
+		}
+
+		databases, err := lister.ListDatabases(id)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -119,12 +243,46 @@ func ListDatabases(manager *database.Manager) gin.HandlerFunc {
 	}
 }
 
-// ListTables lists all tables in a database
+// ListSchemas lists the schemas of a database
+func ListSchemas(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		db := c.Param("db")
+		lister, err := sqlFeature[database.SchemaLister](manager, id, database.CapabilitySchemas)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		schemas, err := lister.ListSchemas(id, db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, schemas)
+	}
+}
+
+// ListTables lists the tables in a database, or in one of its schemas
+// when the schema query parameter is set. stats=true adds estimated row
+// counts and sizes.
 func ListTables(manager *database.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 		db := c.Param("db")
-		tables, err := manager.GetSQLDriver().ListTables(id, db)
+		driver, err := sqlDriver(manager, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		var tables []models.TableInfo
+		stats, _ := strconv.ParseBool(c.Query("stats"))
+		if lister, ok := driver.(database.TableStatsLister); ok && stats {
+			tables, err = lister.ListTablesWithStats(id, db, c.Query("schema"))
+		} else {
+			tables, err = driver.ListTables(id, db, c.Query("schema"))
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -137,8 +295,14 @@ func ListTables(manager *database.Manager) gin.HandlerFunc {
 func GetTableSchema(manager *database.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
-		table := c.Param("table")
-		schema, err := manager.GetSQLDriver().GetTableSchema(id, table)
+		table := qualifiedParam(c, "table")
+		driver, err := sqlDriver(manager, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		schema, err := driver.GetTableSchema(id, table)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -147,37 +311,206 @@ func GetTableSchema(manager *database.Manager) gin.HandlerFunc {
 	}
 }
 
-// GetTableData returns paginated table data
+// GetTableDetail returns a table's columns, indexes, constraints and
+// foreign keys
+func GetTableDetail(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		db := c.Param("db")
+		table := qualifiedParam(c, "table")
+		describer, err := sqlFeature[database.TableDescriber](manager, id, database.CapabilityTableDetail)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		detail, err := describer.GetTableDetail(id, db, table)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, detail)
+	}
+}
+
+// GetObjectDefinition returns the source of a view, routine, trigger or
+// sequence
+func GetObjectDefinition(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		db := c.Param("db")
+		kind := c.Param("type")
+		name := qualifiedParam(c, "name")
+		reader, err := sqlFeature[database.DefinitionReader](manager, id, database.CapabilityDefinitions)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		definitions, err := reader.GetObjectDefinition(id, db, kind, name)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		c.JSON(http.StatusOK, definitions)
+	}
+}
+
+// GetObjectDDL returns the CREATE statement of a table, view, routine,
+// trigger or sequence
+func GetObjectDDL(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		db := c.Param("db")
+		kind := c.Param("type")
+		name := qualifiedParam(c, "name")
+		generator, err := sqlFeature[database.DDLGenerator](manager, id, database.CapabilityDDL)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		ddl, err := generator.GetDDL(id, db, kind, name)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		c.JSON(http.StatusOK, ddl)
+	}
+}
+
+// GetTableData returns a page of a table's rows. Paging, sorting,
+// filtering and counting come from the query string, with sort and filter
+// as JSON, or from the JSON body of a POST.
 func GetTableData(manager *database.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
-		table := c.Param("table")
+		db := c.Param("db")
+		table := qualifiedParam(c, "table")
 
-		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "100"))
+		var req models.TableDataRequest
+		if c.Request.Method == http.MethodPost {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		} else if err := bindTableDataQuery(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		offset := (page - 1) * pageSize
-		query := "SELECT * FROM " + table + " LIMIT " + strconv.Itoa(pageSize) + " OFFSET " + strconv.Itoa(offset)
+		reader, err := sqlFeature[database.TableDataReader](manager, id, database.CapabilityTableData)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
 
-		result, err := manager.GetSQLDriver().ExecuteQuery(id, query)
+		ctx, queryID, done, err := manager.StartQuery(c.Request.Context(), id, req.QueryID, "SELECT * FROM "+table, time.Duration(req.Timeout)*time.Second)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, result)
+		defer done()
+
+		page, err := reader.GetTableData(ctx, id, db, table, req)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		page.QueryID = queryID
+		if req.RowFormat == "map" {
+			c.JSON(http.StatusOK, struct {
+				*models.QueryResult
+				models.TablePaging
+			}{page.MapResult(), page.TablePaging})
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// qualifiedParam returns the object a request addresses: the path
+// parameter, as name or schema.name, or the name in the schema the schema
+// query parameter names
+func qualifiedParam(c *gin.Context, param string) string {
+	name := c.Param(param)
+	if schema := c.Query("schema"); schema != "" {
+		return database.TableRef(schema, name)
+	}
+	return name
+}
+
+// bindTableDataQuery reads a table data request from the query string
+func bindTableDataQuery(c *gin.Context, req *models.TableDataRequest) error {
+	req.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	req.PageSize, _ = strconv.Atoi(c.DefaultQuery("page_size", "100"))
+	req.Timeout, _ = strconv.Atoi(c.Query("timeout"))
+	req.QueryID = c.Query("query_id")
+	req.RowFormat = c.Query("row_format")
+	req.After = c.Query("after")
+	req.Count = c.Query("count")
+
+	if sort := c.Query("sort"); sort != "" {
+		if err := json.Unmarshal([]byte(sort), &req.Sort); err != nil {
+			return fmt.Errorf("invalid sort: %w", err)
+		}
 	}
+	if filter := c.Query("filter"); filter != "" {
+		if err := json.Unmarshal([]byte(filter), &req.Filter); err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	return nil
 }
 
-// ListCollections lists MongoDB collections (placeholder)
+// ListCollections lists the collections of a document database
 func ListCollections(manager *database.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(http.StatusOK, []string{})
+		id := c.Param("id")
+		db := c.Param("db")
+
+		driver, err := documentDriver(manager, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		collections, err := driver.ListCollections(id, db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, collections)
 	}
 }
 
-// FindDocuments finds MongoDB documents (placeholder)
+// FindDocuments finds documents in a collection
 func FindDocuments(manager *database.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(http.StatusOK, []map[string]interface{}{})
+		var req models.DocumentFindRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		driver, err := documentDriver(manager, req.ConnectionID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		ctx, _, done, err := manager.StartQuery(c.Request.Context(), req.ConnectionID, req.QueryID, shellCall(req.Collection, "find", req.Filter), time.Duration(req.Timeout)*time.Second)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		defer done()
+
+		documents, err := driver.FindDocuments(ctx, req.ConnectionID, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, documents)
 	}
 }
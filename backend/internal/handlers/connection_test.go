@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"opendbm/internal/database"
+	"opendbm/internal/models"
+	"opendbm/internal/store"
+	"opendbm/internal/vault"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter serves the table and query routes over a SQLite connection
+// holding a table whose name needs quoting
+func newTestRouter(t *testing.T) (*gin.Engine, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	st, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	v, err := vault.Open(st, vault.Options{KeyFile: filepath.Join(dir, "master.key")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager, err := database.NewManager(st, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := manager.Connect(models.ConnectionConfig{
+		Name:     "test",
+		Type:     "sqlite",
+		Database: filepath.Join(dir, "test.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { manager.Disconnect(conn.ID) })
+
+	driver, err := sqlDriver(manager, conn.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE "we""ird té.x" (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`,
+		`CREATE INDEX "we""ird_name" ON "we""ird té.x" (name)`,
+		`INSERT INTO "we""ird té.x" VALUES (1, 'a'), (2, 'b'), (3, 'c')`,
+		`CREATE VIEW "we""ird.v" AS SELECT name FROM "we""ird té.x"`,
+	} {
+		result, err := driver.ExecuteSQL(context.Background(), conn.ID, "", stmt)
+		if err != nil || result.Error != "" {
+			t.Fatalf("%s: %v %s", stmt, err, result.Error)
+		}
+	}
+
+	r := gin.New()
+	api := r.Group("/api")
+	api.GET("/schemas/:id/:db", ListSchemas(manager))
+	api.GET("/tables/:id/:db", ListTables(manager))
+	api.GET("/tables/:id/:db/:table", GetTableDetail(manager))
+	api.GET("/schema/:id/:table", GetTableSchema(manager))
+	api.GET("/definition/:id/:db/:type/:name", GetObjectDefinition(manager))
+	api.GET("/ddl/:id/:db/:type/:name", GetObjectDDL(manager))
+	api.GET("/data/:id/:db/:table", GetTableData(manager))
+	api.POST("/data/:id/:db/:table", GetTableData(manager))
+	api.POST("/query/stream", StreamQuery(manager))
+	api.POST("/sessions/:id", OpenSession(manager))
+	api.DELETE("/sessions/:id/:sessionId", CloseSession(manager))
+	api.POST("/sessions/:id/:sessionId/begin", BeginTransaction(manager))
+	api.POST("/sessions/:id/:sessionId/commit", CommitTransaction(manager))
+	api.POST("/sessions/:id/:sessionId/rollback", RollbackTransaction(manager))
+	return r, conn.ID
+}
+
+// serve sends a request, decoding a successful JSON response into out
+func serve(t *testing.T, r *gin.Engine, method string, target string, body string, out interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if out != nil && (w.Code == http.StatusOK || w.Code == http.StatusCreated) {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: %v", method, target, err)
+		}
+	}
+	return w.Code
+}
+
+func TestSchemaRoutes(t *testing.T) {
+	r, id := newTestRouter(t)
+
+	var schemas []string
+	if code := serve(t, r, http.MethodGet, "/api/schemas/"+id+"/main", "", &schemas); code != http.StatusOK || len(schemas) != 1 || schemas[0] != "main" {
+		t.Errorf("schemas = %d %v, want main", code, schemas)
+	}
+
+	for _, target := range []string{"/api/tables/" + id + "/main", "/api/tables/" + id + "/main?schema=main"} {
+		var tables []models.TableInfo
+		if code := serve(t, r, http.MethodGet, target, "", &tables); code != http.StatusOK {
+			t.Fatalf("%s = %d", target, code)
+		}
+		if len(tables) != 2 || tables[0].Name != `we"ird té.x` || tables[0].Schema != "main" || tables[1].Type != "view" {
+			t.Errorf("%s = %+v", target, tables)
+		}
+	}
+	if code := serve(t, r, http.MethodGet, "/api/tables/"+id+"/main?schema=missing", "", nil); code != http.StatusInternalServerError {
+		t.Errorf("tables of an unknown schema = %d, want 500", code)
+	}
+}
+
+func TestDefinitionRoute(t *testing.T) {
+	r, id := newTestRouter(t)
+	view := url.PathEscape(database.TableRef("", `we"ird.v`))
+
+	var defs []models.ObjectDefinition
+	if code := serve(t, r, http.MethodGet, "/api/definition/"+id+"/main/view/"+view, "", &defs); code != http.StatusOK {
+		t.Fatalf("view definition = %d", code)
+	}
+	if len(defs) != 1 || defs[0].Name != `we"ird.v` || !strings.HasPrefix(defs[0].Definition, "CREATE VIEW") {
+		t.Errorf("view definition = %+v", defs)
+	}
+
+	statuses := []struct {
+		name   string
+		target string
+		want   int
+	}{
+		{"missing view", "/api/definition/" + id + "/main/view/missing", http.StatusNotFound},
+		{"table", "/api/definition/" + id + "/main/table/" + view, http.StatusBadRequest},
+		{"unknown kind", "/api/definition/" + id + "/main/index/" + view, http.StatusBadRequest},
+	}
+	for _, tt := range statuses {
+		if code := serve(t, r, http.MethodGet, tt.target, "", nil); code != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, code, tt.want)
+		}
+	}
+}
+
+func TestTableRoutes(t *testing.T) {
+	r, id := newTestRouter(t)
+	name := `we"ird té.x`
+	// The table as a path segment, either quoted or named in ?schema=
+	quoted := url.PathEscape(database.TableRef("main", name))
+	bare := url.PathEscape(name) + "?schema=main"
+
+	for _, table := range []string{quoted, bare} {
+		var detail models.TableDetail
+		if code := serve(t, r, http.MethodGet, "/api/tables/"+id+"/main/"+table, "", &detail); code != http.StatusOK {
+			t.Fatalf("detail of %s = %d", table, code)
+		}
+		if detail.Name != name || len(detail.Columns) != 2 || len(detail.Indexes) == 0 {
+			t.Errorf("detail of %s = %+v", table, detail)
+		}
+
+		var columns []models.ColumnInfo
+		if code := serve(t, r, http.MethodGet, "/api/schema/"+id+"/"+table, "", &columns); code != http.StatusOK {
+			t.Fatalf("schema of %s = %d", table, code)
+		}
+		if len(columns) != 2 || columns[0].Name != "id" {
+			t.Errorf("schema of %s = %+v", table, columns)
+		}
+
+		var ddl models.ObjectDDL
+		if code := serve(t, r, http.MethodGet, "/api/ddl/"+id+"/main/table/"+table, "", &ddl); code != http.StatusOK {
+			t.Fatalf("ddl of %s = %d", table, code)
+		}
+		if !strings.Contains(ddl.DDL, `CREATE TABLE "we""ird té.x"`) {
+			t.Errorf("ddl of %s = %q", table, ddl.DDL)
+		}
+	}
+
+	var page models.TablePage
+	target := "/api/data/" + id + "/main/" + quoted
+	body := `{"page_size": 2, "sort": [{"column": "id", "direction": "desc"}], "count": "exact"}`
+	if code := serve(t, r, http.MethodPost, target, body, &page); code != http.StatusOK {
+		t.Fatalf("data = %d", code)
+	}
+	if len(page.Rows) != 2 || page.NextCursor == "" || page.TotalCount == nil || *page.TotalCount != 3 {
+		t.Fatalf("first page = %+v", page)
+	}
+
+	var next models.TablePage
+	query := url.Values{"page_size": {"2"}, "sort": {`[{"column": "id", "direction": "desc"}]`}, "after": {page.NextCursor}}
+	if code := serve(t, r, http.MethodGet, target+"?"+query.Encode(), "", &next); code != http.StatusOK {
+		t.Fatalf("next page = %d", code)
+	}
+	if len(next.Rows) != 1 || next.NextCursor != "" || next.Rows[0][0] != float64(1) {
+		t.Errorf("next page = %+v", next)
+	}
+
+	statuses := []struct {
+		name   string
+		target string
+		body   string
+		want   int
+	}{
+		{"bad filter", target, `{"filter": {"column": "id", "op": "near", "value": 1}}`, http.StatusBadRequest},
+		{"bad cursor", target, `{"after": "garbage"}`, http.StatusBadRequest},
+		{"unterminated name", "/api/data/" + id + "/main/" + url.PathEscape(`"t`), `{}`, http.StatusBadRequest},
+		{"unknown connection", "/api/data/unknown/main/t", `{}`, http.StatusInternalServerError},
+	}
+	for _, tt := range statuses {
+		if code := serve(t, r, http.MethodPost, tt.target, tt.body, nil); code != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, code, tt.want)
+		}
+	}
+
+	// Statement errors are reported in the page, as for queries
+	var missing models.TablePage
+	if code := serve(t, r, http.MethodPost, "/api/data/"+id+"/main/missing", `{}`, &missing); code != http.StatusOK || missing.Error == "" {
+		t.Errorf("missing table = %d %+v, want an error in the page", code, missing)
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"opendbm/internal/database"
+	"opendbm/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// documentWriter is one of the write methods of a DocumentDriver
+type documentWriter func(database.DocumentDriver, context.Context, string, models.DocumentWriteRequest) (*models.QueryResult, error)
+
+// InsertDocument inserts a single document
+func InsertDocument(manager *database.Manager) gin.HandlerFunc {
+	return writeDocuments(manager, "insertOne", database.DocumentDriver.InsertOne)
+}
+
+// InsertDocuments inserts several documents
+func InsertDocuments(manager *database.Manager) gin.HandlerFunc {
+	return writeDocuments(manager, "insertMany", database.DocumentDriver.InsertMany)
+}
+
+// UpdateDocument updates the first document matching a filter
+func UpdateDocument(manager *database.Manager) gin.HandlerFunc {
+	return writeDocuments(manager, "updateOne", database.DocumentDriver.UpdateOne)
+}
+
+// UpdateDocuments updates every document matching a filter
+func UpdateDocuments(manager *database.Manager) gin.HandlerFunc {
+	return writeDocuments(manager, "updateMany", database.DocumentDriver.UpdateMany)
+}
+
+// ReplaceDocument replaces the first document matching a filter
+func ReplaceDocument(manager *database.Manager) gin.HandlerFunc {
+	return writeDocuments(manager, "replaceOne", database.DocumentDriver.ReplaceOne)
+}
+
+// DeleteDocument deletes the first document matching a filter
+func DeleteDocument(manager *database.Manager) gin.HandlerFunc {
+	return writeDocuments(manager, "deleteOne", database.DocumentDriver.DeleteOne)
+}
+
+// DeleteDocuments deletes every document matching a filter
+func DeleteDocuments(manager *database.Manager) gin.HandlerFunc {
+	return writeDocuments(manager, "deleteMany", database.DocumentDriver.DeleteMany)
+}
+
+func writeDocuments(manager *database.Manager, method string, write documentWriter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.DocumentWriteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		driver, err := documentDriver(manager, req.ConnectionID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		ctx, queryID, done, err := manager.StartQuery(c.Request.Context(), req.ConnectionID, req.QueryID, shellCall(req.Collection, method), time.Duration(req.Timeout)*time.Second)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		defer done()
+
+		result, err := write(driver, ctx, req.ConnectionID, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		result.QueryID = queryID
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// AggregateDocuments runs an aggregation pipeline
+func AggregateDocuments(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.DocumentAggregateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		driver, err := documentDriver(manager, req.ConnectionID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		ctx, queryID, done, err := manager.StartQuery(c.Request.Context(), req.ConnectionID, req.QueryID, shellCall(req.Collection, "aggregate", req.Pipeline), time.Duration(req.Timeout)*time.Second)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		defer done()
+
+		result, err := driver.Aggregate(ctx, req.ConnectionID, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		result.QueryID = queryID
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// RunDocumentCommand runs a mongo shell style command
+func RunDocumentCommand(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.DocumentCommandRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		driver, err := documentDriver(manager, req.ConnectionID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		ctx, queryID, done, err := manager.StartQuery(c.Request.Context(), req.ConnectionID, req.QueryID, req.Command, time.Duration(req.Timeout)*time.Second)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		defer done()
+
+		result, err := driver.RunCommand(ctx, req.ConnectionID, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		result.QueryID = queryID
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// shellCall spells a document operation as the shell command it runs, for
+// the list of running queries
+func shellCall(collection string, method string, args ...json.RawMessage) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = string(arg)
+	}
+	return "db." + collection + "." + method + "(" + strings.Join(parts, ", ") + ")"
+}
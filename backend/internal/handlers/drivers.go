@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+
+	"opendbm/internal/database"
+	"opendbm/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// databaseLister is implemented by SQL and document drivers alike
+type databaseLister interface {
+	ListDatabases(id string) ([]string, error)
+}
+
+// ListDrivers returns the registered database types and their capabilities
+func ListDrivers(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		drivers := make([]gin.H, 0)
+		for _, dbType := range database.RegisteredTypes() {
+			caps, err := manager.TypeCapabilities(dbType)
+			if err != nil {
+				continue
+			}
+			drivers = append(drivers, gin.H{"type": dbType, "capabilities": caps})
+		}
+		c.JSON(http.StatusOK, drivers)
+	}
+}
+
+// sqlDriver returns the SQL capability of a connection's driver
+func sqlDriver(manager *database.Manager, id string) (database.SQLDriver, error) {
+	driver, err := manager.Driver(id)
+	if err != nil {
+		return nil, err
+	}
+	sqlDriver, ok := driver.(database.SQLDriver)
+	if !ok {
+		return nil, capabilityError(manager, id, driver, database.CapabilitySQL)
+	}
+	return sqlDriver, nil
+}
+
+// documentDriver returns the document capability of a connection's driver
+func documentDriver(manager *database.Manager, id string) (database.DocumentDriver, error) {
+	driver, err := manager.Driver(id)
+	if err != nil {
+		return nil, err
+	}
+	documentDriver, ok := driver.(database.DocumentDriver)
+	if !ok {
+		return nil, capabilityError(manager, id, driver, database.CapabilityDocument)
+	}
+	return documentDriver, nil
+}
+
+func capabilityError(manager *database.Manager, id string, driver database.Driver, capability string) error {
+	dbType := ""
+	if conn, err := manager.GetConnection(id); err == nil {
+		dbType = conn.Type
+	}
+	return &database.CapabilityError{
+		Type:         dbType,
+		Capability:   capability,
+		Capabilities: database.Capabilities(driver),
+	}
+}
+
+func keyValueDriver(manager *database.Manager, id string) (database.KeyValueDriver, error) {
+	driver, err := manager.Driver(id)
+	if err != nil {
+		return nil, err
+	}
+
+	keyValueDriver, ok := driver.(database.KeyValueDriver)
+	if !ok {
+		return nil, capabilityError(manager, id, driver, database.CapabilityKeyValue)
+	}
+	return keyValueDriver, nil
+}
+
+// sqlFeature returns one of the optional interfaces of a connection's SQL
+// driver, such as database.Explainer
+func sqlFeature[T any](manager *database.Manager, id string, capability string) (T, error) {
+	var none T
+	driver, err := sqlDriver(manager, id)
+	if err != nil {
+		return none, err
+	}
+	feature, ok := driver.(T)
+	if !ok {
+		return none, capabilityError(manager, id, driver, capability)
+	}
+	return feature, nil
+}
+
+// bindParams binds a request's parameters. Drivers without
+// database.ParamBinder can still run statements that have none.
+func bindParams(manager *database.Manager, driver database.SQLDriver, id string, sql string, params []models.QueryParam) (string, []interface{}, error) {
+	binder, ok := driver.(database.ParamBinder)
+	if !ok {
+		if len(params) > 0 {
+			return "", nil, capabilityError(manager, id, driver, database.CapabilityParams)
+		}
+		return sql, nil, nil
+	}
+	return binder.BindParams(id, sql, params)
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"opendbm/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorResponse builds an error body, adding a machine readable code for
+// errors the UI can act on such as untrusted SSH host keys
+func errorResponse(err error) gin.H {
+	body := gin.H{"error": err.Error()}
+
+	var hostKeyErr *database.HostKeyError
+	if errors.As(err, &hostKeyErr) {
+		body["code"] = hostKeyErr.Code
+		body["hostKey"] = hostKeyErr
+	}
+
+	var capErr *database.CapabilityError
+	if errors.As(err, &capErr) {
+		body["code"] = "capability_not_supported"
+		body["capabilities"] = capErr.Capabilities
+	}
+	return body
+}
+
+// respondError writes an error response, answering 501 when the connection's
+// driver does not support the endpoint, 404 for unknown sessions, 409 for
+// busy ones or a transaction in the wrong state and 400 for invalid requests
+func respondError(c *gin.Context, status int, err error) {
+	var capErr *database.CapabilityError
+	switch {
+	case errors.As(err, &capErr):
+		status = http.StatusNotImplemented
+	case errors.Is(err, database.ErrSessionNotFound), errors.Is(err, database.ErrObjectNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, database.ErrSessionBusy), errors.Is(err, database.ErrTransactionOpen),
+		errors.Is(err, database.ErrNoTransaction):
+		status = http.StatusConflict
+	case errors.Is(err, database.ErrInvalidRequest):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, errorResponse(err))
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"opendbm/internal/database"
+	"opendbm/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExplainQuery returns the execution plan of a query as a tree of steps
+// common to all SQL databases, running the query first when analyze is set
+func ExplainQuery(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.QueryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		driver, err := sqlDriver(manager, req.ConnectionID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		explainer, ok := driver.(database.Explainer)
+		if !ok {
+			respondError(c, http.StatusNotImplemented, capabilityError(manager, req.ConnectionID, driver, database.CapabilityExplain))
+			return
+		}
+
+		query, args, err := bindParams(manager, driver, req.ConnectionID, req.SQL, req.Params)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx, queryID, done, err := manager.StartQuery(c.Request.Context(), req.ConnectionID, req.QueryID, req.SQL, time.Duration(req.Timeout)*time.Second)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		defer done()
+
+		plan, err := explainer.Explain(ctx, req.ConnectionID, req.SessionID, query, req.Analyze, args...)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		plan.QueryID = queryID
+		c.JSON(http.StatusOK, plan)
+	}
+}
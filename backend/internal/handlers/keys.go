@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"net/http"
+
+	"opendbm/internal/database"
+	"opendbm/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScanKeys returns one page of keys with their types and TTLs
+func ScanKeys(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.KeyScanRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		driver, err := keyValueDriver(manager, req.ConnectionID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		result, err := driver.ScanKeys(req.ConnectionID, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetKey reads the value of a key according to its type
+func GetKey(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.KeyGetRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		driver, err := keyValueDriver(manager, req.ConnectionID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		value, err := driver.GetKey(req.ConnectionID, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, value)
+	}
+}
+
+// WriteKey applies a type specific write to a key
+func WriteKey(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.KeyWriteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		driver, err := keyValueDriver(manager, req.ConnectionID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if err := driver.WriteKey(req.ConnectionID, req); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// DeleteKeys deletes keys
+func DeleteKeys(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.KeyDeleteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		driver, err := keyValueDriver(manager, req.ConnectionID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		deleted, err := driver.DeleteKeys(req.ConnectionID, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "deleted": deleted})
+	}
+}
+
+// SetKeyTTL sets or removes the expiry of a key
+func SetKeyTTL(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.KeyTTLRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		driver, err := keyValueDriver(manager, req.ConnectionID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if err := driver.SetTTL(req.ConnectionID, req); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// ExecuteKeyCommand runs a raw command from the console
+func ExecuteKeyCommand(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.KeyCommandRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		driver, err := keyValueDriver(manager, req.ConnectionID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		result, err := driver.ExecuteCommand(req.ConnectionID, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
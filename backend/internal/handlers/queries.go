@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"opendbm/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListQueries returns the queries currently running
+func ListQueries(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, manager.Queries().List())
+	}
+}
+
+// CancelQuery stops a running query
+func CancelQuery(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		queryID := c.Param("queryId")
+		if err := manager.Queries().Cancel(queryID); err != nil {
+			if errors.Is(err, database.ErrQueryNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
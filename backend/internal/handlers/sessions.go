@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"opendbm/internal/database"
+	"opendbm/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenSession pins a connection for interactive use; queries naming the
+// session run on it
+func OpenSession(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		var req models.SessionRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		driver, err := sqlFeature[database.SessionDriver](manager, id, database.CapabilitySessions)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		session, err := driver.OpenSession(c.Request.Context(), id, time.Duration(req.IdleTimeout)*time.Second)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusCreated, session)
+	}
+}
+
+// ListSessions lists the open sessions of a connection
+func ListSessions(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		driver, err := sqlFeature[database.SessionDriver](manager, id, database.CapabilitySessions)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, driver.ListSessions(id))
+	}
+}
+
+// CloseSession closes a session, rolling back its open transaction
+func CloseSession(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		driver, err := sqlFeature[database.SessionDriver](manager, id, database.CapabilitySessions)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if err := driver.CloseSession(id, c.Param("sessionId")); err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// BeginTransaction starts a transaction on a session
+func BeginTransaction(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		driver, err := sqlFeature[database.SessionDriver](manager, id, database.CapabilitySessions)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		session, err := driver.BeginTransaction(c.Request.Context(), id, c.Param("sessionId"))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, session)
+	}
+}
+
+// CommitTransaction commits a session's open transaction
+func CommitTransaction(manager *database.Manager) gin.HandlerFunc {
+	return endTransaction(manager, database.SessionDriver.Commit)
+}
+
+// RollbackTransaction rolls back a session's open transaction
+func RollbackTransaction(manager *database.Manager) gin.HandlerFunc {
+	return endTransaction(manager, database.SessionDriver.Rollback)
+}
+
+// transactionEnder is Commit or Rollback of a SessionDriver
+type transactionEnder func(database.SessionDriver, string, string) (*models.SessionInfo, error)
+
+func endTransaction(manager *database.Manager, end transactionEnder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		driver, err := sqlFeature[database.SessionDriver](manager, id, database.CapabilitySessions)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		session, err := end(driver, id, c.Param("sessionId"))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, session)
+	}
+}
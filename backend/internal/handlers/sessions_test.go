@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"opendbm/internal/models"
+)
+
+func TestSessionRoutes(t *testing.T) {
+	r, id := newTestRouter(t)
+
+	var session models.SessionInfo
+	if code := serve(t, r, http.MethodPost, "/api/sessions/"+id, `{"idle_timeout": 60}`, &session); code != http.StatusCreated || session.IdleTimeout != 60 {
+		t.Fatalf("opening a session = %d %+v", code, session)
+	}
+	base := "/api/sessions/" + id + "/" + session.ID
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{"commit without a transaction", http.MethodPost, "/commit", http.StatusConflict},
+		{"rollback without a transaction", http.MethodPost, "/rollback", http.StatusConflict},
+		{"begin", http.MethodPost, "/begin", http.StatusOK},
+		{"begin twice", http.MethodPost, "/begin", http.StatusConflict},
+		{"commit", http.MethodPost, "/commit", http.StatusOK},
+		{"close", http.MethodDelete, "", http.StatusOK},
+		{"begin on a closed session", http.MethodPost, "/begin", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code := serve(t, r, tt.method, base+tt.path, "", nil); code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"opendbm/internal/database"
+	"opendbm/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListTunnels returns all saved SSH tunnels
+func ListTunnels(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, manager.Tunnels().List())
+	}
+}
+
+// GetTunnel returns a single saved SSH tunnel
+func GetTunnel(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tunnel, err := manager.Tunnels().Get(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, tunnel)
+	}
+}
+
+// CreateTunnel saves a new SSH tunnel
+func CreateTunnel(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var config models.SSHTunnelConfig
+		if err := c.ShouldBindJSON(&config); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		tunnel, err := manager.Tunnels().Create(config)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, tunnel)
+	}
+}
+
+// UpdateTunnel replaces a saved SSH tunnel
+func UpdateTunnel(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		var config models.SSHTunnelConfig
+		if err := c.ShouldBindJSON(&config); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if _, err := manager.Tunnels().Get(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		tunnel, err := manager.Tunnels().Update(id, config)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, tunnel)
+	}
+}
+
+// DeleteTunnel removes a saved SSH tunnel that no connection uses
+func DeleteTunnel(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		if manager.TunnelInUse(id) {
+			c.JSON(http.StatusConflict, gin.H{"error": "ssh tunnel is used by a saved connection"})
+			return
+		}
+
+		if err := manager.Tunnels().Delete(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// TestTunnel tests an SSH tunnel config without saving it
+func TestTunnel(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var config models.SSHTunnelConfig
+		if err := c.ShouldBindJSON(&config); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := manager.Tunnels().Test(config); err != nil {
+			body := errorResponse(err)
+			body["success"] = false
+			c.JSON(http.StatusOK, body)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// TestSavedTunnel tests a saved SSH tunnel
+func TestSavedTunnel(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tunnel, err := manager.Tunnels().Get(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := manager.Tunnels().Test(*tunnel); err != nil {
+			body := errorResponse(err)
+			body["success"] = false
+			c.JSON(http.StatusOK, body)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
+
+// ListKnownHosts returns all trusted SSH host keys
+func ListKnownHosts(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hosts, err := manager.Tunnels().KnownHosts().List()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, hosts)
+	}
+}
+
+// ListPendingHostKeys returns host keys seen for the first time that await a trust decision
+func ListPendingHostKeys(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, manager.Tunnels().KnownHosts().Pending())
+	}
+}
+
+// TrustHostKey trusts a pending host key the user has confirmed
+func TrustHostKey(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Host        string `json:"host" binding:"required"`
+			Fingerprint string `json:"fingerprint" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		known, err := manager.Tunnels().KnownHosts().Trust(req.Host, req.Fingerprint)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, known)
+	}
+}
+
+// AddKnownHost trusts a host key supplied in authorized_keys format
+func AddKnownHost(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Host      string `json:"host" binding:"required"`
+			PublicKey string `json:"publicKey" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		known, err := manager.Tunnels().KnownHosts().Add(req.Host, req.PublicKey)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, known)
+	}
+}
+
+// DeleteKnownHost removes a trusted host key
+func DeleteKnownHost(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid known host id"})
+			return
+		}
+
+		if err := manager.Tunnels().KnownHosts().Remove(uint(id)); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	}
+}
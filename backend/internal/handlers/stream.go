@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"opendbm/internal/database"
+	"opendbm/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StreamQuery executes a SQL query and writes its rows to the client as
+// they are read instead of buffering the whole result. The response is
+// NDJSON, or server-sent events when the client accepts text/event-stream
+// or asks for format=sse. Streamed rows are always in column order.
+func StreamQuery(manager *database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.QueryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		driver, err := sqlDriver(manager, req.ConnectionID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		streamer, ok := driver.(database.QueryStreamer)
+		if !ok {
+			respondError(c, http.StatusNotImplemented, capabilityError(manager, req.ConnectionID, driver, database.CapabilityStreaming))
+			return
+		}
+
+		query, args, err := bindParams(manager, driver, req.ConnectionID, req.SQL, req.Params)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		ctx, queryID, done, err := manager.StartQuery(c.Request.Context(), req.ConnectionID, req.QueryID, req.SQL, time.Duration(req.Timeout)*time.Second)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		defer done()
+
+		maxRows := database.MaxStreamRows
+		if req.MaxRows > 0 && req.MaxRows < maxRows {
+			maxRows = req.MaxRows
+		}
+
+		w := &streamWriter{
+			c:       c,
+			queryID: queryID,
+			sse:     c.Query("format") == "sse" || strings.Contains(c.GetHeader("Accept"), "text/event-stream"),
+		}
+		trailer, err := streamer.StreamQuery(ctx, req.ConnectionID, req.SessionID, query, maxRows, w, args...)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		// A statement that fails before returning columns still gets a header
+		if !w.started {
+			w.Header([]models.ResultColumn{})
+		}
+		trailer.Type = "trailer"
+		w.send(trailer.Type, trailer)
+	}
+}
+
+// streamWriter writes a streamed query result to the response, flushing
+// after every message
+type streamWriter struct {
+	c       *gin.Context
+	queryID string
+	sse     bool
+	started bool
+}
+
+func (w *streamWriter) Header(columns []models.ResultColumn) error {
+	header := w.c.Writer.Header()
+	if w.sse {
+		header.Set("Content-Type", "text/event-stream")
+	} else {
+		header.Set("Content-Type", "application/x-ndjson")
+	}
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	w.c.Status(http.StatusOK)
+	w.started = true
+
+	return w.send("header", models.QueryStreamHeader{
+		Type:    "header",
+		QueryID: w.queryID,
+		Columns: columns,
+	})
+}
+
+func (w *streamWriter) Rows(rows [][]interface{}) error {
+	return w.send("rows", models.QueryStreamRows{Type: "rows", Rows: rows})
+}
+
+func (w *streamWriter) send(event string, msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if w.sse {
+		_, err = fmt.Fprintf(w.c.Writer, "event: %s\ndata: %s\n\n", event, data)
+	} else {
+		_, err = w.c.Writer.Write(append(data, '\n'))
+	}
+	if err != nil {
+		return err
+	}
+	w.c.Writer.Flush()
+	return nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStreamQueryRoute(t *testing.T) {
+	r, id := newTestRouter(t)
+	body := `{"connection_id": "` + id + `", "sql": "SELECT id, name FROM \"we\"\"ird té.x\" ORDER BY id", "max_rows": 2}`
+
+	t.Run("ndjson", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/query/stream", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+			t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+		}
+
+		var types []string
+		var rows int
+		var trailer struct {
+			RowCount  int  `json:"rowCount"`
+			Truncated bool `json:"truncated"`
+		}
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var msg struct {
+				Type    string          `json:"type"`
+				QueryID string          `json:"queryId"`
+				Rows    [][]interface{} `json:"rows"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				t.Fatalf("line %q: %v", scanner.Text(), err)
+			}
+			types = append(types, msg.Type)
+			rows += len(msg.Rows)
+			if msg.Type == "header" && msg.QueryID == "" {
+				t.Error("header has no query ID")
+			}
+			if msg.Type == "trailer" {
+				json.Unmarshal(scanner.Bytes(), &trailer)
+			}
+		}
+		if strings.Join(types, ",") != "header,rows,trailer" || rows != 2 || trailer.RowCount != 2 || !trailer.Truncated {
+			t.Errorf("messages %v with %d rows, trailer %+v; want header, rows and a truncated trailer of 2 rows", types, rows, trailer)
+		}
+	})
+
+	t.Run("sse", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/query/stream?format=sse", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+		}
+		var events []string
+		for _, line := range strings.Split(w.Body.String(), "\n") {
+			if event, ok := strings.CutPrefix(line, "event: "); ok {
+				events = append(events, event)
+			}
+		}
+		if strings.Join(events, ",") != "header,rows,trailer" {
+			t.Errorf("events = %v, want header, rows and trailer", events)
+		}
+	})
+
+	t.Run("statement error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/query/stream", strings.NewReader(`{"connection_id": "`+id+`", "sql": "SELECT * FROM missing"}`))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if w.Code != http.StatusOK || len(lines) != 2 || !strings.Contains(lines[1], `"error"`) {
+			t.Errorf("status %d with %q, want a header and a trailer with the error", w.Code, lines)
+		}
+	})
+}
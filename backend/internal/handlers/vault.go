@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"opendbm/internal/vault"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VaultStatus reports the master key in use and how many secrets it protects
+func VaultStatus(v *vault.Vault) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := v.Status()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// RotateVaultKey replaces the master key and re-encrypts every secret
+func RotateVaultKey(v *vault.Vault) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := v.Rotate()
+		if errors.Is(err, vault.ErrKeyFromEnv) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// ReEncryptVault re-encrypts secrets still held under an older master key
+func ReEncryptVault(v *vault.Vault) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		count, err := v.ReEncrypt()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "reencrypted": count})
+	}
+}
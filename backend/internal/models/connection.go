@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // ConnectionConfig represents the configuration for a database connection
 type ConnectionConfig struct {
@@ -10,10 +13,49 @@ type ConnectionConfig struct {
 	Host     string `json:"host"`
 	Port     int    `json:"port"`
 	Username string `json:"username"`
-	Password string `json:"password"`
+	Password string `json:"password,omitempty"` // write-only, never returned by the API
 	Database string `json:"database"`
-	SSL      bool   `json:"ssl,omitempty"`
-	GroupID  string `json:"groupId,omitempty"`
+	// URI is a full connection string, e.g. mongodb+srv://user@cluster.example.com/db,
+	// used instead of Host and Port by drivers that support it. An embedded
+	// password is moved into the credential vault.
+	URI        string `json:"uri,omitempty"`
+	AuthSource string `json:"authSource,omitempty"`
+	// Oracle connects to ServiceName, or to SID on older instances. An empty
+	// ServiceName falls back to Database.
+	ServiceName string `json:"serviceName,omitempty"`
+	SID         string `json:"sid,omitempty"`
+	SSL         bool   `json:"ssl,omitempty"`
+	GroupID     string `json:"groupId,omitempty"`
+	// QueryTimeout in seconds applies to queries that set no timeout of their own
+	QueryTimeout int `json:"queryTimeout,omitempty"`
+
+	// TLS settings. SSLMode is one of disable, require, verify-ca or
+	// verify-full; when empty it is derived from SSL and SSLRejectUnauthorized.
+	// Certificates can be given as file paths on the server or as PEM content.
+	SSLMode               string `json:"sslMode,omitempty"`
+	SSLRejectUnauthorized *bool  `json:"sslRejectUnauthorized,omitempty"`
+	SSLCAPath             string `json:"sslCaPath,omitempty"`
+	SSLCertPath           string `json:"sslCertPath,omitempty"`
+	SSLKeyPath            string `json:"sslKeyPath,omitempty"`
+	SSLCA                 string `json:"sslCa,omitempty"`
+	SSLCert               string `json:"sslCert,omitempty"`
+	SSLKey                string `json:"sslKey,omitempty"` // write-only, kept in the credential vault
+	HasSSLKey             bool   `json:"hasSslKey"`
+	SSLKeyRef             string `json:"-"`
+
+	// Reusable SSH tunnel. SSHHops lists tunnel IDs to pass through in order,
+	// e.g. a bastion followed by an internal jump box, and takes precedence
+	// over the single SSHTunnelID.
+	UseSSH      bool     `json:"useSsh,omitempty"`
+	SSHTunnelID string   `json:"sshTunnelId,omitempty"`
+	SSHHops     []string `json:"sshHops,omitempty"`
+
+	// HasPassword reports whether a password is stored in the credential vault
+	HasPassword bool `json:"hasPassword"`
+	// ClearPassword removes the stored password when updating a connection
+	ClearPassword bool `json:"clearPassword,omitempty"`
+	// PasswordRef points at the vault entry holding the password
+	PasswordRef string `json:"-"`
 }
 
 // Connection represents an active database connection
@@ -25,16 +67,93 @@ type Connection struct {
 	Error           string     `json:"error,omitempty"`
 }
 
+// QueryParam is a value bound to a query placeholder. Named parameters fill
+// :name placeholders; unnamed ones fill the dialect's own positional
+// placeholders ($1, ?, @p1 or :1) in order. Type is one of integer, number,
+// decimal, boolean, string, datetime, json or binary (base64); without one
+// the type follows the JSON value.
+type QueryParam struct {
+	Name  string          `json:"name,omitempty"`
+	Type  string          `json:"type,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
 // QueryRequest represents a query execution request
 type QueryRequest struct {
 	ConnectionID string `json:"connection_id"`
 	SQL          string `json:"sql"`
+	// SessionID runs the query on a session instead of a pooled connection
+	SessionID string `json:"session_id,omitempty"`
+	// Params are bound to the query's placeholders
+	Params []QueryParam `json:"params,omitempty"`
+	// QueryID lets the client cancel the query while it runs; one is
+	// generated when empty
+	QueryID string `json:"query_id,omitempty"`
+	Timeout int    `json:"timeout,omitempty"` // seconds, overrides the connection's query timeout
+	// MaxRows lowers the server's row cap for this query
+	MaxRows int `json:"max_rows,omitempty"`
+	// RowFormat "map" returns rows keyed by column name instead of in
+	// column order
+	RowFormat string `json:"row_format,omitempty"`
+	// OnError is "stop" (the default) or "continue" for scripts
+	OnError string `json:"on_error,omitempty"`
+	// Analyze runs an explained query to report actual rows and timings
+	Analyze bool `json:"analyze,omitempty"`
 }
 
-// DocumentFindRequest represents a MongoDB find request
+// DocumentFindRequest represents a MongoDB find request. Filter, Projection
+// and Sort are MongoDB Extended JSON documents so typed values such as
+// {"$oid": "..."} or {"$date": "..."} survive the round trip.
 type DocumentFindRequest struct {
-	ConnectionID string                 `json:"connection_id"`
-	Database     string                 `json:"database"`
-	Collection   string                 `json:"collection"`
-	Filter       map[string]interface{} `json:"filter"`
+	ConnectionID string          `json:"connection_id"`
+	Database     string          `json:"database"`
+	Collection   string          `json:"collection"`
+	Filter       json.RawMessage `json:"filter,omitempty"`
+	Projection   json.RawMessage `json:"projection,omitempty"`
+	Sort         json.RawMessage `json:"sort,omitempty"`
+	Skip         int64           `json:"skip,omitempty"`
+	Limit        int64           `json:"limit,omitempty"`
+	Canonical    bool            `json:"canonical,omitempty"` // canonical instead of relaxed Extended JSON output
+	// QueryID and Timeout work as they do for SQL queries
+	QueryID string `json:"query_id,omitempty"`
+	Timeout int    `json:"timeout,omitempty"` // seconds
+}
+
+// DocumentWriteRequest represents a MongoDB insert, update, replace or delete.
+// Document is the document to insert or the replacement, Documents feeds
+// insertMany and Update is an update document or an aggregation pipeline.
+type DocumentWriteRequest struct {
+	ConnectionID string            `json:"connection_id"`
+	Database     string            `json:"database"`
+	Collection   string            `json:"collection"`
+	Filter       json.RawMessage   `json:"filter,omitempty"`
+	Document     json.RawMessage   `json:"document,omitempty"`
+	Documents    []json.RawMessage `json:"documents,omitempty"`
+	Update       json.RawMessage   `json:"update,omitempty"`
+	Upsert       bool              `json:"upsert,omitempty"`
+	Canonical    bool              `json:"canonical,omitempty"`
+	QueryID      string            `json:"query_id,omitempty"`
+	Timeout      int               `json:"timeout,omitempty"` // seconds
+}
+
+// DocumentAggregateRequest represents a MongoDB aggregation pipeline run
+type DocumentAggregateRequest struct {
+	ConnectionID string          `json:"connection_id"`
+	Database     string          `json:"database"`
+	Collection   string          `json:"collection"`
+	Pipeline     json.RawMessage `json:"pipeline"`
+	Canonical    bool            `json:"canonical,omitempty"`
+	QueryID      string          `json:"query_id,omitempty"`
+	Timeout      int             `json:"timeout,omitempty"` // seconds
+}
+
+// DocumentCommandRequest represents a mongo shell style command such as
+// db.users.find({age: {$gt: 21}}).limit(10)
+type DocumentCommandRequest struct {
+	ConnectionID string `json:"connection_id"`
+	Database     string `json:"database"`
+	Command      string `json:"command"`
+	Canonical    bool   `json:"canonical,omitempty"`
+	QueryID      string `json:"query_id,omitempty"`
+	Timeout      int    `json:"timeout,omitempty"` // seconds
 }
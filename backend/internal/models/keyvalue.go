@@ -0,0 +1,163 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// BinaryString is a Redis string, which may hold any bytes. It is sent as a
+// JSON string when it is valid UTF-8 and as a BinaryValue otherwise, and is
+// read from either form.
+type BinaryString string
+
+// MarshalJSON implements json.Marshaler
+func (s BinaryString) MarshalJSON() ([]byte, error) {
+	if utf8.ValidString(string(s)) {
+		return json.Marshal(string(s))
+	}
+	return json.Marshal(BinaryValue{Base64: base64.StdEncoding.EncodeToString([]byte(s))})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *BinaryString) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*s = BinaryString(text)
+		return nil
+	}
+	var v BinaryValue
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf(`expected a string or {"$base64": ...}`)
+	}
+	b, err := base64.StdEncoding.DecodeString(v.Base64)
+	if err != nil {
+		return fmt.Errorf("invalid $base64 value: %w", err)
+	}
+	*s = BinaryString(b)
+	return nil
+}
+
+// KeyInfo describes a key found while scanning. TTL is in seconds, -1 when
+// the key does not expire and -2 when it no longer exists.
+type KeyInfo struct {
+	Key  BinaryString `json:"key"`
+	Type string       `json:"type"`
+	TTL  int64        `json:"ttl"`
+}
+
+// KeyScanRequest asks for one page of a SCAN iteration. Database, here and
+// in the other key requests, is the connection's own database when omitted.
+type KeyScanRequest struct {
+	ConnectionID string `json:"connection_id"`
+	Database     *int   `json:"database,omitempty"`
+	Cursor       uint64 `json:"cursor,omitempty"`
+	Match        string `json:"match,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Count        int64  `json:"count,omitempty"`
+}
+
+// KeyScanResult is one page of keys. Cursor is 0 once the iteration is complete.
+type KeyScanResult struct {
+	Keys   []KeyInfo `json:"keys"`
+	Cursor uint64    `json:"cursor"`
+}
+
+// KeyGetRequest reads a key. Collections are read a page at a time starting
+// at Cursor, which is empty for the first page.
+type KeyGetRequest struct {
+	ConnectionID string       `json:"connection_id"`
+	Database     *int         `json:"database,omitempty"`
+	Key          BinaryString `json:"key"`
+	Cursor       string       `json:"cursor,omitempty"`
+	Count        int64        `json:"count,omitempty"`
+}
+
+// HashField is a field of a hash or a stream entry
+type HashField struct {
+	Field BinaryString `json:"field"`
+	Value BinaryString `json:"value"`
+}
+
+// ZMember is a member of a sorted set
+type ZMember struct {
+	Member BinaryString `json:"member"`
+	Score  float64      `json:"score"`
+}
+
+// StreamEntry is an entry of a stream
+type StreamEntry struct {
+	ID     string      `json:"id"`
+	Fields []HashField `json:"fields"`
+}
+
+// KeyValue is the typed value of a key. Only the field matching Type is set:
+// Value for strings, Fields for hashes, Items for lists and sets, Members for
+// sorted sets and Entries for streams. Length is the total size of the key
+// and Cursor is the next page, empty when everything has been read.
+type KeyValue struct {
+	Key     BinaryString   `json:"key"`
+	Type    string         `json:"type"`
+	TTL     int64          `json:"ttl"`
+	Length  int64          `json:"length"`
+	Value   BinaryString   `json:"value,omitempty"`
+	Fields  []HashField    `json:"fields,omitempty"`
+	Items   []BinaryString `json:"items,omitempty"`
+	Members []ZMember      `json:"members,omitempty"`
+	Entries []StreamEntry  `json:"entries,omitempty"`
+	Cursor  string         `json:"cursor,omitempty"`
+}
+
+// KeyWriteRequest changes a key with a type specific operation:
+//
+//	string: set (Value)
+//	hash:   hset (Fields), hdel (Values holds field names)
+//	list:   rpush, lpush (Values), lset (Index, Value), lrem (Value)
+//	set:    sadd, srem (Values)
+//	zset:   zadd (Members), zrem (Values)
+//	stream: xadd (StreamID, Fields), xdel (Values holds entry IDs)
+//
+// A TTL in seconds, when given, is applied after the write.
+type KeyWriteRequest struct {
+	ConnectionID string         `json:"connection_id"`
+	Database     *int           `json:"database,omitempty"`
+	Key          BinaryString   `json:"key"`
+	Op           string         `json:"op"`
+	Value        BinaryString   `json:"value,omitempty"`
+	Values       []BinaryString `json:"values,omitempty"`
+	Fields       []HashField    `json:"fields,omitempty"`
+	Members      []ZMember      `json:"members,omitempty"`
+	Index        int64          `json:"index,omitempty"`
+	StreamID     string         `json:"streamId,omitempty"`
+	TTL          *int64         `json:"ttl,omitempty"`
+}
+
+// KeyDeleteRequest deletes keys
+type KeyDeleteRequest struct {
+	ConnectionID string         `json:"connection_id"`
+	Database     *int           `json:"database,omitempty"`
+	Keys         []BinaryString `json:"keys"`
+}
+
+// KeyTTLRequest sets the TTL of a key in seconds; zero or less removes it
+type KeyTTLRequest struct {
+	ConnectionID string       `json:"connection_id"`
+	Database     *int         `json:"database,omitempty"`
+	Key          BinaryString `json:"key"`
+	TTL          int64        `json:"ttl"`
+}
+
+// KeyCommandRequest is a raw command typed into the console, e.g. HGETALL user:1
+type KeyCommandRequest struct {
+	ConnectionID string `json:"connection_id"`
+	Database     *int   `json:"database,omitempty"`
+	Command      string `json:"command"`
+}
+
+// KeyCommandResult is the reply to a console command
+type KeyCommandResult struct {
+	Result        interface{} `json:"result"`
+	ExecutionTime int64       `json:"executionTime"`
+	Error         string      `json:"error,omitempty"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// QueryPlan is the execution plan of a query. Plans holds one tree per
+// statement, or per top-level step where the engine reports several. Raw
+// is the plan as the database returned it, in Format json, xml or text.
+type QueryPlan struct {
+	Plans         []PlanNode `json:"plans"`
+	Analyzed      bool       `json:"analyzed"`               // the query ran and actual figures are set
+	PlanningTime  *float64   `json:"planningTime,omitempty"` // milliseconds
+	Format        string     `json:"format"`
+	Raw           string     `json:"raw"`
+	ExecutionTime int64      `json:"executionTime"`
+	QueryID       string     `json:"queryId,omitempty"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// PlanNode is one step of an execution plan. Figures the engine does not
+// report are omitted; rows and times are per execution of the step, which
+// runs Loops times. Cost is the engine's estimated total cost in its own
+// units. Details holds the remaining engine specific properties.
+type PlanNode struct {
+	Operation     string                 `json:"operation"`
+	Object        string                 `json:"object,omitempty"` // table, view or function
+	Index         string                 `json:"index,omitempty"`
+	EstimatedRows *float64               `json:"estimatedRows,omitempty"`
+	ActualRows    *float64               `json:"actualRows,omitempty"`
+	Cost          *float64               `json:"cost,omitempty"`
+	ActualTime    *float64               `json:"actualTime,omitempty"` // milliseconds
+	Loops         *float64               `json:"loops,omitempty"`
+	Details       map[string]interface{} `json:"details,omitempty"`
+	Children      []PlanNode             `json:"children,omitempty"`
+}
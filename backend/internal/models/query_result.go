@@ -1,20 +1,183 @@
 package models
 
-// QueryResult represents the result of a query execution
+// QueryResult represents the result of a query execution with each row
+// keyed by column name
 type QueryResult struct {
 	Columns       []string                 `json:"columns"`
 	Rows          []map[string]interface{} `json:"rows"`
 	RowCount      int                      `json:"rowCount"`
 	ExecutionTime int64                    `json:"executionTime"`
+	QueryID       string                   `json:"queryId,omitempty"`
+	Truncated     bool                     `json:"truncated,omitempty"` // rows were dropped at the row cap
+	StatementType string                   `json:"statementType,omitempty"`
+	RowsAffected  *int64                   `json:"rowsAffected,omitempty"`
+	LastInsertID  *int64                   `json:"lastInsertId,omitempty"`
+	Messages      []ServerMessage          `json:"messages,omitempty"`
 	Error         string                   `json:"error,omitempty"`
 }
 
-// TableInfo represents basic information about a table
+// ResultSet is the result of a SQL query: ordered, typed columns and rows
+// of values in column order. Binary values are sent as BinaryValue.
+// StatementType is query, dml, ddl or other. Statements run without a
+// result set report RowsAffected and, for inserts where the database
+// supports it, LastInsertID. Messages are the notices and warnings the
+// server sent while the statement ran.
+type ResultSet struct {
+	Columns       []ResultColumn  `json:"columns"`
+	Rows          [][]interface{} `json:"rows"`
+	RowCount      int             `json:"rowCount"`
+	ExecutionTime int64           `json:"executionTime"`
+	QueryID       string          `json:"queryId,omitempty"`
+	Truncated     bool            `json:"truncated,omitempty"` // rows were dropped at the row cap
+	StatementType string          `json:"statementType,omitempty"`
+	RowsAffected  *int64          `json:"rowsAffected,omitempty"`
+	LastInsertID  *int64          `json:"lastInsertId,omitempty"`
+	Messages      []ServerMessage `json:"messages,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// ServerMessage is a notice, warning or other message the server sent
+// alongside a statement. Level is the server's own, lower-cased: notice or
+// warning for PostgreSQL, note or warning for MySQL, info for SQL Server.
+type ServerMessage struct {
+	Level   string `json:"level"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// ResultColumn describes a column of a ResultSet. Nullable, Precision, Scale
+// and Length are omitted when the driver does not report them. JSONType
+// tells clients how values are encoded: integer, number, decimal (exact
+// numbers as strings), boolean, string, datetime, json (JSON text), binary
+// or unknown.
+type ResultColumn struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"` // database type name, e.g. VARCHAR
+	Nullable  *bool  `json:"nullable,omitempty"`
+	Precision *int64 `json:"precision,omitempty"`
+	Scale     *int64 `json:"scale,omitempty"`
+	Length    *int64 `json:"length,omitempty"`
+	JSONType  string `json:"jsonType"`
+}
+
+// StatementResult is the outcome of one statement of a script. A statement
+// producing several result sets gets one StatementResult per set.
+type StatementResult struct {
+	Statement string `json:"statement"`
+	Line      int    `json:"line"` // line of the script the statement starts on
+	ResultSet
+}
+
+// ScriptResult is the outcome of running a script. Statements after a
+// failing one are missing unless the script continued on error. Error is
+// set when the script could not start at all.
+type ScriptResult struct {
+	QueryID       string            `json:"queryId,omitempty"`
+	Statements    []StatementResult `json:"statements"`
+	Failed        int               `json:"failed"` // number of failed statements
+	ExecutionTime int64             `json:"executionTime"`
+	Error         string            `json:"error,omitempty"`
+}
+
+// BinaryValue is a binary cell, base64 encoded
+type BinaryValue struct {
+	Base64 string `json:"$base64"`
+}
+
+// MapResult converts the result to the map format. Columns sharing a name
+// keep only the last value.
+func (r *ResultSet) MapResult() *QueryResult {
+	columns := make([]string, len(r.Columns))
+	for i, col := range r.Columns {
+		columns[i] = col.Name
+	}
+
+	rows := make([]map[string]interface{}, len(r.Rows))
+	for i, values := range r.Rows {
+		row := make(map[string]interface{}, len(columns))
+		for j, name := range columns {
+			row[name] = values[j]
+		}
+		rows[i] = row
+	}
+
+	return &QueryResult{
+		Columns:       columns,
+		Rows:          rows,
+		RowCount:      r.RowCount,
+		ExecutionTime: r.ExecutionTime,
+		QueryID:       r.QueryID,
+		Truncated:     r.Truncated,
+		StatementType: r.StatementType,
+		RowsAffected:  r.RowsAffected,
+		LastInsertID:  r.LastInsertID,
+		Messages:      r.Messages,
+		Error:         r.Error,
+	}
+}
+
+// A streamed query result is a header, any number of row batches and a
+// trailer. Type names the message: header, rows or trailer.
+
+// QueryStreamHeader opens a streamed result with its columns
+type QueryStreamHeader struct {
+	Type    string         `json:"type"`
+	QueryID string         `json:"queryId"`
+	Columns []ResultColumn `json:"columns"`
+}
+
+// QueryStreamRows is a batch of rows of a streamed result, each row in
+// column order as in ResultSet
+type QueryStreamRows struct {
+	Type string          `json:"type"`
+	Rows [][]interface{} `json:"rows"`
+}
+
+// QueryStreamTrailer ends a streamed result
+type QueryStreamTrailer struct {
+	Type          string          `json:"type"`
+	RowCount      int             `json:"rowCount"`
+	ExecutionTime int64           `json:"executionTime"`
+	Truncated     bool            `json:"truncated"`
+	Messages      []ServerMessage `json:"messages,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// TableInfo represents basic information about a table or other schema
+// object. Type is table, view, materialized_view, foreign_table, sequence,
+// function, procedure or trigger; Table is the table a trigger is on.
+// RowCount, DataSize and IndexSize, in bytes, are estimates from the
+// catalog, set only when asked for and known.
 type TableInfo struct {
-	Name     string `json:"name"`
-	Schema   string `json:"schema,omitempty"`
-	Type     string `json:"type"` // table, view
-	RowCount int64  `json:"rowCount,omitempty"`
+	Name      string `json:"name"`
+	Schema    string `json:"schema,omitempty"`
+	Type      string `json:"type"`
+	Table     string `json:"table,omitempty"`
+	RowCount  *int64 `json:"rowCount,omitempty"`
+	DataSize  *int64 `json:"dataSize,omitempty"`
+	IndexSize *int64 `json:"indexSize,omitempty"`
+}
+
+// ObjectDDL is the CREATE statement of a table or other schema object,
+// followed by those of its indexes and comments where they are separate
+type ObjectDDL struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema,omitempty"`
+	Type   string `json:"type"`
+	DDL    string `json:"ddl"`
+}
+
+// ObjectDefinition is the source of a view, routine, trigger or sequence
+// as the database keeps it: a view's query, a routine's body, a trigger's
+// statement or a sequence's options
+type ObjectDefinition struct {
+	Name       string `json:"name"`
+	Schema     string `json:"schema,omitempty"`
+	Type       string `json:"type"`
+	Table      string `json:"table,omitempty"`
+	Arguments  string `json:"arguments,omitempty"` // of a routine, telling overloads apart
+	Language   string `json:"language,omitempty"`
+	Definition string `json:"definition"`
 }
 
 // ColumnInfo represents column metadata
@@ -25,5 +188,6 @@ type ColumnInfo struct {
 	DefaultValue string `json:"defaultValue,omitempty"`
 	IsPrimaryKey bool   `json:"isPrimaryKey"`
 	IsForeignKey bool   `json:"isForeignKey"`
+	IsIdentity   bool   `json:"isIdentity"` // identity or auto-increment
 	Comment      string `json:"comment,omitempty"`
 }
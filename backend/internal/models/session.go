@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// SessionInfo describes a session: a connection pinned for one client so
+// that transactions and session settings carry over between requests
+type SessionInfo struct {
+	ID            string    `json:"id"`
+	ConnectionID  string    `json:"connectionId"`
+	InTransaction bool      `json:"inTransaction"`
+	IdleTimeout   int       `json:"idleTimeout"` // seconds
+	CreatedAt     time.Time `json:"createdAt"`
+	LastUsedAt    time.Time `json:"lastUsedAt"`
+}
+
+// SessionRequest opens a session
+type SessionRequest struct {
+	// IdleTimeout in seconds after which an unused session is closed and
+	// its open transaction rolled back
+	IdleTimeout int `json:"idle_timeout,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// SSHTunnelConfig represents a reusable SSH tunnel that connections can route through
+type SSHTunnelConfig struct {
+	ID         string `json:"id,omitempty"`
+	Name       string `json:"name"`
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+	Username   string `json:"username"`
+	AuthMethod string `json:"authMethod"` // password, key
+	KeyPath    string `json:"keyPath,omitempty"`
+
+	// Secrets are write-only and are moved into the credential vault
+	Password   string `json:"password,omitempty"`
+	PrivateKey string `json:"privateKey,omitempty"` // PEM content, alternative to KeyPath
+	Passphrase string `json:"passphrase,omitempty"`
+
+	HasPassword   bool `json:"hasPassword"`
+	HasPrivateKey bool `json:"hasPrivateKey"`
+	HasPassphrase bool `json:"hasPassphrase"`
+}
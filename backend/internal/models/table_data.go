@@ -0,0 +1,55 @@
+package models
+
+import "encoding/json"
+
+// TableDataRequest selects a page of a table's rows
+type TableDataRequest struct {
+	Page     int          `json:"page,omitempty"` // from 1
+	PageSize int          `json:"page_size,omitempty"`
+	Sort     []TableSort  `json:"sort,omitempty"`
+	Filter   *TableFilter `json:"filter,omitempty"`
+	// After is the NextCursor of the previous page. It continues keyset
+	// pagination, in which case Page is ignored.
+	After string `json:"after,omitempty"`
+	// Count asks for the total number of matching rows: exact, or estimate
+	// to read it from the database's statistics where it keeps them
+	Count     string `json:"count,omitempty"`
+	QueryID   string `json:"query_id,omitempty"`
+	Timeout   int    `json:"timeout,omitempty"` // seconds
+	RowFormat string `json:"row_format,omitempty"`
+}
+
+// TableSort orders rows by a column, asc (the default) or desc
+type TableSort struct {
+	Column    string `json:"column"`
+	Direction string `json:"direction,omitempty"`
+}
+
+// TableFilter is a tree of row conditions. A node either combines the
+// filters in And or Or, or compares Column using Op: eq, ne, lt, le, gt,
+// ge, like, not_like, in, not_in, between, is_null or is_not_null. Value is
+// an array for in and not_in, [low, high] for between and unused for the
+// null checks. Type converts values as for QueryParam.
+type TableFilter struct {
+	And    []TableFilter   `json:"and,omitempty"`
+	Or     []TableFilter   `json:"or,omitempty"`
+	Column string          `json:"column,omitempty"`
+	Op     string          `json:"op,omitempty"`
+	Value  json.RawMessage `json:"value,omitempty"`
+	Type   string          `json:"type,omitempty"`
+}
+
+// TablePaging describes where a page of table rows sits. NextCursor is set
+// when keyset pagination can continue after the page. TotalCount is set
+// when a count was asked for; CountEstimated tells it came from statistics.
+type TablePaging struct {
+	NextCursor     string `json:"nextCursor,omitempty"`
+	TotalCount     *int64 `json:"totalCount,omitempty"`
+	CountEstimated bool   `json:"countEstimated,omitempty"`
+}
+
+// TablePage is a page of a table's rows
+type TablePage struct {
+	ResultSet
+	TablePaging
+}
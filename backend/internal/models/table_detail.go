@@ -0,0 +1,46 @@
+package models
+
+// TableDetail describes a table's structure: its columns, indexes,
+// constraints and foreign keys
+type TableDetail struct {
+	Name        string           `json:"name"`
+	Schema      string           `json:"schema,omitempty"`
+	Comment     string           `json:"comment,omitempty"`
+	Columns     []ColumnInfo     `json:"columns"`
+	Indexes     []IndexInfo      `json:"indexes"`
+	Constraints []ConstraintInfo `json:"constraints"`
+	ForeignKeys []ForeignKeyInfo `json:"foreignKeys"`
+}
+
+// IndexInfo describes an index. Columns holds the key columns in order,
+// or the expression for a key part that is one. Predicate is the WHERE
+// clause of a partial index.
+type IndexInfo struct {
+	Name      string   `json:"name"`
+	Columns   []string `json:"columns"`
+	Unique    bool     `json:"unique"`
+	Primary   bool     `json:"primary"`
+	Method    string   `json:"method,omitempty"` // btree, hash, clustered, ...
+	Predicate string   `json:"predicate,omitempty"`
+}
+
+// ConstraintInfo describes a primary key, unique or check constraint.
+// Expression is the condition of a check constraint.
+type ConstraintInfo struct {
+	Name       string   `json:"name,omitempty"`
+	Type       string   `json:"type"` // primary_key, unique, check
+	Columns    []string `json:"columns,omitempty"`
+	Expression string   `json:"expression,omitempty"`
+}
+
+// ForeignKeyInfo describes a foreign key. Rules are spelled as in SQL,
+// e.g. CASCADE or NO ACTION.
+type ForeignKeyInfo struct {
+	Name              string   `json:"name,omitempty"`
+	Columns           []string `json:"columns"`
+	ReferencedSchema  string   `json:"referencedSchema,omitempty"`
+	ReferencedTable   string   `json:"referencedTable"`
+	ReferencedColumns []string `json:"referencedColumns"`
+	OnDelete          string   `json:"onDelete,omitempty"`
+	OnUpdate          string   `json:"onUpdate,omitempty"`
+}
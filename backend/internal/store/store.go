@@ -0,0 +1,346 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"opendbm/internal/models"
+)
+
+// ErrNotFound is returned when a record does not exist in the store
+var ErrNotFound = errors.New("record not found")
+
+// Store persists OpenDBM state in an embedded SQLite database
+type Store struct {
+	db *gorm.DB
+}
+
+// connectionRecord is the on-disk representation of a saved connection
+type connectionRecord struct {
+	ID              string `gorm:"primaryKey"`
+	Name            string
+	Type            string
+	Config          string // JSON encoded models.ConnectionConfig
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	LastConnectedAt *time.Time
+}
+
+func (connectionRecord) TableName() string {
+	return "connections"
+}
+
+// tunnelRecord is the on-disk representation of a saved SSH tunnel
+type tunnelRecord struct {
+	ID        string `gorm:"primaryKey"`
+	Name      string
+	Config    string // JSON encoded models.SSHTunnelConfig
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (tunnelRecord) TableName() string {
+	return "ssh_tunnels"
+}
+
+// KnownHost is a trusted SSH host key
+type KnownHost struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Host        string    `gorm:"index" json:"host"` // normalized host[:port]
+	KeyType     string    `json:"keyType"`
+	Key         []byte    `json:"-"` // wire format public key
+	Fingerprint string    `json:"fingerprint"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Secret is an encrypted value held on behalf of the credential vault
+type Secret struct {
+	Ref       string `gorm:"primaryKey"`
+	KeyID     string
+	Data      []byte // nonce followed by AES-GCM ciphertext
+	UpdatedAt time.Time
+}
+
+// SavedConnection is a connection config together with its bookkeeping timestamps
+type SavedConnection struct {
+	Config          models.ConnectionConfig
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	LastConnectedAt *time.Time
+}
+
+// DefaultDataDir returns the data directory used when none is configured
+func DefaultDataDir() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "opendbm")
+	}
+	return ".opendbm"
+}
+
+// Open opens (or creates) the store inside dataDir
+func Open(dataDir string) (*Store, error) {
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	path := filepath.Join(dataDir, "opendbm.db")
+	db, err := gorm.Open(sqlite.Open(path+"?_busy_timeout=5000&_journal_mode=WAL"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+
+	if err := db.AutoMigrate(&connectionRecord{}, &tunnelRecord{}, &KnownHost{}, &Secret{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate store: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database
+func (s *Store) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// ListConnections returns every saved connection ordered by creation time
+func (s *Store) ListConnections() ([]SavedConnection, error) {
+	var records []connectionRecord
+	if err := s.db.Order("created_at").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]SavedConnection, 0, len(records))
+	for _, rec := range records {
+		saved, err := rec.toSaved()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, saved)
+	}
+	return result, nil
+}
+
+// GetConnection returns a single saved connection
+func (s *Store) GetConnection(id string) (*SavedConnection, error) {
+	var rec connectionRecord
+	err := s.db.First(&rec, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := rec.toSaved()
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// SaveConnection inserts or updates a connection config
+func (s *Store) SaveConnection(config models.ConnectionConfig) error {
+	if config.ID == "" {
+		return fmt.Errorf("connection config has no id")
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode connection config: %w", err)
+	}
+
+	var rec connectionRecord
+	err = s.db.First(&rec, "id = ?", config.ID).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		rec = connectionRecord{ID: config.ID}
+	case err != nil:
+		return err
+	}
+
+	rec.Name = config.Name
+	rec.Type = config.Type
+	rec.Config = string(data)
+	return s.db.Save(&rec).Error
+}
+
+// TouchConnection records a successful connect for a saved connection
+func (s *Store) TouchConnection(id string, at time.Time) error {
+	return s.db.Model(&connectionRecord{}).Where("id = ?", id).
+		UpdateColumn("last_connected_at", at).Error
+}
+
+// DeleteConnection removes a saved connection
+func (s *Store) DeleteConnection(id string) error {
+	res := s.db.Delete(&connectionRecord{}, "id = ?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r connectionRecord) toSaved() (SavedConnection, error) {
+	var config models.ConnectionConfig
+	if err := json.Unmarshal([]byte(r.Config), &config); err != nil {
+		return SavedConnection{}, fmt.Errorf("failed to decode connection %s: %w", r.ID, err)
+	}
+	config.ID = r.ID
+
+	return SavedConnection{
+		Config:          config,
+		CreatedAt:       r.CreatedAt,
+		UpdatedAt:       r.UpdatedAt,
+		LastConnectedAt: r.LastConnectedAt,
+	}, nil
+}
+
+// ListTunnels returns every saved SSH tunnel ordered by creation time
+func (s *Store) ListTunnels() ([]models.SSHTunnelConfig, error) {
+	var records []tunnelRecord
+	if err := s.db.Order("created_at").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]models.SSHTunnelConfig, 0, len(records))
+	for _, rec := range records {
+		var config models.SSHTunnelConfig
+		if err := json.Unmarshal([]byte(rec.Config), &config); err != nil {
+			return nil, fmt.Errorf("failed to decode ssh tunnel %s: %w", rec.ID, err)
+		}
+		config.ID = rec.ID
+		result = append(result, config)
+	}
+	return result, nil
+}
+
+// SaveTunnel inserts or updates an SSH tunnel config
+func (s *Store) SaveTunnel(config models.SSHTunnelConfig) error {
+	if config.ID == "" {
+		return fmt.Errorf("ssh tunnel config has no id")
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode ssh tunnel config: %w", err)
+	}
+
+	var rec tunnelRecord
+	err = s.db.First(&rec, "id = ?", config.ID).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		rec = tunnelRecord{ID: config.ID}
+	case err != nil:
+		return err
+	}
+
+	rec.Name = config.Name
+	rec.Config = string(data)
+	return s.db.Save(&rec).Error
+}
+
+// DeleteTunnel removes a saved SSH tunnel
+func (s *Store) DeleteTunnel(id string) error {
+	res := s.db.Delete(&tunnelRecord{}, "id = ?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListKnownHosts returns every trusted host key
+func (s *Store) ListKnownHosts() ([]KnownHost, error) {
+	var hosts []KnownHost
+	if err := s.db.Order("host, key_type").Find(&hosts).Error; err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+// FindKnownHosts returns the trusted keys of a single host
+func (s *Store) FindKnownHosts(host string) ([]KnownHost, error) {
+	var hosts []KnownHost
+	if err := s.db.Where("host = ?", host).Find(&hosts).Error; err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+// SaveKnownHost trusts a host key
+func (s *Store) SaveKnownHost(host *KnownHost) error {
+	return s.db.Save(host).Error
+}
+
+// DeleteKnownHost removes a trusted host key
+func (s *Store) DeleteKnownHost(id uint) error {
+	res := s.db.Delete(&KnownHost{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetSecret returns a single encrypted secret
+func (s *Store) GetSecret(ref string) (*Secret, error) {
+	var secret Secret
+	err := s.db.First(&secret, "ref = ?", ref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// ListSecrets returns every encrypted secret
+func (s *Store) ListSecrets() ([]Secret, error) {
+	var secrets []Secret
+	if err := s.db.Order("ref").Find(&secrets).Error; err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// SaveSecret inserts or replaces an encrypted secret
+func (s *Store) SaveSecret(secret Secret) error {
+	return s.db.Save(&secret).Error
+}
+
+// ReplaceSecrets saves a batch of secrets atomically
+func (s *Store) ReplaceSecrets(secrets []Secret) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range secrets {
+			if err := tx.Save(&secrets[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteSecret removes an encrypted secret
+func (s *Store) DeleteSecret(ref string) error {
+	return s.db.Delete(&Secret{}, "ref = ?", ref).Error
+}
@@ -0,0 +1,367 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"opendbm/internal/store"
+)
+
+const keySize = 32
+
+var (
+	// ErrNotFound is returned when no secret exists for a reference
+	ErrNotFound = errors.New("secret not found")
+	// ErrUnknownKey is returned when a secret was encrypted with a key that is not in the keyring
+	ErrUnknownKey = errors.New("secret was encrypted with an unknown master key")
+	// ErrKeyFromEnv is returned when rotating a master key that is supplied by the environment
+	ErrKeyFromEnv = errors.New("master key is provided by the environment; set a new OPENDBM_MASTER_KEY (keeping the old key after a comma) and re-encrypt instead")
+)
+
+// Options selects where the master key comes from. Key takes precedence over
+// KeyFile. Both hold a keyring: base64 encoded 32-byte keys separated by
+// commas or newlines, the first being the current key and the rest older keys
+// that are still accepted for decryption.
+type Options struct {
+	Key     string
+	KeyFile string
+}
+
+// Status describes the state of the vault without revealing any key material
+type Status struct {
+	KeySource    string `json:"keySource"` // env, file
+	KeyID        string `json:"keyId"`
+	PreviousKeys int    `json:"previousKeys"`
+	Secrets      int    `json:"secrets"`
+	StaleSecrets int    `json:"staleSecrets"`
+}
+
+// Vault encrypts secrets at rest with AES-256-GCM under a master key
+type Vault struct {
+	store   *store.Store
+	keys    map[string][]byte
+	order   []string
+	source  string
+	keyFile string
+	mu      sync.RWMutex
+}
+
+// Open loads the master keyring and returns a vault backed by the store.
+// When no key is configured a new key file is generated.
+func Open(st *store.Store, opts Options) (*Vault, error) {
+	v := &Vault{store: st}
+
+	var keyring string
+	switch {
+	case opts.Key != "":
+		v.source = "env"
+		keyring = opts.Key
+	case opts.KeyFile != "":
+		v.source = "file"
+		v.keyFile = opts.KeyFile
+		data, err := os.ReadFile(opts.KeyFile)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			key, err := generateKey()
+			if err != nil {
+				return nil, err
+			}
+			if err := writeKeyFile(opts.KeyFile, [][]byte{key}); err != nil {
+				return nil, err
+			}
+			keyring = encodeKey(key)
+		case err != nil:
+			return nil, fmt.Errorf("failed to read master key file: %w", err)
+		default:
+			keyring = string(data)
+		}
+	default:
+		return nil, fmt.Errorf("no master key configured")
+	}
+
+	keys, err := parseKeyring(keyring)
+	if err != nil {
+		return nil, err
+	}
+	v.setKeys(keys)
+
+	return v, nil
+}
+
+// Get decrypts the secret stored under ref
+func (v *Vault) Get(ref string) (string, error) {
+	secret, err := v.store.GetSecret(ref)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	plaintext, err := v.decrypt(secret)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Has reports whether a secret is stored under ref
+func (v *Vault) Has(ref string) bool {
+	_, err := v.store.GetSecret(ref)
+	return err == nil
+}
+
+// Put encrypts value with the current master key and stores it under ref
+func (v *Vault) Put(ref string, value string) error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	secret, err := v.encrypt(ref, []byte(value))
+	if err != nil {
+		return err
+	}
+	return v.store.SaveSecret(*secret)
+}
+
+// Delete removes the secret stored under ref
+func (v *Vault) Delete(ref string) error {
+	return v.store.DeleteSecret(ref)
+}
+
+// Status reports the current key and how many secrets still use older keys
+func (v *Vault) Status() (*Status, error) {
+	secrets, err := v.store.ListSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	status := &Status{
+		KeySource:    v.source,
+		KeyID:        v.order[0],
+		PreviousKeys: len(v.order) - 1,
+		Secrets:      len(secrets),
+	}
+	for _, secret := range secrets {
+		if secret.KeyID != v.order[0] {
+			status.StaleSecrets++
+		}
+	}
+	return status, nil
+}
+
+// ReEncrypt re-encrypts every secret that is not under the current master
+// key and returns how many secrets were rewritten
+func (v *Vault) ReEncrypt() (int, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.reEncrypt()
+}
+
+// Rotate generates a new master key, re-encrypts every secret with it and
+// retires the old keys. Only file based keys can be rotated.
+func (v *Vault) Rotate() (*Status, error) {
+	v.mu.Lock()
+	if v.source != "file" {
+		v.mu.Unlock()
+		return nil, ErrKeyFromEnv
+	}
+
+	key, err := generateKey()
+	if err != nil {
+		v.mu.Unlock()
+		return nil, err
+	}
+
+	// Persist the new key ahead of the old ones first so that a crash
+	// part-way through re-encryption never strands a secret
+	keys := [][]byte{key}
+	for _, id := range v.order {
+		keys = append(keys, v.keys[id])
+	}
+	if err := writeKeyFile(v.keyFile, keys); err != nil {
+		v.mu.Unlock()
+		return nil, err
+	}
+	v.setKeys(keys)
+
+	if _, err := v.reEncrypt(); err != nil {
+		v.mu.Unlock()
+		return nil, fmt.Errorf("failed to re-encrypt secrets: %w", err)
+	}
+
+	if err := writeKeyFile(v.keyFile, [][]byte{key}); err != nil {
+		v.mu.Unlock()
+		return nil, err
+	}
+	v.setKeys([][]byte{key})
+	v.mu.Unlock()
+
+	return v.Status()
+}
+
+func (v *Vault) reEncrypt() (int, error) {
+	secrets, err := v.store.ListSecrets()
+	if err != nil {
+		return 0, err
+	}
+
+	var updated []store.Secret
+	for _, secret := range secrets {
+		if secret.KeyID == v.order[0] {
+			continue
+		}
+		plaintext, err := v.decrypt(&secret)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", secret.Ref, err)
+		}
+		fresh, err := v.encrypt(secret.Ref, plaintext)
+		if err != nil {
+			return 0, err
+		}
+		updated = append(updated, *fresh)
+	}
+
+	if len(updated) == 0 {
+		return 0, nil
+	}
+	if err := v.store.ReplaceSecrets(updated); err != nil {
+		return 0, err
+	}
+	return len(updated), nil
+}
+
+func (v *Vault) encrypt(ref string, plaintext []byte) (*store.Secret, error) {
+	keyID := v.order[0]
+	gcm, err := newGCM(v.keys[keyID])
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	// The reference is bound as additional data so ciphertexts cannot be swapped between secrets
+	data := gcm.Seal(nonce, nonce, plaintext, []byte(ref))
+	return &store.Secret{Ref: ref, KeyID: keyID, Data: data}, nil
+}
+
+func (v *Vault) decrypt(secret *store.Secret) ([]byte, error) {
+	key, ok := v.keys[secret.KeyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(secret.Data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("secret %s is corrupt", secret.Ref)
+	}
+	nonce, ciphertext := secret.Data[:gcm.NonceSize()], secret.Data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(secret.Ref))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret %s: %w", secret.Ref, err)
+	}
+	return plaintext, nil
+}
+
+func (v *Vault) setKeys(keys [][]byte) {
+	v.keys = make(map[string][]byte, len(keys))
+	v.order = nil
+	for _, key := range keys {
+		id := keyID(key)
+		if _, exists := v.keys[id]; exists {
+			continue
+		}
+		v.keys[id] = key
+		v.order = append(v.order, id)
+	}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+func generateKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate master key: %w", err)
+	}
+	return key, nil
+}
+
+func encodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func parseKeyring(keyring string) ([][]byte, error) {
+	fields := strings.FieldsFunc(keyring, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ' ' || r == '\t'
+	})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("master key is empty")
+	}
+
+	keys := make([][]byte, 0, len(fields))
+	for i, field := range fields {
+		key, err := base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("master key %d is not valid base64: %w", i+1, err)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("master key %d must be %d bytes, got %d", i+1, keySize, len(key))
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func writeKeyFile(path string, keys [][]byte) error {
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = encodeKey(key)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		return fmt.Errorf("failed to write master key file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write master key file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"opendbm/internal/store"
+)
+
+// openTestVault opens a vault on a fresh store with a generated key file
+func openTestVault(t *testing.T) (*Vault, *store.Store, string) {
+	t.Helper()
+	dir := t.TempDir()
+	st, err := store.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	keyFile := filepath.Join(dir, "master.key")
+	v, err := Open(st, Options{KeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v, st, keyFile
+}
+
+func mustKey(t *testing.T) string {
+	t.Helper()
+	key, err := generateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encodeKey(key)
+}
+
+func TestPutGet(t *testing.T) {
+	v, st, _ := openTestVault(t)
+
+	if err := v.Put("connection/a/password", "s3cret pässword"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := v.Get("connection/a/password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "s3cret pässword" {
+		t.Errorf("Get() = %q, want the stored value", got)
+	}
+
+	secret, err := st.GetSecret("connection/a/password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(secret.Data, []byte("s3cret")) {
+		t.Error("secret is stored in plaintext")
+	}
+
+	if _, err := v.Get("connection/missing/password"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a missing secret error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestCiphertextIsBoundToRef(t *testing.T) {
+	v, st, _ := openTestVault(t)
+
+	if err := v.Put("connection/a/password", "alpha"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Put("connection/b/password", "bravo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Moving a's ciphertext under b must not decrypt
+	a, err := st.GetSecret("connection/a/password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Ref = "connection/b/password"
+	if err := st.SaveSecret(*a); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := v.Get("connection/b/password"); err == nil {
+		t.Errorf("Get() of a swapped ciphertext = %q, want an error", got)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	v, _, keyFile := openTestVault(t)
+
+	if err := v.Put("connection/a/password", "alpha"); err != nil {
+		t.Fatal(err)
+	}
+	before, err := v.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := v.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.KeyID == before.KeyID {
+		t.Error("Rotate() kept the old key")
+	}
+	if after.PreviousKeys != 0 || after.StaleSecrets != 0 || after.Secrets != 1 {
+		t.Errorf("Status() after Rotate() = %+v, want one fresh secret and no previous keys", after)
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Fields(string(data)); len(lines) != 1 {
+		t.Errorf("key file holds %d keys after rotation, want 1", len(lines))
+	}
+
+	got, err := v.Get("connection/a/password")
+	if err != nil || got != "alpha" {
+		t.Errorf("Get() after Rotate() = %q, %v; want alpha", got, err)
+	}
+}
+
+func TestRotateEnvKey(t *testing.T) {
+	st, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	v, err := Open(st, Options{Key: mustKey(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Rotate(); !errors.Is(err, ErrKeyFromEnv) {
+		t.Errorf("Rotate() error = %v, want %v", err, ErrKeyFromEnv)
+	}
+}
+
+func TestKeyringReEncrypt(t *testing.T) {
+	st, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	oldKey, newKey := mustKey(t), mustKey(t)
+	old, err := Open(st, Options{Key: oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := old.Put("connection/a/password", "alpha"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the old key the secret cannot be read
+	stranger, err := Open(st, Options{Key: newKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stranger.Get("connection/a/password"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Get() without the old key error = %v, want %v", err, ErrUnknownKey)
+	}
+
+	// With it kept after the new key, the secret reads and can be moved over
+	v, err := Open(st, Options{Key: newKey + "," + oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := v.Get("connection/a/password"); err != nil || got != "alpha" {
+		t.Fatalf("Get() with the old key in the keyring = %q, %v; want alpha", got, err)
+	}
+	status, err := v.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.StaleSecrets != 1 || status.PreviousKeys != 1 {
+		t.Errorf("Status() = %+v, want one stale secret and one previous key", status)
+	}
+
+	n, err := v.ReEncrypt()
+	if err != nil || n != 1 {
+		t.Fatalf("ReEncrypt() = %d, %v; want 1 secret rewritten", n, err)
+	}
+	if got, err := stranger.Get("connection/a/password"); err != nil || got != "alpha" {
+		t.Errorf("Get() with only the new key after ReEncrypt() = %q, %v; want alpha", got, err)
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	a, b := mustKey(t), mustKey(t)
+	tests := []struct {
+		name    string
+		keyring string
+		keys    int
+		wantErr string
+	}{
+		{"single", a, 1, ""},
+		{"comma separated", a + "," + b, 2, ""},
+		{"newline separated with padding", "\n" + a + "\r\n" + b + "\n", 2, ""},
+		{"empty", " \n ", 0, "master key is empty"},
+		{"not base64", a + ",not-base64!", 0, "master key 2 is not valid base64"},
+		{"wrong size", "c2hvcnQ=", 0, "master key 1 must be 32 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := parseKeyring(tt.keyring)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseKeyring() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != tt.keys {
+				t.Errorf("parseKeyring() returned %d keys, want %d", len(keys), tt.keys)
+			}
+		})
+	}
+}
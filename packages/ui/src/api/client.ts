@@ -1,5 +1,5 @@
 import type { ConnectionConfig, Connection } from "../types/connection";
-import type { QueryResult, TableInfo, ColumnInfo } from "../types/database";
+import type { ObjectDDL, ObjectDefinition, ObjectKind, QueryParam, QueryPlan, QueryResult, ScriptResult, SessionInfo, TableDataOptions, TableDetail, TableInfo, TablePage, ColumnInfo } from "../types/database";
 
 const isDesktop = typeof window !== "undefined" && "__TAURI__" in window;
 
@@ -24,6 +24,18 @@ async function request<T>(path: string, options?: RequestInit): Promise<T> {
   return res.json();
 }
 
+/**
+ * Spells a table for the API as table or schema.table, double quoting any
+ * part that contains a dot or a quote
+ */
+export function tableRef(table: string, schema?: string): string {
+  const part = (name: string) =>
+    /[".]/.test(name) ? `"${name.replace(/"/g, '""')}"` : name;
+  return schema ? `${part(schema)}.${part(table)}` : part(table);
+}
+
+const segment = encodeURIComponent;
+
 export const api = {
   // Connection management
   async createConnection(config: ConnectionConfig): Promise<Connection> {
@@ -53,24 +65,109 @@ export const api = {
   },
 
   // Query execution
-  async executeQuery(connectionId: string, sql: string): Promise<QueryResult> {
+  async executeQuery(connectionId: string, sql: string, params?: QueryParam[]): Promise<QueryResult> {
     return request<QueryResult>("/query", {
       method: "POST",
-      body: JSON.stringify({ connection_id: connectionId, sql }),
+      body: JSON.stringify({ connection_id: connectionId, sql, params }),
     });
   },
 
+  async executeScript(
+    connectionId: string,
+    sql: string,
+    onError: "stop" | "continue" = "stop",
+    params?: QueryParam[]
+  ): Promise<ScriptResult> {
+    return request<ScriptResult>("/query/script", {
+      method: "POST",
+      body: JSON.stringify({ connection_id: connectionId, sql, on_error: onError, params }),
+    });
+  },
+
+  async explain(
+    connectionId: string,
+    sql: string,
+    analyze = false,
+    params?: QueryParam[]
+  ): Promise<QueryPlan> {
+    return request<QueryPlan>("/explain", {
+      method: "POST",
+      body: JSON.stringify({ connection_id: connectionId, sql, analyze, params }),
+    });
+  },
+
+  // Sessions and transactions
+  async openSession(connectionId: string, idleTimeout?: number): Promise<SessionInfo> {
+    return request<SessionInfo>(`/sessions/${connectionId}`, {
+      method: "POST",
+      body: JSON.stringify({ idle_timeout: idleTimeout }),
+    });
+  },
+
+  async closeSession(connectionId: string, sessionId: string): Promise<void> {
+    await request(`/sessions/${connectionId}/${sessionId}`, { method: "DELETE" });
+  },
+
+  async beginTransaction(connectionId: string, sessionId: string): Promise<SessionInfo> {
+    return request<SessionInfo>(`/sessions/${connectionId}/${sessionId}/begin`, { method: "POST" });
+  },
+
+  async commitTransaction(connectionId: string, sessionId: string): Promise<SessionInfo> {
+    return request<SessionInfo>(`/sessions/${connectionId}/${sessionId}/commit`, { method: "POST" });
+  },
+
+  async rollbackTransaction(connectionId: string, sessionId: string): Promise<SessionInfo> {
+    return request<SessionInfo>(`/sessions/${connectionId}/${sessionId}/rollback`, { method: "POST" });
+  },
+
   // Database structure
   async listDatabases(connectionId: string): Promise<string[]> {
     return request<string[]>(`/databases/${connectionId}`);
   },
 
-  async listTables(connectionId: string, database: string): Promise<TableInfo[]> {
-    return request<TableInfo[]>(`/tables/${connectionId}/${database}`);
+  async listSchemas(connectionId: string, database: string): Promise<string[]> {
+    return request<string[]>(`/schemas/${connectionId}/${segment(database)}`);
+  },
+
+  /** stats adds estimated row counts and sizes from the catalog */
+  async listTables(connectionId: string, database: string, schema?: string, stats = false): Promise<TableInfo[]> {
+    const params = new URLSearchParams();
+    if (schema) params.set("schema", schema);
+    if (stats) params.set("stats", "true");
+    const query = params.toString() ? `?${params}` : "";
+    return request<TableInfo[]>(`/tables/${connectionId}/${segment(database)}${query}`);
+  },
+
+  /** table is a tableRef */
+  async getTableDetail(connectionId: string, database: string, table: string): Promise<TableDetail> {
+    return request<TableDetail>(`/tables/${connectionId}/${segment(database)}/${segment(table)}`);
+  },
+
+  /** name is a tableRef; overloaded routines return one definition each */
+  async getObjectDefinition(
+    connectionId: string,
+    database: string,
+    type: ObjectKind,
+    name: string
+  ): Promise<ObjectDefinition[]> {
+    return request<ObjectDefinition[]>(
+      `/definition/${connectionId}/${segment(database)}/${type}/${segment(name)}`
+    );
+  },
+
+  /** name is a tableRef */
+  async getObjectDDL(
+    connectionId: string,
+    database: string,
+    type: ObjectKind,
+    name: string
+  ): Promise<ObjectDDL> {
+    return request<ObjectDDL>(`/ddl/${connectionId}/${segment(database)}/${type}/${segment(name)}`);
   },
 
+  /** table is a tableRef */
   async getTableSchema(connectionId: string, table: string): Promise<ColumnInfo[]> {
-    return request<ColumnInfo[]>(`/schema/${connectionId}/${table}`);
+    return request<ColumnInfo[]>(`/schema/${connectionId}/${segment(table)}`);
   },
 
   // MongoDB specific
@@ -96,15 +193,17 @@ export const api = {
   },
 
   // Table data
+  /** table is a tableRef */
   async getTableData(
     connectionId: string,
     database: string,
     table: string,
-    page: number = 1,
-    pageSize: number = 100
-  ): Promise<QueryResult> {
-    return request<QueryResult>(
-      `/data/${connectionId}/${database}/${table}?page=${page}&page_size=${pageSize}`
-    );
+    options: TableDataOptions = {}
+  ): Promise<TablePage> {
+    const { pageSize, ...rest } = options;
+    return request<TablePage>(`/data/${connectionId}/${segment(database)}/${segment(table)}`, {
+      method: "POST",
+      body: JSON.stringify({ ...rest, page_size: pageSize }),
+    });
   },
 };
@@ -1,10 +1,15 @@
 import { useState } from "react";
-import { cn } from "@/lib/utils";
+import { cn, formatBytes } from "@/lib/utils";
 import type { Connection } from "@/types/connection";
-import { api } from "@/api/client";
+import type { ObjectKind, TableInfo } from "@/types/database";
+import { api, tableRef } from "@/api/client";
 import {
+  Braces,
   Database,
+  Eye,
+  Hash,
   Table,
+  Zap,
   Trash2,
   Unplug,
   Loader2,
@@ -32,10 +37,38 @@ interface DatabaseTreeProps {
 
 interface DatabaseNode {
   name: string;
-  tables: string[];
+  tables: TableInfo[];
   isLoading: boolean;
 }
 
+const objectIcons: Record<ObjectKind, typeof Table> = {
+  table: Table,
+  view: Eye,
+  materialized_view: Eye,
+  foreign_table: Table,
+  sequence: Hash,
+  function: Braces,
+  procedure: Braces,
+  trigger: Zap,
+};
+
+/** Objects that can be selected from, and so open a query when clicked */
+const queryableKinds: ObjectKind[] = ["table", "view", "materialized_view", "foreign_table"];
+
+/** Describes an object for its tooltip, with the catalog's estimates */
+function describe(table: TableInfo): string {
+  const parts = [table.table ? `${table.type} on ${table.table}` : table.type.replace("_", " ")];
+  if (table.rowCount !== undefined) parts.push(`~${table.rowCount.toLocaleString()} rows`);
+  if (table.dataSize !== undefined) parts.push(`${formatBytes(table.dataSize)} data`);
+  if (table.indexSize !== undefined) parts.push(`${formatBytes(table.indexSize)} indexes`);
+  return parts.join(", ");
+}
+
+/** Tables are labeled with their schema when they span more than one */
+function showSchemas(tables: TableInfo[]): boolean {
+  return new Set(tables.map((t) => t.schema)).size > 1;
+}
+
 export function DatabaseTree({ connection, isActive, onSelect, onSelectTable }: DatabaseTreeProps) {
   const [databases, setDatabases] = useState<DatabaseNode[]>([]);
   const [isLoading, setIsLoading] = useState(false);
@@ -62,10 +95,10 @@ export function DatabaseTree({ connection, isActive, onSelect, onSelectTable }:
       return next;
     });
     try {
-      const tables = await api.listTables(connection.id, db.name);
+      const tables = await api.listTables(connection.id, db.name, undefined, true);
       setDatabases((prev) => {
         const next = [...prev];
-        next[dbIndex] = { ...next[dbIndex], tables: tables.map((t) => t.name), isLoading: false };
+        next[dbIndex] = { ...next[dbIndex], tables, isLoading: false };
         return next;
       });
     } catch {
@@ -169,16 +202,31 @@ export function DatabaseTree({ connection, isActive, onSelect, onSelectTable }:
                             ) : db.tables.length === 0 ? (
                               <p className="px-2 py-1 text-[10px] text-muted-foreground italic pl-4">No tables found</p>
                             ) : (
-                              db.tables.map((table) => (
-                                <button
-                                  key={table}
-                                  className="flex w-full items-center gap-2 rounded-md px-2 py-1 text-[11px] text-muted-foreground hover:bg-sidebar-accent/30 hover:text-foreground transition-colors group"
-                                  onClick={() => onSelectTable(db.name, table)}
-                                >
-                                  <Table className="h-2.5 w-2.5 opacity-50 group-hover:opacity-100" />
-                                  <span className="truncate">{table}</span>
-                                </button>
-                              ))
+                              db.tables.map((table) => {
+                                const Icon = objectIcons[table.type] ?? Table;
+                                return (
+                                  <button
+                                    key={`${table.type}:${tableRef(table.name, table.schema)}:${table.table ?? ""}`}
+                                    className="flex w-full items-center gap-2 rounded-md px-2 py-1 text-[11px] text-muted-foreground hover:bg-sidebar-accent/30 hover:text-foreground transition-colors group"
+                                    title={describe(table)}
+                                    onClick={() => {
+                                      if (queryableKinds.includes(table.type)) {
+                                        onSelectTable(db.name, tableRef(table.name, table.schema));
+                                      }
+                                    }}
+                                  >
+                                    <Icon className="h-2.5 w-2.5 opacity-50 group-hover:opacity-100" />
+                                    <span className="truncate">
+                                      {showSchemas(db.tables) && table.schema ? `${table.schema}.${table.name}` : table.name}
+                                    </span>
+                                    {table.dataSize !== undefined && (
+                                      <span className="ml-auto shrink-0 text-[10px] tabular-nums opacity-60">
+                                        {formatBytes(table.dataSize + (table.indexSize ?? 0))}
+                                      </span>
+                                    )}
+                                  </button>
+                                );
+                              })
                             )}
                           </div>
                         </AccordionContent>
@@ -2,6 +2,19 @@ import { useRef } from "react";
 import { useVirtualizer } from "@tanstack/react-virtual";
 import type { QueryResult } from "@/types/database";
 
+/** Formats a cell for display; binary cells show their size */
+export function formatCell(value: unknown): string {
+  if (value !== null && typeof value === "object" && "$base64" in value) {
+    const encoded = (value as { $base64: string }).$base64;
+    const size = Math.floor((encoded.length * 3) / 4) - (encoded.match(/=+$/)?.[0].length ?? 0);
+    return `(binary ${size} bytes)`;
+  }
+  if (value !== null && typeof value === "object") {
+    return JSON.stringify(value);
+  }
+  return String(value);
+}
+
 interface TableViewProps {
   data: QueryResult;
 }
@@ -33,13 +46,14 @@ export function TableView({ data }: TableViewProps) {
               <th className="border-b border-r border-border/50 px-3 py-2 text-[10px] font-bold uppercase tracking-wider text-muted-foreground/70 bg-muted/50 w-12 text-center">
                 #
               </th>
-              {data.columns.map((col) => (
+              {data.columns.map((col, i) => (
                 <th
-                  key={col}
+                  key={i}
+                  title={col.type}
                   className="border-b border-r border-border/50 px-4 py-2 text-[10px] font-bold uppercase tracking-wider text-muted-foreground/70"
                 >
                   <div className="flex items-center gap-2">
-                    <span className="truncate">{col}</span>
+                    <span className="truncate">{col.name}</span>
                   </div>
                 </th>
               ))}
@@ -60,15 +74,15 @@ export function TableView({ data }: TableViewProps) {
                   <td className="flex items-center justify-center border-r border-border/40 px-3 text-[10px] font-mono text-muted-foreground/40 w-12 shrink-0 bg-muted/5 group-hover:text-muted-foreground/80 transition-colors">
                     {virtualRow.index + 1}
                   </td>
-                  {data.columns.map((col) => (
+                  {row.map((value, i) => (
                     <td
-                      key={col}
+                      key={i}
                       className="flex items-center border-r border-border/40 px-4 text-xs font-mono truncate min-w-[150px] shrink-0"
                     >
-                      {row[col] === null ? (
+                      {value === null ? (
                         <span className="text-muted-foreground/40 italic text-[10px]">NULL</span>
                       ) : (
-                        <span className="text-foreground/90 truncate">{String(row[col])}</span>
+                        <span className="text-foreground/90 truncate">{formatCell(value)}</span>
                       )}
                     </td>
                   ))}
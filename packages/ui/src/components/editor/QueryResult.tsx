@@ -1,5 +1,5 @@
 import type { QueryResult as QueryResultType } from "@/types/database";
-import { TableView } from "@/components/database/TableView";
+import { TableView, formatCell } from "@/components/database/TableView";
 import {
   AlertCircle,
   Loader2,
@@ -31,8 +31,8 @@ export function QueryResultPanel({ result, isLoading }: QueryResultPanelProps) {
   const handleCopy = () => {
     if (!result) return;
     const csv = [
-      result.columns.join(","),
-      ...result.rows.map(row => result.columns.map(col => String(row[col])).join(","))
+      result.columns.map(col => col.name).join(","),
+      ...result.rows.map(row => row.map(formatCell).join(","))
     ].join("\n");
     navigator.clipboard.writeText(csv);
     setCopied(true);
@@ -81,8 +81,15 @@ export function QueryResultPanel({ result, isLoading }: QueryResultPanelProps) {
         <div className="flex items-center gap-4 text-[10px] font-medium text-muted-foreground/60">
           <div className="flex items-center gap-1.5 px-1.5 py-0.5 rounded-md bg-muted/20">
             <List className="h-3 w-3 opacity-70" />
-            <span className="font-mono text-foreground/70">{result.rowCount}</span>
+            <span className="font-mono text-foreground/70">
+              {result.rowsAffected !== undefined ? `${result.rowsAffected} affected` : result.rowCount}
+            </span>
           </div>
+          {result.lastInsertId !== undefined && (
+            <div className="flex items-center gap-1.5 px-1.5 py-0.5 rounded-md bg-muted/20">
+              <span className="font-mono text-foreground/70">id {result.lastInsertId}</span>
+            </div>
+          )}
           <div className="flex items-center gap-1.5 px-1.5 py-0.5 rounded-md bg-muted/20">
             <Clock className="h-3 w-3 opacity-70" />
             <span className="font-mono text-foreground/70">{result.executionTime}ms</span>
@@ -114,6 +121,15 @@ export function QueryResultPanel({ result, isLoading }: QueryResultPanelProps) {
           </DropdownMenu>
         </div>
       </div>
+      {result.messages && result.messages.length > 0 && (
+        <div className="max-h-24 overflow-auto border-b bg-muted/5 px-4 py-1.5">
+          {result.messages.map((msg, i) => (
+            <p key={i} className="text-[11px] font-mono text-muted-foreground">
+              <span className="uppercase opacity-60">{msg.level}</span> {msg.message}
+            </p>
+          ))}
+        </div>
+      )}
       <div className="flex-1 overflow-hidden">
         <TableView data={result} />
       </div>
@@ -1,5 +1,5 @@
 // Types
-export type { DatabaseType, TableInfo, ColumnInfo, QueryResult, DatabaseSchema } from "./types/database";
+export type { DatabaseType, TableInfo, ColumnInfo, ResultColumn, BinaryValue, QueryParam, QueryResult, ServerMessage, StatementResult, ScriptResult, PlanNode, QueryPlan, SessionInfo, DatabaseSchema } from "./types/database";
 export type { ConnectionConfig, Connection } from "./types/connection";
 export { DEFAULT_PORTS } from "./types/connection";
 
@@ -24,10 +24,15 @@ export interface ConnectionConfig {
   password: string;
   database: string;
   groupId?: string;
+  // Oracle: service name (defaults to database) or SID for older instances
+  serviceName?: string;
+  sid?: string;
 
   // Reusable SSH Tunnel
   useSsh?: boolean;
   sshTunnelId?: string;
+  // Ordered tunnel IDs for jump-host chains, e.g. bastion then jump box
+  sshHops?: string[];
 
   // SSL
   useSsl?: boolean;
@@ -35,15 +40,22 @@ export interface ConnectionConfig {
   sslCertPath?: string;
   sslKeyPath?: string;
   sslRejectUnauthorized?: boolean;
+  sslMode?: "disable" | "require" | "verify-ca" | "verify-full";
+  // PEM content, alternative to the *Path fields
+  sslCa?: string;
+  sslCert?: string;
+  sslKey?: string;
 
   // Advanced
   connectTimeout?: number;
+  queryTimeout?: number;
   maxPoolSize?: number;
 }
 
 export interface Connection extends ConnectionConfig {
   id: string;
   status: "connected" | "disconnected" | "connecting" | "error";
+  hasPassword?: boolean;
   createdAt: string;
   lastConnectedAt?: string;
   error?: string;
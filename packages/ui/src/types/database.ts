@@ -1,10 +1,43 @@
 export type DatabaseType = "mysql" | "postgres" | "mongodb" | "redis" | "sqlite" | "oracle" | "sqlserver";
 
+export type ObjectKind =
+  | "table"
+  | "view"
+  | "materialized_view"
+  | "foreign_table"
+  | "sequence"
+  | "function"
+  | "procedure"
+  | "trigger";
+
 export interface TableInfo {
   name: string;
   schema?: string;
-  type: "table" | "view";
+  type: ObjectKind;
+  /** The table a trigger is on */
+  table?: string;
+  /** Catalog estimates, present when listed with stats */
   rowCount?: number;
+  dataSize?: number;
+  indexSize?: number;
+}
+
+/** The source of a view, routine, trigger or sequence */
+export interface ObjectDefinition {
+  name: string;
+  schema?: string;
+  type: ObjectKind;
+  table?: string;
+  arguments?: string;
+  language?: string;
+  definition: string;
+}
+
+export interface ObjectDDL {
+  name: string;
+  schema?: string;
+  type: ObjectKind;
+  ddl: string;
 }
 
 export interface ColumnInfo {
@@ -14,15 +47,173 @@ export interface ColumnInfo {
   defaultValue?: string;
   isPrimaryKey: boolean;
   isForeignKey: boolean;
+  /** Identity or auto-increment */
+  isIdentity: boolean;
   comment?: string;
 }
 
-export interface QueryResult {
+export interface IndexInfo {
+  name: string;
+  columns: string[];
+  unique: boolean;
+  primary: boolean;
+  method?: string;
+  /** The WHERE clause of a partial index */
+  predicate?: string;
+}
+
+export interface ConstraintInfo {
+  name?: string;
+  type: "primary_key" | "unique" | "check";
+  columns?: string[];
+  expression?: string;
+}
+
+export interface ForeignKeyInfo {
+  name?: string;
   columns: string[];
-  rows: Record<string, unknown>[];
+  referencedSchema?: string;
+  referencedTable: string;
+  referencedColumns: string[];
+  onDelete?: string;
+  onUpdate?: string;
+}
+
+/** A table's columns, indexes, constraints and foreign keys */
+export interface TableDetail {
+  name: string;
+  schema?: string;
+  comment?: string;
+  columns: ColumnInfo[];
+  indexes: IndexInfo[];
+  constraints: ConstraintInfo[];
+  foreignKeys: ForeignKeyInfo[];
+}
+
+export interface ResultColumn {
+  name: string;
+  type: string;
+  nullable?: boolean;
+  precision?: number;
+  scale?: number;
+  length?: number;
+  jsonType: "integer" | "number" | "decimal" | "boolean" | "string" | "datetime" | "json" | "binary" | "unknown";
+}
+
+/** A binary cell, base64 encoded */
+export interface BinaryValue {
+  $base64: string;
+}
+
+/** A bind parameter; unnamed parameters fill positional placeholders */
+export interface QueryParam {
+  name?: string;
+  type?: ResultColumn["jsonType"];
+  value: unknown;
+}
+
+/** A notice or warning the server sent with a statement */
+export interface ServerMessage {
+  level: string;
+  code?: string;
+  message: string;
+}
+
+export interface QueryResult {
+  columns: ResultColumn[];
+  rows: unknown[][];
   rowCount: number;
   executionTime: number;
   error?: string;
+  queryId?: string;
+  truncated?: boolean;
+  statementType?: "query" | "dml" | "ddl" | "other";
+  rowsAffected?: number;
+  lastInsertId?: number;
+  messages?: ServerMessage[];
+}
+
+/** Orders table rows by a column */
+export interface TableSort {
+  column: string;
+  direction?: "asc" | "desc";
+}
+
+/** A tree of row conditions: a group of and/or filters, or a comparison */
+export interface TableFilter {
+  and?: TableFilter[];
+  or?: TableFilter[];
+  column?: string;
+  op?:
+    | "eq" | "ne" | "lt" | "le" | "gt" | "ge"
+    | "like" | "not_like" | "in" | "not_in" | "between"
+    | "is_null" | "is_not_null";
+  value?: unknown;
+  type?: QueryParam["type"];
+}
+
+export interface TableDataOptions {
+  page?: number;
+  pageSize?: number;
+  sort?: TableSort[];
+  filter?: TableFilter;
+  /** The nextCursor of the previous page, for keyset pagination */
+  after?: string;
+  count?: "exact" | "estimate";
+}
+
+/** A page of a table's rows */
+export interface TablePage extends QueryResult {
+  nextCursor?: string;
+  totalCount?: number;
+  countEstimated?: boolean;
+}
+
+export interface StatementResult extends QueryResult {
+  statement: string;
+  line: number;
+}
+
+export interface ScriptResult {
+  queryId?: string;
+  statements: StatementResult[];
+  failed: number;
+  executionTime: number;
+  error?: string;
+}
+
+/** One step of an execution plan; rows and times are per loop */
+export interface PlanNode {
+  operation: string;
+  object?: string;
+  index?: string;
+  estimatedRows?: number;
+  actualRows?: number;
+  cost?: number;
+  actualTime?: number;
+  loops?: number;
+  details?: Record<string, unknown>;
+  children?: PlanNode[];
+}
+
+export interface QueryPlan {
+  plans: PlanNode[];
+  analyzed: boolean;
+  planningTime?: number;
+  format: "json" | "xml" | "text" | "";
+  raw: string;
+  executionTime: number;
+  queryId?: string;
+  error?: string;
+}
+
+export interface SessionInfo {
+  id: string;
+  connectionId: string;
+  inTransaction: boolean;
+  idleTimeout: number;
+  createdAt: string;
+  lastUsedAt: string;
 }
 
 export interface DatabaseSchema {